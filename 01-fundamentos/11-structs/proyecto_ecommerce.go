@@ -44,6 +44,11 @@ func (t *Timestampable) MarcarEliminado() {
 	t.EliminadoEn = &now
 }
 
+func (t *Timestampable) Restaurar() {
+	t.EliminadoEn = nil
+	t.MarcarActualizado()
+}
+
 func (t Timestampable) EstaEliminado() bool {
 	return t.EliminadoEn != nil
 }
@@ -85,6 +90,7 @@ func (d Direccion) Validar() error {
 type Usuario struct {
 	Identificable   `json:",inline"`
 	Timestampable   `json:",inline"`
+	Versionable     `json:",inline"`
 	Email           string                 `json:"email" validate:"required,email"`
 	Username        string                 `json:"username" validate:"required,min=3"`
 	PasswordHash    string                 `json:"-"`
@@ -142,6 +148,7 @@ func (u *Usuario) AgregarDireccion(direccion Direccion) {
 	}
 	u.Direcciones = append(u.Direcciones, direccion)
 	u.MarcarActualizado()
+	u.incrementarVersion()
 }
 
 func (u Usuario) DireccionPrincipal() (Direccion, bool) {
@@ -179,18 +186,24 @@ type Variante struct {
 type Review struct {
 	Identificable `json:",inline"`
 	Timestampable `json:",inline"`
-	UsuarioID     string `json:"usuario_id"`
-	ProductoID    string `json:"producto_id"`
-	Calificacion  int    `json:"calificacion" validate:"min=1,max=5"`
-	Titulo        string `json:"titulo"`
-	Comentario    string `json:"comentario"`
-	Verificado    bool   `json:"verificado"`
-	Util          int    `json:"votos_util"`
+	UsuarioID     string       `json:"usuario_id"`
+	ProductoID    string       `json:"producto_id"`
+	Calificacion  int          `json:"calificacion" validate:"min=1,max=5"`
+	Titulo        string       `json:"titulo"`
+	Comentario    string       `json:"comentario"`
+	Verificado    bool         `json:"verificado"`
+	Util          int          `json:"votos_util"`
+	Estado        EstadoReview `json:"estado"`
+	OrdenID       string       `json:"orden_id,omitempty"`
+	ModeradoPor   string       `json:"moderado_por,omitempty"`
+	MotivoRechazo string       `json:"motivo_rechazo,omitempty"`
+	votantesUtil  map[string]bool
 }
 
 type Producto struct {
 	Identificable `json:",inline"`
 	Timestampable `json:",inline"`
+	Versionable   `json:",inline"`
 	Nombre        string                 `json:"nombre" validate:"required"`
 	Descripcion   string                 `json:"descripcion"`
 	SKU           string                 `json:"sku" validate:"required"`
@@ -251,23 +264,29 @@ func (p Producto) DescuentoPorcentaje() float64 {
 }
 
 func (p Producto) CalificacionPromedio() float64 {
-	if len(p.Reviews) == 0 {
-		return 0
-	}
-
-	total := 0
+	total, cantidad := 0, 0
 	for _, review := range p.Reviews {
+		if review.Estado != ReviewAprobada {
+			continue
+		}
 		total += review.Calificacion
+		cantidad++
 	}
 
-	return float64(total) / float64(len(p.Reviews))
+	if cantidad == 0 {
+		return 0
+	}
+	return float64(total) / float64(cantidad)
 }
 
-func (p *Producto) AgregarReview(review Review) {
+// agregarReview guarda la review tal como llega; la única entrada pública es
+// Ecommerce.EnviarReview, que valida, marca la compra verificada y la deja
+// pendiente de moderación
+func (p *Producto) agregarReview(review Review) {
 	review.ProductoID = p.ID
 	review.MarcarCreado()
 	p.Reviews = append(p.Reviews, review)
-	p.MarcarActualizado()
+	p.registrarCambio()
 }
 
 func (p *Producto) ReducirStock(cantidad int) error {
@@ -275,13 +294,27 @@ func (p *Producto) ReducirStock(cantidad int) error {
 		return fmt.Errorf("stock insuficiente: disponible %d, solicitado %d", p.Stock, cantidad)
 	}
 	p.Stock -= cantidad
-	p.MarcarActualizado()
+	p.registrarCambio()
 	return nil
 }
 
 func (p *Producto) AumentarStock(cantidad int) {
 	p.Stock += cantidad
+	p.registrarCambio()
+}
+
+// FijarStock reemplaza el stock por un conteo físico
+func (p *Producto) FijarStock(stock int) {
+	p.Stock = stock
+	p.registrarCambio()
+}
+
+// registrarCambio sube la versión junto con la fecha de actualización, así
+// una modificación hecha fuera del repositorio también invalida a quien leyó
+// la versión anterior
+func (p *Producto) registrarCambio() {
 	p.MarcarActualizado()
+	p.incrementarVersion()
 }
 
 // ==============================================
@@ -464,6 +497,7 @@ type Envio struct {
 type Orden struct {
 	Identificable    `json:",inline"`
 	Timestampable    `json:",inline"`
+	Versionable      `json:",inline"`
 	NumeroOrden      string            `json:"numero_orden"`
 	UsuarioID        string            `json:"usuario_id"`
 	Items            []ItemOrden       `json:"items"`
@@ -526,6 +560,7 @@ func (o *Orden) CambiarEstado(nuevoEstado EstadoOrden, comentario, usuarioID str
 	o.Estado = nuevoEstado
 	o.AgregarEstadoHistorial(nuevoEstado, comentario, usuarioID)
 	o.MarcarActualizado()
+	o.incrementarVersion()
 }
 
 func (o *Orden) AgregarEstadoHistorial(estado EstadoOrden, comentario, usuarioID string) {
@@ -582,9 +617,10 @@ type MovimientoInventario struct {
 }
 
 type Inventario struct {
-	productos   map[string]*Producto
-	movimientos []MovimientoInventario
-	reservas    map[string]ReservaStock
+	productos    map[string]*Producto
+	movimientos  []MovimientoInventario
+	reservas     map[string]ReservaStock
+	observadores []func(*Producto)
 }
 
 type ReservaStock struct {
@@ -603,8 +639,39 @@ func NewInventario() *Inventario {
 	}
 }
 
-func (inv *Inventario) AgregarProducto(producto *Producto) {
+// AlCambiarStock registra una función que se llama tras cada movimiento de stock
+func (inv *Inventario) AlCambiarStock(fn func(*Producto)) {
+	inv.observadores = append(inv.observadores, fn)
+}
+
+func (inv *Inventario) AgregarProducto(producto *Producto) error {
+	// Volver a agregarlo duplicaría el asiento de stock inicial
+	if _, existe := inv.productos[producto.ID]; existe {
+		return fmt.Errorf("producto %s ya está en el inventario", producto.ID)
+	}
 	inv.productos[producto.ID] = producto
+
+	// El stock inicial es el primer asiento del ledger y lleva la fecha de
+	// alta del producto, para que StockEn(p.CreadoEn) ya lo incluya
+	if producto.Stock > 0 {
+		inv.registrarMovimiento(producto.ID, "entrada", producto.Stock,
+			0, producto.Stock, "Stock inicial", "", "sistema")
+		inicial := &inv.movimientos[len(inv.movimientos)-1]
+		inicial.CreadoEn = producto.CreadoEn
+		inicial.ActualizadoEn = producto.CreadoEn
+	}
+	return nil
+}
+
+// QuitarProducto da de baja el producto y sus reservas; los movimientos ya
+// registrados se conservan como historial
+func (inv *Inventario) QuitarProducto(productoID string) {
+	delete(inv.productos, productoID)
+	for reservaID, reserva := range inv.reservas {
+		if reserva.ProductoID == productoID {
+			delete(inv.reservas, reservaID)
+		}
+	}
 }
 
 func (inv *Inventario) ReservarStock(productoID, usuarioID string, cantidad int, duracion time.Duration) error {
@@ -680,6 +747,12 @@ func (inv *Inventario) registrarMovimiento(productoID, tipo string, cantidad, st
 	movimiento.MarcarCreado()
 
 	inv.movimientos = append(inv.movimientos, movimiento)
+
+	if producto, existe := inv.productos[productoID]; existe {
+		for _, fn := range inv.observadores {
+			fn(producto)
+		}
+	}
 }
 
 func (inv *Inventario) LimpiarReservasExpiradas() int {
@@ -702,28 +775,35 @@ func (inv *Inventario) LimpiarReservasExpiradas() int {
 // ==============================================
 
 type Ecommerce struct {
-	usuarios   map[string]*Usuario
-	productos  map[string]*Producto
+	usuarios   *Repositorio[*Usuario]
+	productos  *Repositorio[*Producto]
 	categorias map[string]*Categoria
 	carritos   map[string]*Carrito
-	ordenes    map[string]*Orden
+	ordenes    *Repositorio[*Orden]
 	inventario *Inventario
+	catalogo   *CatalogoIndice
 }
 
 func NewEcommerce() *Ecommerce {
-	return &Ecommerce{
-		usuarios:   make(map[string]*Usuario),
-		productos:  make(map[string]*Producto),
+	e := &Ecommerce{
+		usuarios:   NewRepositorio[*Usuario](),
+		productos:  NewRepositorio[*Producto](),
 		categorias: make(map[string]*Categoria),
 		carritos:   make(map[string]*Carrito),
-		ordenes:    make(map[string]*Orden),
+		ordenes:    NewRepositorio[*Orden](),
 		inventario: NewInventario(),
+		catalogo:   NewCatalogoIndice(),
 	}
+
+	// Mantener el índice de disponibilidad sincronizado con el inventario
+	e.inventario.AlCambiarStock(e.catalogo.ActualizarStock)
+
+	return e
 }
 
 func (e *Ecommerce) RegistrarUsuario(email, username, nombre, apellido string) (*Usuario, error) {
-	// Verificar que el email no exista
-	for _, usuario := range e.usuarios {
+	// Verificar que el email no exista, incluso entre usuarios eliminados
+	for _, usuario := range e.usuarios.ListarTodos() {
 		if usuario.Email == email {
 			return nil, errors.New("email ya registrado")
 		}
@@ -737,7 +817,9 @@ func (e *Ecommerce) RegistrarUsuario(email, username, nombre, apellido string) (
 		return nil, err
 	}
 
-	e.usuarios[usuario.ID] = usuario
+	if err := e.usuarios.Agregar(usuario); err != nil {
+		return nil, err
+	}
 	return usuario, nil
 }
 
@@ -748,15 +830,22 @@ func (e *Ecommerce) CrearProducto(nombre, descripcion, sku, categoriaID string,
 	}
 
 	// Verificar que el SKU no exista
-	for _, producto := range e.productos {
+	for _, producto := range e.productos.ListarTodos() {
 		if producto.SKU == sku {
 			return nil, errors.New("SKU ya existe")
 		}
 	}
 
 	producto := NewProducto(nombre, descripcion, sku, categoriaID, precio, stock)
-	e.productos[producto.ID] = producto
-	e.inventario.AgregarProducto(producto)
+	if err := e.productos.Agregar(producto); err != nil {
+		return nil, err
+	}
+	// Sin inventario el producto no se podría vender: se deshace el alta
+	if err := e.inventario.AgregarProducto(producto); err != nil {
+		e.productos.descartar(producto.ID)
+		return nil, err
+	}
+	e.catalogo.Indexar(producto)
 
 	return producto, nil
 }
@@ -776,8 +865,8 @@ func (e *Ecommerce) ObtenerCarritoUsuario(usuarioID string) *Carrito {
 }
 
 func (e *Ecommerce) AgregarAlCarrito(usuarioID, productoID string, cantidad int) error {
-	producto, existe := e.productos[productoID]
-	if !existe {
+	producto, err := e.productos.Obtener(productoID)
+	if err != nil {
 		return errors.New("producto no encontrado")
 	}
 
@@ -799,7 +888,10 @@ func (e *Ecommerce) ProcesarOrden(usuarioID string, direccionEnvio Direccion, me
 
 	// Validar stock disponible
 	for _, item := range carrito.Items {
-		producto := e.productos[item.ProductoID]
+		producto, err := e.productos.Obtener(item.ProductoID)
+		if err != nil {
+			return nil, fmt.Errorf("producto %s ya no está disponible", item.ProductoID)
+		}
 		if !producto.TieneStock(item.Cantidad) {
 			return nil, fmt.Errorf("stock insuficiente para producto %s", producto.Nombre)
 		}
@@ -823,7 +915,9 @@ func (e *Ecommerce) ProcesarOrden(usuarioID string, direccionEnvio Direccion, me
 	}
 
 	// Guardar orden y limpiar carrito
-	e.ordenes[orden.ID] = orden
+	if err := e.ordenes.Agregar(orden); err != nil {
+		return nil, err
+	}
 	carrito.Vaciar()
 
 	// Cambiar estado a procesando
@@ -833,8 +927,8 @@ func (e *Ecommerce) ProcesarOrden(usuarioID string, direccionEnvio Direccion, me
 }
 
 func (e *Ecommerce) ConfirmarPago(ordenID string) error {
-	orden, existe := e.ordenes[ordenID]
-	if !existe {
+	orden, err := e.ordenes.Obtener(ordenID)
+	if err != nil {
 		return errors.New("orden no encontrada")
 	}
 
@@ -1051,6 +1145,9 @@ func main() {
 	orden.MarcarComoEntregada("sistema")
 	fmt.Printf("✅ Orden entregada exitosamente\n")
 
+	// Emitir el recibo de la orden
+	demostrarFacturas(ecommerce, orden.ID)
+
 	// Mostrar historial de estados
 	fmt.Println("\n📊 Historial de estados:")
 	for _, estado := range orden.HistorialEstados {
@@ -1060,32 +1157,42 @@ func main() {
 			estado.Comentario)
 	}
 
-	// Agregar review al producto
-	review := Review{
-		Identificable: Identificable{ID: generarID("REV")},
-		UsuarioID:     usuario1.ID,
-		Calificacion:  5,
-		Titulo:        "Excelente producto",
-		Comentario:    "La laptop funciona perfectamente, muy recomendada",
-		Verificado:    true,
+	// Agregar review al producto: pasa por moderación y la compra verificada
+	// sale de la orden entregada, no de un campo puesto a mano
+	review, err := ecommerce.EnviarReview(usuario1.ID, laptop.ID, 5, "Excelente producto",
+		"La laptop funciona perfectamente, muy recomendada")
+	if err != nil {
+		fmt.Printf("Error enviando review: %v\n", err)
+	} else if err := ecommerce.ModerarReview(laptop.ID, review.ID, true, "moderador", ""); err != nil {
+		fmt.Printf("Error moderando review: %v\n", err)
+	} else {
+		fmt.Printf("\n⭐ Review agregada a %s (compra verificada: %t)\n", laptop.Nombre, review.Verificado)
+		fmt.Printf("📊 Calificación promedio: %.1f/5\n", laptop.CalificacionPromedio())
 	}
-	laptop.AgregarReview(review)
 
-	fmt.Printf("\n⭐ Review agregada a %s\n", laptop.Nombre)
-	fmt.Printf("📊 Calificación promedio: %.1f/5\n", laptop.CalificacionPromedio())
+	// Reviews de clientes: compra verificada, moderación y votos
+	demostrarReviews(ecommerce, usuario1.ID, usuario2.ID, mouse)
 
 	// Mostrar inventario
 	fmt.Println("\n📦 Estado del inventario:")
-	for id, producto := range ecommerce.productos {
+	for _, producto := range ecommerce.productos.Listar() {
 		fmt.Printf("  %s: %d unidades (mín: %d)\n",
 			producto.Nombre, producto.Stock, producto.StockMinimo)
-		_ = id
 	}
 
 	// Limpiar reservas expiradas
 	liberadas := ecommerce.inventario.LimpiarReservasExpiradas()
 	fmt.Printf("🔄 Reservas expiradas liberadas: %d\n", liberadas)
 
+	// Consultar el ledger de inventario
+	demostrarLedgerInventario(ecommerce, laptop, mouse)
+
+	// Buscar y filtrar el catálogo
+	demostrarCatalogo(ecommerce)
+
+	// Concurrencia optimista, borrado lógico y purga
+	demostrarRepositorios(ecommerce, camiseta)
+
 	// Serializar datos a JSON (ejemplo)
 	usuarioJSON, _ := json.MarshalIndent(usuario1, "", "  ")
 	fmt.Println("\n📄 Datos del usuario (JSON):")
@@ -0,0 +1,440 @@
+// Archivo: proyecto_ecommerce_catalogo.go
+// Proyecto: Sistema de E-commerce - Búsqueda y filtrado facetado del catálogo
+// Demuestra: índices invertidos con maps de structs, structs de consulta con
+// valores opcionales, ordenamiento con sort.Slice y paginación
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ==============================================
+// ÍNDICE DEL CATÁLOGO
+// ==============================================
+
+// Pesos de relevancia según el campo donde aparece el término
+const (
+	pesoSKU         = 5
+	pesoNombre      = 3
+	pesoTag         = 2
+	pesoDescripcion = 1
+)
+
+type CatalogoIndice struct {
+	productos map[string]*Producto
+	terminos  map[string]map[string]int // término -> producto -> peso
+	enStock   map[string]bool
+	indexados map[string][]string // producto -> términos, para reindexar
+}
+
+func NewCatalogoIndice() *CatalogoIndice {
+	return &CatalogoIndice{
+		productos: make(map[string]*Producto),
+		terminos:  make(map[string]map[string]int),
+		enStock:   make(map[string]bool),
+		indexados: make(map[string][]string),
+	}
+}
+
+// Indexar agrega o reemplaza un producto en todos los índices
+func (c *CatalogoIndice) Indexar(p *Producto) {
+	c.Eliminar(p.ID)
+	c.productos[p.ID] = p
+
+	pesos := make(map[string]int)
+	agregar := func(texto string, peso int) {
+		for _, termino := range tokenizar(texto) {
+			if peso > pesos[termino] {
+				pesos[termino] = peso
+			}
+		}
+	}
+	agregar(p.Descripcion, pesoDescripcion)
+	for _, tag := range p.Tags {
+		agregar(tag, pesoTag)
+	}
+	agregar(p.Nombre, pesoNombre)
+	agregar(p.SKU, pesoSKU)
+	pesos[normalizar(p.SKU)] = pesoSKU
+
+	terminos := make([]string, 0, len(pesos))
+	for termino, peso := range pesos {
+		if c.terminos[termino] == nil {
+			c.terminos[termino] = make(map[string]int)
+		}
+		c.terminos[termino][p.ID] = peso
+		terminos = append(terminos, termino)
+	}
+	c.indexados[p.ID] = terminos
+
+	c.ActualizarStock(p)
+}
+
+func (c *CatalogoIndice) Eliminar(productoID string) {
+	if _, existe := c.productos[productoID]; !existe {
+		return
+	}
+
+	for _, termino := range c.indexados[productoID] {
+		delete(c.terminos[termino], productoID)
+		if len(c.terminos[termino]) == 0 {
+			delete(c.terminos, termino)
+		}
+	}
+	delete(c.indexados, productoID)
+	delete(c.enStock, productoID)
+	delete(c.productos, productoID)
+}
+
+// ActualizarStock refleja en el índice de disponibilidad el stock actual
+func (c *CatalogoIndice) ActualizarStock(p *Producto) {
+	if _, existe := c.productos[p.ID]; !existe {
+		return
+	}
+	if p.Stock > 0 {
+		c.enStock[p.ID] = true
+	} else {
+		delete(c.enStock, p.ID)
+	}
+}
+
+// ==============================================
+// CONSULTAS
+// ==============================================
+
+type OrdenCatalogo string
+
+const (
+	OrdenRelevancia   OrdenCatalogo = "relevancia"
+	OrdenPrecioAsc    OrdenCatalogo = "precio_asc"
+	OrdenPrecioDesc   OrdenCatalogo = "precio_desc"
+	OrdenCalificacion OrdenCatalogo = "calificacion"
+	OrdenNombre       OrdenCatalogo = "nombre"
+	OrdenRecientes    OrdenCatalogo = "recientes"
+)
+
+// ConsultaCatalogo usa valores cero como "sin filtro"
+type ConsultaCatalogo struct {
+	Texto           string
+	CategoriaIDs    []string
+	PrecioMin       float64
+	PrecioMax       float64
+	CalificacionMin float64
+	SoloEnStock     bool
+	Orden           OrdenCatalogo
+	Pagina          int // empieza en 1
+	TamanoPagina    int
+}
+
+type RangoPrecio struct {
+	Etiqueta string
+	Min      float64
+	Max      float64 // 0 = sin límite superior
+	Cantidad int
+}
+
+type FacetasCatalogo struct {
+	Categorias     map[string]int
+	RangosPrecio   []RangoPrecio
+	Calificaciones map[int]int // N estrellas o más -> productos
+	EnStock        int
+	SinStock       int
+}
+
+type ResultadoCatalogo struct {
+	Productos    []*Producto
+	Total        int
+	Pagina       int
+	TotalPaginas int
+	Facetas      FacetasCatalogo
+}
+
+var rangosPrecioCatalogo = []RangoPrecio{
+	{Etiqueta: "Menos de $25", Min: 0, Max: 25},
+	{Etiqueta: "$25 - $100", Min: 25, Max: 100},
+	{Etiqueta: "$100 - $500", Min: 100, Max: 500},
+	{Etiqueta: "Más de $500", Min: 500},
+}
+
+const tamanoPaginaPorDefecto = 20
+
+func (c *CatalogoIndice) Buscar(consulta ConsultaCatalogo) (ResultadoCatalogo, error) {
+	if consulta.PrecioMax > 0 && consulta.PrecioMin > consulta.PrecioMax {
+		return ResultadoCatalogo{}, errors.New("precio mínimo mayor que el máximo")
+	}
+	if consulta.Pagina < 1 {
+		consulta.Pagina = 1
+	}
+	if consulta.TamanoPagina <= 0 {
+		consulta.TamanoPagina = tamanoPaginaPorDefecto
+	}
+	if consulta.Orden == "" {
+		consulta.Orden = OrdenRelevancia
+	}
+
+	puntajes := c.coincidenciasTexto(consulta.Texto)
+
+	// Candidatos que cumplen todos los filtros salvo la categoría, para
+	// que la faceta de categorías muestre las alternativas disponibles
+	candidatos := []*Producto{}
+	for id, p := range c.productos {
+		if puntajes != nil {
+			if _, ok := puntajes[id]; !ok {
+				continue
+			}
+		}
+		if c.cumpleFiltros(p, consulta) {
+			candidatos = append(candidatos, p)
+		}
+	}
+
+	categorias := make(map[string]bool)
+	for _, id := range consulta.CategoriaIDs {
+		categorias[id] = true
+	}
+
+	resultado := ResultadoCatalogo{
+		Pagina: consulta.Pagina,
+		Facetas: FacetasCatalogo{
+			Categorias:     make(map[string]int),
+			RangosPrecio:   make([]RangoPrecio, len(rangosPrecioCatalogo)),
+			Calificaciones: make(map[int]int),
+		},
+	}
+	copy(resultado.Facetas.RangosPrecio, rangosPrecioCatalogo)
+
+	filtrados := []*Producto{}
+	for _, p := range candidatos {
+		resultado.Facetas.Categorias[p.CategoriaID]++
+		if len(categorias) > 0 && !categorias[p.CategoriaID] {
+			continue
+		}
+		filtrados = append(filtrados, p)
+		c.contarFacetas(&resultado.Facetas, p)
+	}
+
+	c.ordenar(filtrados, consulta.Orden, puntajes)
+
+	resultado.Total = len(filtrados)
+	resultado.TotalPaginas = int(math.Ceil(float64(len(filtrados)) / float64(consulta.TamanoPagina)))
+
+	inicio := (consulta.Pagina - 1) * consulta.TamanoPagina
+	if inicio > len(filtrados) {
+		inicio = len(filtrados)
+	}
+	fin := inicio + consulta.TamanoPagina
+	if fin > len(filtrados) {
+		fin = len(filtrados)
+	}
+	resultado.Productos = filtrados[inicio:fin]
+
+	return resultado, nil
+}
+
+// coincidenciasTexto devuelve nil si no hay texto; si lo hay, devuelve los
+// productos que contienen todos los términos (por prefijo) con su puntaje
+func (c *CatalogoIndice) coincidenciasTexto(texto string) map[string]int {
+	terminosConsulta := tokenizar(texto)
+	if len(terminosConsulta) == 0 {
+		return nil
+	}
+
+	var puntajes map[string]int
+	for _, consultaTermino := range terminosConsulta {
+		parciales := make(map[string]int)
+		for termino, productos := range c.terminos {
+			if !strings.HasPrefix(termino, consultaTermino) {
+				continue
+			}
+			for id, peso := range productos {
+				if termino == consultaTermino {
+					peso *= 2 // coincidencia exacta sobre prefijo
+				}
+				if peso > parciales[id] {
+					parciales[id] = peso
+				}
+			}
+		}
+
+		if puntajes == nil {
+			puntajes = parciales
+			continue
+		}
+		for id := range puntajes {
+			if peso, ok := parciales[id]; ok {
+				puntajes[id] += peso
+			} else {
+				delete(puntajes, id)
+			}
+		}
+	}
+	return puntajes
+}
+
+func (c *CatalogoIndice) cumpleFiltros(p *Producto, consulta ConsultaCatalogo) bool {
+	if !p.Activo || p.EstaEliminado() {
+		return false
+	}
+	precio := p.PrecioFinal()
+	if consulta.PrecioMin > 0 && precio < consulta.PrecioMin {
+		return false
+	}
+	if consulta.PrecioMax > 0 && precio > consulta.PrecioMax {
+		return false
+	}
+	if consulta.CalificacionMin > 0 && p.CalificacionPromedio() < consulta.CalificacionMin {
+		return false
+	}
+	if consulta.SoloEnStock && !c.enStock[p.ID] {
+		return false
+	}
+	return true
+}
+
+func (c *CatalogoIndice) contarFacetas(f *FacetasCatalogo, p *Producto) {
+	precio := p.PrecioFinal()
+	for i, rango := range f.RangosPrecio {
+		if precio >= rango.Min && (rango.Max == 0 || precio < rango.Max) {
+			f.RangosPrecio[i].Cantidad++
+		}
+	}
+
+	calificacion := p.CalificacionPromedio()
+	for estrellas := 1; estrellas <= 5; estrellas++ {
+		if calificacion >= float64(estrellas) {
+			f.Calificaciones[estrellas]++
+		}
+	}
+
+	if c.enStock[p.ID] {
+		f.EnStock++
+	} else {
+		f.SinStock++
+	}
+}
+
+func (c *CatalogoIndice) ordenar(productos []*Producto, orden OrdenCatalogo, puntajes map[string]int) {
+	menor := func(i, j int) bool { return productos[i].Nombre < productos[j].Nombre }
+
+	switch orden {
+	case OrdenPrecioAsc:
+		menor = func(i, j int) bool { return productos[i].PrecioFinal() < productos[j].PrecioFinal() }
+	case OrdenPrecioDesc:
+		menor = func(i, j int) bool { return productos[i].PrecioFinal() > productos[j].PrecioFinal() }
+	case OrdenCalificacion:
+		menor = func(i, j int) bool {
+			return productos[i].CalificacionPromedio() > productos[j].CalificacionPromedio()
+		}
+	case OrdenRecientes:
+		menor = func(i, j int) bool { return productos[i].CreadoEn.After(productos[j].CreadoEn) }
+	case OrdenRelevancia:
+		if puntajes != nil {
+			menor = func(i, j int) bool {
+				if puntajes[productos[i].ID] != puntajes[productos[j].ID] {
+					return puntajes[productos[i].ID] > puntajes[productos[j].ID]
+				}
+				return productos[i].Destacado && !productos[j].Destacado
+			}
+		}
+	}
+
+	// Orden base por ID para que la paginación sea estable entre consultas
+	sort.Slice(productos, func(i, j int) bool { return productos[i].ID < productos[j].ID })
+	sort.SliceStable(productos, menor)
+}
+
+// ==============================================
+// NORMALIZACIÓN DE TEXTO
+// ==============================================
+
+var quitarAcentos = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+)
+
+func normalizar(texto string) string {
+	return quitarAcentos.Replace(strings.ToLower(strings.TrimSpace(texto)))
+}
+
+func tokenizar(texto string) []string {
+	return strings.FieldsFunc(normalizar(texto), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ==============================================
+// INTEGRACIÓN CON ECOMMERCE
+// ==============================================
+
+func (e *Ecommerce) BuscarProductos(consulta ConsultaCatalogo) (ResultadoCatalogo, error) {
+	return e.catalogo.Buscar(consulta)
+}
+
+// ActualizarProducto reindexa un producto después de modificar sus datos y
+// registra el cambio, para que quien leyó la versión anterior no la guarde
+// encima
+func (e *Ecommerce) ActualizarProducto(producto *Producto) error {
+	if !e.productos.Existe(producto.ID) {
+		return errors.New("producto no encontrado")
+	}
+	producto.registrarCambio()
+	e.catalogo.Indexar(producto)
+	return nil
+}
+
+// ==============================================
+// DEMOSTRACIÓN DEL CATÁLOGO
+// ==============================================
+
+func demostrarCatalogo(e *Ecommerce) {
+	fmt.Println("\n🔍 Búsqueda en el catálogo:")
+
+	mostrar := func(titulo string, consulta ConsultaCatalogo) {
+		resultado, err := e.BuscarProductos(consulta)
+		if err != nil {
+			fmt.Printf("Error en búsqueda %q: %v\n", titulo, err)
+			return
+		}
+		fmt.Printf("  %s → %d resultado(s), página %d/%d\n",
+			titulo, resultado.Total, resultado.Pagina, resultado.TotalPaginas)
+		for _, p := range resultado.Productos {
+			fmt.Printf("    - %s [%s] $%.2f ⭐%.1f stock:%d\n",
+				p.Nombre, p.SKU, p.PrecioFinal(), p.CalificacionPromedio(), p.Stock)
+		}
+	}
+
+	mostrar(`texto "gaming"`, ConsultaCatalogo{Texto: "gaming"})
+	mostrar(`texto "algodon" (sin acento)`, ConsultaCatalogo{Texto: "algodon"})
+	mostrar(`SKU "mou-001"`, ConsultaCatalogo{Texto: "mou-001"})
+	mostrar("precio < $100, más caro primero", ConsultaCatalogo{PrecioMax: 100, Orden: OrdenPrecioDesc})
+	mostrar("4+ estrellas", ConsultaCatalogo{CalificacionMin: 4})
+	mostrar("página 2 de tamaño 2", ConsultaCatalogo{Orden: OrdenNombre, Pagina: 2, TamanoPagina: 2})
+
+	// Agotar un producto actualiza el índice de disponibilidad vía el inventario
+	if r, _ := e.BuscarProductos(ConsultaCatalogo{Texto: "CAM-001"}); len(r.Productos) == 1 {
+		camiseta := r.Productos[0]
+		if err := e.inventario.RegistrarSalida(camiseta.ID, camiseta.Stock, "Liquidación", "", "almacen"); err != nil {
+			fmt.Printf("Error registrando salida: %v\n", err)
+		}
+	}
+	mostrar("solo en stock", ConsultaCatalogo{SoloEnStock: true})
+
+	resultado, _ := e.BuscarProductos(ConsultaCatalogo{})
+	fmt.Println("  Facetas:")
+	for categoriaID, cantidad := range resultado.Facetas.Categorias {
+		nombre := categoriaID
+		if categoria, existe := e.categorias[categoriaID]; existe {
+			nombre = categoria.Nombre
+		}
+		fmt.Printf("    categoría %s: %d\n", nombre, cantidad)
+	}
+	for _, rango := range resultado.Facetas.RangosPrecio {
+		fmt.Printf("    %s: %d\n", rango.Etiqueta, rango.Cantidad)
+	}
+	fmt.Printf("    en stock: %d, sin stock: %d\n", resultado.Facetas.EnStock, resultado.Facetas.SinStock)
+}
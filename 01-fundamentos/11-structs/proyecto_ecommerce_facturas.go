@@ -0,0 +1,523 @@
+// Archivo: proyecto_ecommerce_facturas.go
+// Proyecto: Sistema de E-commerce - Facturas y recibos
+// Demuestra: structs como modelo de vista para plantillas, text/template y
+// html/template, formato regional y un escritor PDF mínimo sobre io.Writer
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ==============================================
+// CONFIGURACIÓN REGIONAL
+// ==============================================
+
+type Locale struct {
+	Codigo           string
+	SimboloMoneda    string
+	SimboloAntes     bool
+	SeparadorMiles   string
+	SeparadorDecimal string
+	FormatoFecha     string // layout de time.Format
+	Etiquetas        map[string]string
+}
+
+var etiquetasES = map[string]string{
+	"factura":   "FACTURA",
+	"recibo":    "RECIBO DE PAGO",
+	"numero":    "Número",
+	"orden":     "Orden",
+	"fecha":     "Fecha",
+	"emisor":    "Emisor",
+	"cliente":   "Cliente",
+	"envio_a":   "Enviar a",
+	"producto":  "Producto",
+	"cantidad":  "Cant.",
+	"precio":    "Precio",
+	"importe":   "Importe",
+	"subtotal":  "Subtotal",
+	"descuento": "Descuento",
+	"envio":     "Envío",
+	"impuestos": "Impuestos",
+	"total":     "Total",
+	"pago":      "Forma de pago",
+	"terminada": "terminada en",
+	"pagado":    "PAGADO",
+	"gracias":   "¡Gracias por su compra!",
+}
+
+var (
+	LocaleES = Locale{
+		Codigo: "es-ES", SimboloMoneda: "€", SimboloAntes: false,
+		SeparadorMiles: ".", SeparadorDecimal: ",", FormatoFecha: "02/01/2006",
+		Etiquetas: etiquetasES,
+	}
+	LocaleMX = Locale{
+		Codigo: "es-MX", SimboloMoneda: "$", SimboloAntes: true,
+		SeparadorMiles: ",", SeparadorDecimal: ".", FormatoFecha: "02/01/2006",
+		Etiquetas: etiquetasES,
+	}
+	LocaleUS = Locale{
+		Codigo: "en-US", SimboloMoneda: "$", SimboloAntes: true,
+		SeparadorMiles: ",", SeparadorDecimal: ".", FormatoFecha: "01/02/2006",
+		Etiquetas: map[string]string{
+			"factura": "INVOICE", "recibo": "PAYMENT RECEIPT", "numero": "Number",
+			"orden": "Order", "fecha": "Date", "emisor": "Issuer", "cliente": "Customer",
+			"envio_a": "Ship to", "producto": "Item", "cantidad": "Qty", "precio": "Price",
+			"importe": "Amount", "subtotal": "Subtotal", "descuento": "Discount",
+			"envio": "Shipping", "impuestos": "Tax", "total": "Total",
+			"pago": "Payment method", "terminada": "ending in", "pagado": "PAID",
+			"gracias": "Thank you for your purchase!",
+		},
+	}
+)
+
+// Moneda formatea un importe con los separadores y el símbolo del locale
+func (l Locale) Moneda(valor float64) string {
+	centavos := int64(math.Round(math.Abs(valor) * 100))
+	entero := fmt.Sprintf("%d", centavos/100)
+
+	var grupos []string
+	for len(entero) > 3 {
+		grupos = append([]string{entero[len(entero)-3:]}, grupos...)
+		entero = entero[:len(entero)-3]
+	}
+	grupos = append([]string{entero}, grupos...)
+
+	numero := fmt.Sprintf("%s%s%02d", strings.Join(grupos, l.SeparadorMiles), l.SeparadorDecimal, centavos%100)
+	if valor < 0 && centavos > 0 {
+		numero = "-" + numero
+	}
+	if l.SimboloAntes {
+		return l.SimboloMoneda + numero
+	}
+	return numero + " " + l.SimboloMoneda
+}
+
+func (l Locale) Fecha(t time.Time) string {
+	return t.Format(l.FormatoFecha)
+}
+
+func (l Locale) Texto(clave string) string {
+	if texto, ok := l.Etiquetas[clave]; ok {
+		return texto
+	}
+	return clave
+}
+
+func (l Locale) funciones() map[string]interface{} {
+	return map[string]interface{}{
+		"moneda": l.Moneda,
+		"fecha":  l.Fecha,
+		"t":      l.Texto,
+	}
+}
+
+// ==============================================
+// MODELO DE LA FACTURA
+// ==============================================
+
+type TipoDocumento string
+
+const (
+	DocumentoFactura TipoDocumento = "factura"
+	DocumentoRecibo  TipoDocumento = "recibo"
+)
+
+type DatosEmisor struct {
+	RazonSocial string
+	NIF         string
+	Direccion   string
+	Email       string
+}
+
+type LineaFactura struct {
+	Descripcion    string
+	SKU            string
+	Cantidad       int
+	PrecioUnitario float64
+	Descuento      float64
+	Importe        float64
+}
+
+type Factura struct {
+	Tipo           TipoDocumento
+	Numero         string
+	NumeroOrden    string
+	FechaEmision   time.Time
+	Emisor         DatosEmisor
+	Cliente        string
+	EmailCliente   string
+	DireccionEnvio Direccion
+	Lineas         []LineaFactura
+	Subtotal       float64
+	Descuento      float64
+	Envio          float64
+	TasaImpuesto   float64
+	Impuestos      float64
+	Total          float64
+	MetodoPago     MetodoPago
+	Pagada         bool
+}
+
+// DescripcionPago describe el método de pago sin exponer datos sensibles
+func (f Factura) DescripcionPago(l Locale) string {
+	descripcion := f.MetodoPago.Tipo
+	if f.MetodoPago.UltimosDigitos != "" {
+		descripcion += fmt.Sprintf(" %s %s", l.Texto("terminada"), f.MetodoPago.UltimosDigitos)
+	}
+	return descripcion
+}
+
+type GeneradorFacturas struct {
+	emisor        DatosEmisor
+	plantillaTxt  string
+	plantillaHTML string
+}
+
+func NewGeneradorFacturas(emisor DatosEmisor) *GeneradorFacturas {
+	return &GeneradorFacturas{
+		emisor:        emisor,
+		plantillaTxt:  plantillaFacturaTexto,
+		plantillaHTML: plantillaFacturaHTML,
+	}
+}
+
+// UsarPlantillas reemplaza las plantillas por defecto; una cadena vacía conserva la actual
+func (g *GeneradorFacturas) UsarPlantillas(texto, html string) error {
+	if texto != "" {
+		if _, err := template.New("factura").Funcs(funcionesFactura(LocaleES)).Parse(texto); err != nil {
+			return fmt.Errorf("plantilla de texto inválida: %w", err)
+		}
+		g.plantillaTxt = texto
+	}
+	if html != "" {
+		if _, err := htmltemplate.New("factura").Funcs(funcionesFactura(LocaleES)).Parse(html); err != nil {
+			return fmt.Errorf("plantilla HTML inválida: %w", err)
+		}
+		g.plantillaHTML = html
+	}
+	return nil
+}
+
+// GenerarFactura construye el documento de una orden. Los recibos solo se
+// emiten cuando el pago ya está confirmado.
+func (e *Ecommerce) GenerarFactura(g *GeneradorFacturas, ordenID string, tipo TipoDocumento) (*Factura, error) {
+	if tipo != DocumentoFactura && tipo != DocumentoRecibo {
+		return nil, fmt.Errorf("tipo de documento desconocido: %q", tipo)
+	}
+	orden, err := e.ordenes.ObtenerIncluyendoEliminados(ordenID)
+	if err != nil {
+		return nil, errors.New("orden no encontrada")
+	}
+
+	pagada := false
+	switch orden.Estado {
+	case OrdenConfirmada, OrdenEnEnvio, OrdenEntregada:
+		pagada = true
+	case OrdenCancelada:
+		return nil, errors.New("no se emiten documentos de órdenes canceladas")
+	}
+	if tipo == DocumentoRecibo && !pagada {
+		return nil, fmt.Errorf("la orden en estado %s aún no tiene pago confirmado", orden.Estado)
+	}
+
+	factura := &Factura{
+		Tipo:           tipo,
+		Numero:         strings.Replace(orden.NumeroOrden, "ORD", strings.ToUpper(string(tipo[:3])), 1),
+		NumeroOrden:    orden.NumeroOrden,
+		FechaEmision:   time.Now(),
+		Emisor:         g.emisor,
+		DireccionEnvio: orden.Envio.Direccion,
+		Subtotal:       orden.Subtotal,
+		Descuento:      orden.DescuentoTotal,
+		Envio:          orden.Envio.Costo,
+		Impuestos:      orden.Impuestos,
+		Total:          orden.Total,
+		MetodoPago:     orden.MetodoPago,
+		Pagada:         pagada,
+	}
+
+	if usuario, err := e.usuarios.ObtenerIncluyendoEliminados(orden.UsuarioID); err == nil {
+		factura.Cliente = usuario.NombreCompleto()
+		factura.EmailCliente = usuario.Email
+	}
+
+	for _, item := range orden.Items {
+		linea := LineaFactura{
+			Descripcion:    item.Nombre,
+			SKU:            item.SKU,
+			Cantidad:       item.Cantidad,
+			PrecioUnitario: item.PrecioUnitario,
+			Descuento:      item.Descuento,
+			Importe:        item.Subtotal,
+		}
+		if producto, err := e.productos.ObtenerIncluyendoEliminados(item.ProductoID); err == nil {
+			if linea.Descripcion == "" {
+				linea.Descripcion = producto.Nombre
+			}
+			if linea.SKU == "" {
+				linea.SKU = producto.SKU
+			}
+		}
+		factura.Lineas = append(factura.Lineas, linea)
+	}
+
+	// Los importes salen tal cual de la orden: el documento no puede cobrar
+	// impuestos que el cliente no pagó. La tasa solo se informa
+	if base := factura.Subtotal - factura.Descuento; base > 0 {
+		factura.TasaImpuesto = factura.Impuestos / base
+	}
+
+	return factura, nil
+}
+
+// ==============================================
+// RENDERIZADO: TEXTO, HTML Y PDF
+// ==============================================
+
+const plantillaFacturaTexto = `{{t (printf "%s" .Tipo)}}{{if .Pagada}} - {{t "pagado"}}{{end}}
+{{t "numero"}}: {{.Numero}}   {{t "orden"}}: {{.NumeroOrden}}   {{t "fecha"}}: {{fecha .FechaEmision}}
+
+{{t "emisor"}}: {{.Emisor.RazonSocial}} ({{.Emisor.NIF}})
+{{.Emisor.Direccion}}
+
+{{t "cliente"}}: {{.Cliente}} <{{.EmailCliente}}>
+{{t "envio_a"}}: {{.DireccionEnvio.Calle}}, {{.DireccionEnvio.Ciudad}} {{.DireccionEnvio.CodigoP}}, {{.DireccionEnvio.Pais}}
+
+{{printf "%-28s %-8s %5s %14s %14s" (t "producto") "SKU" (t "cantidad") (t "precio") (t "importe")}}
+{{range .Lineas}}{{printf "%-28.28s %-8s %5d %14s %14s" .Descripcion .SKU .Cantidad (moneda .PrecioUnitario) (moneda .Importe)}}
+{{end}}
+{{printf "%58s %14s" (t "subtotal") (moneda .Subtotal)}}
+{{if .Descuento}}{{printf "%58s %14s" (t "descuento") (moneda (neg .Descuento))}}
+{{end}}{{printf "%58s %14s" (t "envio") (moneda .Envio)}}
+{{printf "%58s %14s" (printf "%s (%.0f%%)" (t "impuestos") (pct .TasaImpuesto)) (moneda .Impuestos)}}
+{{printf "%58s %14s" (t "total") (moneda .Total)}}
+
+{{t "pago"}}: {{.DescripcionPago $.Locale}}
+{{t "gracias"}}
+`
+
+const plantillaFacturaHTML = `<!DOCTYPE html>
+<html lang="{{.Locale.Codigo}}">
+<head><meta charset="utf-8"><title>{{t (printf "%s" .Tipo)}} {{.Numero}}</title></head>
+<body>
+<h1>{{t (printf "%s" .Tipo)}}{{if .Pagada}} <small>{{t "pagado"}}</small>{{end}}</h1>
+<p>{{t "numero"}}: <strong>{{.Numero}}</strong> · {{t "orden"}}: {{.NumeroOrden}} · {{t "fecha"}}: {{fecha .FechaEmision}}</p>
+<p>{{t "emisor"}}: {{.Emisor.RazonSocial}} ({{.Emisor.NIF}})<br>{{.Emisor.Direccion}}</p>
+<p>{{t "cliente"}}: {{.Cliente}} &lt;{{.EmailCliente}}&gt;</p>
+<table>
+<thead><tr><th>{{t "producto"}}</th><th>SKU</th><th>{{t "cantidad"}}</th><th>{{t "precio"}}</th><th>{{t "importe"}}</th></tr></thead>
+<tbody>
+{{range .Lineas}}<tr><td>{{.Descripcion}}</td><td>{{.SKU}}</td><td>{{.Cantidad}}</td><td>{{moneda .PrecioUnitario}}</td><td>{{moneda .Importe}}</td></tr>
+{{end}}</tbody>
+<tfoot>
+<tr><td colspan="4">{{t "subtotal"}}</td><td>{{moneda .Subtotal}}</td></tr>
+{{if .Descuento}}<tr><td colspan="4">{{t "descuento"}}</td><td>{{moneda (neg .Descuento)}}</td></tr>
+{{end}}<tr><td colspan="4">{{t "envio"}}</td><td>{{moneda .Envio}}</td></tr>
+<tr><td colspan="4">{{t "impuestos"}}</td><td>{{moneda .Impuestos}}</td></tr>
+<tr><td colspan="4"><strong>{{t "total"}}</strong></td><td><strong>{{moneda .Total}}</strong></td></tr>
+</tfoot>
+</table>
+<p>{{t "pago"}}: {{.DescripcionPago $.Locale}}</p>
+<p>{{t "gracias"}}</p>
+</body>
+</html>
+`
+
+// vistaFactura expone el locale a las plantillas junto con los datos
+type vistaFactura struct {
+	*Factura
+	Locale Locale
+}
+
+func funcionesFactura(l Locale) map[string]interface{} {
+	funciones := l.funciones()
+	funciones["neg"] = func(v float64) float64 { return -v }
+	funciones["pct"] = func(v float64) float64 { return v * 100 }
+	return funciones
+}
+
+func (g *GeneradorFacturas) RenderizarTexto(w io.Writer, f *Factura, l Locale) error {
+	tmpl, err := template.New("factura").Funcs(funcionesFactura(l)).Parse(g.plantillaTxt)
+	if err != nil {
+		return fmt.Errorf("error en plantilla de texto: %w", err)
+	}
+	return tmpl.Execute(w, vistaFactura{Factura: f, Locale: l})
+}
+
+func (g *GeneradorFacturas) RenderizarHTML(w io.Writer, f *Factura, l Locale) error {
+	tmpl, err := htmltemplate.New("factura").Funcs(funcionesFactura(l)).Parse(g.plantillaHTML)
+	if err != nil {
+		return fmt.Errorf("error en plantilla HTML: %w", err)
+	}
+	return tmpl.Execute(w, vistaFactura{Factura: f, Locale: l})
+}
+
+// RenderizarPDF reutiliza la versión de texto y la compone en páginas A4
+func (g *GeneradorFacturas) RenderizarPDF(w io.Writer, f *Factura, l Locale) error {
+	var texto bytes.Buffer
+	if err := g.RenderizarTexto(&texto, f, l); err != nil {
+		return err
+	}
+
+	lineas := []string{}
+	scanner := bufio.NewScanner(&texto)
+	for scanner.Scan() {
+		lineas = append(lineas, scanner.Text())
+	}
+	return EscribirPDF(w, fmt.Sprintf("%s %s", l.Texto(string(f.Tipo)), f.Numero), lineas)
+}
+
+// ==============================================
+// ESCRITOR PDF MÍNIMO
+// ==============================================
+
+const (
+	pdfAncho           = 595 // A4 en puntos
+	pdfAlto            = 842
+	pdfMargen          = 40
+	pdfTamanoFuente    = 9
+	pdfInterlineado    = 12
+	pdfLineasPorPagina = (pdfAlto - 2*pdfMargen) / pdfInterlineado
+)
+
+// escritorPDF lleva la cuenta de los offsets de cada objeto para la tabla xref
+type escritorPDF struct {
+	w       io.Writer
+	escrito int
+	offsets []int
+	err     error
+}
+
+func (p *escritorPDF) escribir(formato string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(p.w, formato, args...)
+	p.escrito += n
+	p.err = err
+}
+
+func (p *escritorPDF) objeto(numero int, contenido string) {
+	for len(p.offsets) < numero {
+		p.offsets = append(p.offsets, 0)
+	}
+	p.offsets[numero-1] = p.escrito
+	p.escribir("%d 0 obj\n%s\nendobj\n", numero, contenido)
+}
+
+// EscribirPDF genera un PDF 1.4 con texto monoespaciado (Courier, WinAnsi)
+func EscribirPDF(w io.Writer, titulo string, lineas []string) error {
+	paginas := [][]string{}
+	for len(lineas) > pdfLineasPorPagina {
+		paginas = append(paginas, lineas[:pdfLineasPorPagina])
+		lineas = lineas[pdfLineasPorPagina:]
+	}
+	paginas = append(paginas, lineas)
+
+	p := &escritorPDF{w: w}
+	p.escribir("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	// 1: catálogo, 2: árbol de páginas, 3: fuente, 4: info, 5+: página y contenido
+	kids := []string{}
+	for i := range paginas {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*i))
+	}
+	p.objeto(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	p.objeto(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(paginas)))
+	p.objeto(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	p.objeto(4, fmt.Sprintf("<< /Title (%s) /Producer (go-deep e-commerce) >>", textoPDF(titulo)))
+
+	for i, pagina := range paginas {
+		var contenido strings.Builder
+		fmt.Fprintf(&contenido, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n",
+			pdfTamanoFuente, pdfInterlineado, pdfMargen, pdfAlto-pdfMargen)
+		for _, linea := range pagina {
+			fmt.Fprintf(&contenido, "(%s) Tj T*\n", textoPDF(linea))
+		}
+		contenido.WriteString("ET")
+
+		numeroPagina := 5 + 2*i
+		p.objeto(numeroPagina, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfAncho, pdfAlto, numeroPagina+1))
+		p.objeto(numeroPagina+1, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream",
+			contenido.Len(), contenido.String()))
+	}
+
+	inicioXref := p.escrito
+	p.escribir("xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, offset := range p.offsets {
+		p.escribir("%010d 00000 n \n", offset)
+	}
+	p.escribir("trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(p.offsets)+1, inicioXref)
+
+	return p.err
+}
+
+// textoPDF escapa una cadena literal de PDF y la convierte a WinAnsi
+func textoPDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '€':
+			b.WriteString(`\200`)
+		case r < 0x80:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r) // Latin-1 coincide con WinAnsi en este rango
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// ==============================================
+// DEMOSTRACIÓN DE FACTURAS
+// ==============================================
+
+func demostrarFacturas(e *Ecommerce, ordenID string) {
+	generador := NewGeneradorFacturas(DatosEmisor{
+		RazonSocial: "Go Deep Store S.L.",
+		NIF:         "B12345678",
+		Direccion:   "Calle Gopher 42, Madrid",
+		Email:       "facturas@godeep.dev",
+	})
+
+	factura, err := e.GenerarFactura(generador, ordenID, DocumentoRecibo)
+	if err != nil {
+		fmt.Printf("Error generando recibo: %v\n", err)
+		return
+	}
+
+	fmt.Println("\n🧾 Recibo (es-ES):")
+	if err := generador.RenderizarTexto(os.Stdout, factura, LocaleES); err != nil {
+		fmt.Printf("Error renderizando texto: %v\n", err)
+	}
+
+	var html, pdf bytes.Buffer
+	if err := generador.RenderizarHTML(&html, factura, LocaleUS); err != nil {
+		fmt.Printf("Error renderizando HTML: %v\n", err)
+	}
+	if err := generador.RenderizarPDF(&pdf, factura, LocaleMX); err != nil {
+		fmt.Printf("Error renderizando PDF: %v\n", err)
+	}
+	fmt.Printf("🌐 HTML (en-US): %d bytes | 📄 PDF (es-MX): %d bytes\n", html.Len(), pdf.Len())
+}
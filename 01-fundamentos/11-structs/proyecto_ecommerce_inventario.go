@@ -0,0 +1,337 @@
+// Archivo: proyecto_ecommerce_inventario.go
+// Proyecto: Sistema de E-commerce - Libro mayor (ledger) de inventario
+// Demuestra: consultas sobre slices de structs, filtros con structs opcionales,
+// reconstrucción de estado a partir de eventos y exportación CSV
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ==============================================
+// TIPOS DE MOVIMIENTO Y DELTAS
+// ==============================================
+
+const (
+	MovimientoEntrada    = "entrada"
+	MovimientoSalida     = "salida"
+	MovimientoReserva    = "reserva"
+	MovimientoLiberacion = "liberacion"
+	MovimientoAjuste     = "ajuste"
+)
+
+// Delta devuelve el efecto con signo del movimiento sobre el stock.
+// Los ajustes guardan la diferencia real en StockNuevo - StockAnterior.
+func (m MovimientoInventario) Delta() int {
+	switch m.TipoMovimiento {
+	case MovimientoEntrada, MovimientoLiberacion:
+		return m.Cantidad
+	case MovimientoSalida, MovimientoReserva:
+		return -m.Cantidad
+	case MovimientoAjuste:
+		return m.StockNuevo - m.StockAnterior
+	}
+	return 0
+}
+
+// ==============================================
+// OPERACIONES QUE ESCRIBEN EN EL LEDGER
+// ==============================================
+
+func (inv *Inventario) RegistrarEntrada(productoID string, cantidad int, motivo, referenciaID, usuarioID string) error {
+	producto, existe := inv.productos[productoID]
+	if !existe {
+		return errors.New("producto no encontrado")
+	}
+	if cantidad <= 0 {
+		return errors.New("la cantidad debe ser positiva")
+	}
+
+	anterior := producto.Stock
+	producto.AumentarStock(cantidad)
+	inv.registrarMovimiento(productoID, MovimientoEntrada, cantidad,
+		anterior, producto.Stock, motivo, referenciaID, usuarioID)
+	return nil
+}
+
+func (inv *Inventario) RegistrarSalida(productoID string, cantidad int, motivo, referenciaID, usuarioID string) error {
+	producto, existe := inv.productos[productoID]
+	if !existe {
+		return errors.New("producto no encontrado")
+	}
+	if cantidad <= 0 {
+		return errors.New("la cantidad debe ser positiva")
+	}
+
+	anterior := producto.Stock
+	if err := producto.ReducirStock(cantidad); err != nil {
+		return err
+	}
+	inv.registrarMovimiento(productoID, MovimientoSalida, cantidad,
+		anterior, producto.Stock, motivo, referenciaID, usuarioID)
+	return nil
+}
+
+// AjustarStock fija el stock a un conteo físico. El movimiento parte del
+// stock que indica el ledger, así que también corrige cualquier divergencia.
+func (inv *Inventario) AjustarStock(productoID string, stockReal int, motivo, usuarioID string) error {
+	producto, existe := inv.productos[productoID]
+	if !existe {
+		return errors.New("producto no encontrado")
+	}
+	if stockReal < 0 {
+		return errors.New("el stock no puede ser negativo")
+	}
+
+	anterior, _ := inv.StockEn(productoID, time.Now())
+	diferencia := stockReal - anterior
+	if diferencia < 0 {
+		diferencia = -diferencia
+	}
+	producto.FijarStock(stockReal)
+	inv.registrarMovimiento(productoID, MovimientoAjuste, diferencia,
+		anterior, stockReal, motivo, "", usuarioID)
+	return nil
+}
+
+// ==============================================
+// CONSULTAS SOBRE EL LEDGER
+// ==============================================
+
+// FiltroMovimientos usa valores cero como "sin filtro" en cada campo
+type FiltroMovimientos struct {
+	ProductoID   string
+	Tipo         string
+	UsuarioID    string
+	ReferenciaID string
+	Desde        time.Time
+	Hasta        time.Time
+}
+
+func (f FiltroMovimientos) Coincide(m MovimientoInventario) bool {
+	if f.ProductoID != "" && m.ProductoID != f.ProductoID {
+		return false
+	}
+	if f.Tipo != "" && m.TipoMovimiento != f.Tipo {
+		return false
+	}
+	if f.UsuarioID != "" && m.UsuarioID != f.UsuarioID {
+		return false
+	}
+	if f.ReferenciaID != "" && m.ReferenciaID != f.ReferenciaID {
+		return false
+	}
+	if !f.Desde.IsZero() && m.CreadoEn.Before(f.Desde) {
+		return false
+	}
+	if !f.Hasta.IsZero() && m.CreadoEn.After(f.Hasta) {
+		return false
+	}
+	return true
+}
+
+// ConsultarMovimientos devuelve una copia de los movimientos que cumplen el filtro,
+// en orden cronológico.
+func (inv *Inventario) ConsultarMovimientos(filtro FiltroMovimientos) []MovimientoInventario {
+	resultado := []MovimientoInventario{}
+	for _, m := range inv.movimientos {
+		if filtro.Coincide(m) {
+			resultado = append(resultado, m)
+		}
+	}
+	sort.SliceStable(resultado, func(i, j int) bool {
+		return resultado[i].CreadoEn.Before(resultado[j].CreadoEn)
+	})
+	return resultado
+}
+
+// StockEn reconstruye el stock de un producto en un instante sumando los
+// deltas de todos los movimientos registrados hasta ese momento. Un
+// producto purgado conserva sus movimientos, así que se puede seguir
+// consultando; solo un ID sin producto ni historial es un error.
+func (inv *Inventario) StockEn(productoID string, instante time.Time) (int, error) {
+	_, conocido := inv.productos[productoID]
+
+	stock := 0
+	for _, m := range inv.movimientos {
+		if m.ProductoID != productoID {
+			continue
+		}
+		conocido = true
+		if !m.CreadoEn.After(instante) {
+			stock += m.Delta()
+		}
+	}
+	if !conocido {
+		return 0, fmt.Errorf("%w: producto %s sin historial de inventario", ErrEntidadNoEncontrada, productoID)
+	}
+	return stock, nil
+}
+
+// ==============================================
+// CONCILIACIÓN
+// ==============================================
+
+type DiscrepanciaInventario struct {
+	ProductoID  string
+	Nombre      string
+	StockLedger int
+	StockActual int
+	Diferencia  int
+	Movimiento  string // ID del movimiento donde se rompe la cadena, si aplica
+	Descripcion string
+}
+
+func (d DiscrepanciaInventario) String() string {
+	return fmt.Sprintf("%s (%s): ledger=%d actual=%d diferencia=%+d - %s",
+		d.Nombre, d.ProductoID, d.StockLedger, d.StockActual, d.Diferencia, d.Descripcion)
+}
+
+// Conciliar compara la suma del ledger con Producto.Stock y verifica que cada
+// movimiento parta del stock en que terminó el anterior.
+func (inv *Inventario) Conciliar() []DiscrepanciaInventario {
+	discrepancias := []DiscrepanciaInventario{}
+
+	ids := make([]string, 0, len(inv.productos))
+	for id := range inv.productos {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		producto := inv.productos[id]
+		movimientos := inv.ConsultarMovimientos(FiltroMovimientos{ProductoID: id})
+
+		suma := 0
+		for i, m := range movimientos {
+			if i > 0 && m.StockAnterior != movimientos[i-1].StockNuevo {
+				discrepancias = append(discrepancias, DiscrepanciaInventario{
+					ProductoID:  id,
+					Nombre:      producto.Nombre,
+					StockLedger: movimientos[i-1].StockNuevo,
+					StockActual: m.StockAnterior,
+					Diferencia:  m.StockAnterior - movimientos[i-1].StockNuevo,
+					Movimiento:  m.ID,
+					Descripcion: "cambio de stock sin movimiento registrado",
+				})
+			}
+			suma += m.Delta()
+		}
+
+		if suma != producto.Stock {
+			discrepancias = append(discrepancias, DiscrepanciaInventario{
+				ProductoID:  id,
+				Nombre:      producto.Nombre,
+				StockLedger: suma,
+				StockActual: producto.Stock,
+				Diferencia:  producto.Stock - suma,
+				Descripcion: "la suma del ledger no coincide con el stock",
+			})
+		}
+	}
+
+	return discrepancias
+}
+
+// ==============================================
+// EXPORTACIÓN PARA CONTABILIDAD
+// ==============================================
+
+var encabezadoCSVMovimientos = []string{
+	"id", "fecha", "producto_id", "sku", "tipo", "cantidad", "delta",
+	"stock_anterior", "stock_nuevo", "motivo", "referencia_id", "usuario_id",
+}
+
+func (inv *Inventario) ExportarMovimientosCSV(w io.Writer, filtro FiltroMovimientos) error {
+	escritor := csv.NewWriter(w)
+
+	if err := escritor.Write(encabezadoCSVMovimientos); err != nil {
+		return fmt.Errorf("error escribiendo encabezado: %w", err)
+	}
+
+	for _, m := range inv.ConsultarMovimientos(filtro) {
+		sku := ""
+		if producto, existe := inv.productos[m.ProductoID]; existe {
+			sku = producto.SKU
+		}
+
+		fila := []string{
+			m.ID,
+			m.CreadoEn.Format(time.RFC3339),
+			m.ProductoID,
+			sku,
+			m.TipoMovimiento,
+			strconv.Itoa(m.Cantidad),
+			strconv.Itoa(m.Delta()),
+			strconv.Itoa(m.StockAnterior),
+			strconv.Itoa(m.StockNuevo),
+			m.Motivo,
+			m.ReferenciaID,
+			m.UsuarioID,
+		}
+		if err := escritor.Write(fila); err != nil {
+			return fmt.Errorf("error escribiendo movimiento %s: %w", m.ID, err)
+		}
+	}
+
+	escritor.Flush()
+	return escritor.Error()
+}
+
+// ==============================================
+// DEMOSTRACIÓN DEL LEDGER
+// ==============================================
+
+func demostrarLedgerInventario(e *Ecommerce, laptop, mouse *Producto) {
+	inv := e.inventario
+
+	fmt.Println("\n📒 Ledger de inventario:")
+	for _, m := range inv.ConsultarMovimientos(FiltroMovimientos{ProductoID: laptop.ID}) {
+		fmt.Printf("  %s %-10s %+4d  (%d → %d) %s\n",
+			m.CreadoEn.Format("15:04:05.000"), m.TipoMovimiento, m.Delta(),
+			m.StockAnterior, m.StockNuevo, m.Motivo)
+	}
+
+	stockInicial, _ := inv.StockEn(laptop.ID, laptop.CreadoEn)
+	stockHoy, _ := inv.StockEn(laptop.ID, time.Now())
+	fmt.Printf("🕰️ Stock de %s al crearse: %d, ahora: %d\n", laptop.Nombre, stockInicial, stockHoy)
+
+	// Un alta repetida se rechaza en lugar de duplicar el stock inicial
+	if err := inv.AgregarProducto(laptop); err != nil {
+		fmt.Printf("🚫 %v\n", err)
+	}
+
+	if err := inv.RegistrarEntrada(laptop.ID, 5, "Compra a proveedor", "PO-2024-001", "almacen"); err != nil {
+		fmt.Printf("Error registrando entrada: %v\n", err)
+	}
+
+	reservas := inv.ConsultarMovimientos(FiltroMovimientos{Tipo: MovimientoReserva})
+	fmt.Printf("🔎 Movimientos de reserva: %d\n", len(reservas))
+
+	// Un cambio hecho sobre el producto y no a través del inventario no deja
+	// rastro en el ledger
+	if err := mouse.ReducirStock(2); err != nil {
+		fmt.Printf("Error reduciendo stock: %v\n", err)
+	}
+	for _, d := range inv.Conciliar() {
+		fmt.Printf("⚠️ Discrepancia: %s\n", d)
+	}
+
+	if err := inv.AjustarStock(mouse.ID, mouse.Stock, "Conteo físico", "auditor"); err != nil {
+		fmt.Printf("Error ajustando stock: %v\n", err)
+	}
+	fmt.Printf("✅ Discrepancias tras el ajuste: %d\n", len(inv.Conciliar()))
+
+	fmt.Println("\n📄 Exportación CSV (laptop):")
+	if err := inv.ExportarMovimientosCSV(os.Stdout, FiltroMovimientos{ProductoID: laptop.ID}); err != nil {
+		fmt.Printf("Error exportando CSV: %v\n", err)
+	}
+}
@@ -0,0 +1,319 @@
+// Archivo: proyecto_ecommerce_repositorio.go
+// Proyecto: Sistema de E-commerce - Repositorios genéricos con borrado lógico
+// Demuestra: generics con restricciones de interfaz, métodos promovidos por
+// embedding, concurrencia optimista con un campo de versión
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// CONTRATOS DE LAS ENTIDADES
+// ==============================================
+
+var (
+	ErrEntidadNoEncontrada = errors.New("entidad no encontrada")
+	ErrEntidadExistente    = errors.New("entidad ya existe")
+	ErrConflictoVersion    = errors.New("conflicto de versión: la entidad fue modificada")
+	ErrEntidadNoEliminada  = errors.New("la entidad no está eliminada")
+)
+
+// Componente para control de concurrencia optimista
+type Versionable struct {
+	Version int `json:"version"`
+}
+
+func (v Versionable) ObtenerVersion() int {
+	return v.Version
+}
+
+func (v *Versionable) incrementarVersion() {
+	v.Version++
+}
+
+func (i Identificable) ObtenerID() string {
+	return i.ID
+}
+
+// EntidadPersistible la cumplen los punteros a structs que embeben
+// Identificable, Timestampable y Versionable
+type EntidadPersistible interface {
+	ObtenerID() string
+	EstaEliminado() bool
+	MarcarEliminado()
+	Restaurar()
+	MarcarActualizado()
+	FechaEliminacion() (time.Time, bool)
+	ObtenerVersion() int
+	incrementarVersion()
+}
+
+// ==============================================
+// REPOSITORIO GENÉRICO
+// ==============================================
+
+type Repositorio[T EntidadPersistible] struct {
+	mu        sync.RWMutex
+	entidades map[string]T
+}
+
+func NewRepositorio[T EntidadPersistible]() *Repositorio[T] {
+	return &Repositorio[T]{entidades: make(map[string]T)}
+}
+
+func (r *Repositorio[T]) Agregar(entidad T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, existe := r.entidades[entidad.ObtenerID()]; existe {
+		return fmt.Errorf("%w: %s", ErrEntidadExistente, entidad.ObtenerID())
+	}
+	if entidad.ObtenerVersion() == 0 {
+		entidad.incrementarVersion()
+	}
+	r.entidades[entidad.ObtenerID()] = entidad
+	return nil
+}
+
+// Obtener oculta las entidades eliminadas lógicamente
+func (r *Repositorio[T]) Obtener(id string) (T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entidad, existe := r.entidades[id]
+	if !existe || entidad.EstaEliminado() {
+		var cero T
+		return cero, fmt.Errorf("%w: %s", ErrEntidadNoEncontrada, id)
+	}
+	return entidad, nil
+}
+
+func (r *Repositorio[T]) ObtenerIncluyendoEliminados(id string) (T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entidad, existe := r.entidades[id]
+	if !existe {
+		var cero T
+		return cero, fmt.Errorf("%w: %s", ErrEntidadNoEncontrada, id)
+	}
+	return entidad, nil
+}
+
+func (r *Repositorio[T]) Existe(id string) bool {
+	_, err := r.Obtener(id)
+	return err == nil
+}
+
+// Listar devuelve las entidades activas ordenadas por ID
+func (r *Repositorio[T]) Listar() []T {
+	return r.Buscar(func(T) bool { return true })
+}
+
+func (r *Repositorio[T]) ListarEliminados() []T {
+	return r.filtrar(func(e T) bool { return e.EstaEliminado() })
+}
+
+// ListarTodos incluye las eliminadas; útil para validar unicidad
+func (r *Repositorio[T]) ListarTodos() []T {
+	return r.filtrar(func(T) bool { return true })
+}
+
+// Buscar aplica el predicado solo sobre las entidades activas
+func (r *Repositorio[T]) Buscar(predicado func(T) bool) []T {
+	return r.filtrar(func(e T) bool { return !e.EstaEliminado() && predicado(e) })
+}
+
+func (r *Repositorio[T]) filtrar(predicado func(T) bool) []T {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resultado := []T{}
+	for _, entidad := range r.entidades {
+		if predicado(entidad) {
+			resultado = append(resultado, entidad)
+		}
+	}
+	sort.Slice(resultado, func(i, j int) bool {
+		return resultado[i].ObtenerID() < resultado[j].ObtenerID()
+	})
+	return resultado
+}
+
+func (r *Repositorio[T]) Contar() int {
+	return len(r.Listar())
+}
+
+// Actualizar aplica la modificación solo si la versión almacenada coincide
+// con la que leyó el llamador; si la función falla no se incrementa la versión.
+// Si la modificación usa mutadores que ya suben la versión, no se vuelve a subir
+func (r *Repositorio[T]) Actualizar(id string, versionEsperada int, modificar func(T) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entidad, existe := r.entidades[id]
+	if !existe || entidad.EstaEliminado() {
+		return fmt.Errorf("%w: %s", ErrEntidadNoEncontrada, id)
+	}
+	if entidad.ObtenerVersion() != versionEsperada {
+		return fmt.Errorf("%w: %s (esperada %d, actual %d)",
+			ErrConflictoVersion, id, versionEsperada, entidad.ObtenerVersion())
+	}
+
+	if err := modificar(entidad); err != nil {
+		return err
+	}
+	entidad.MarcarActualizado()
+	if entidad.ObtenerVersion() == versionEsperada {
+		entidad.incrementarVersion()
+	}
+	return nil
+}
+
+func (r *Repositorio[T]) Eliminar(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entidad, existe := r.entidades[id]
+	if !existe || entidad.EstaEliminado() {
+		return fmt.Errorf("%w: %s", ErrEntidadNoEncontrada, id)
+	}
+	entidad.MarcarEliminado()
+	entidad.incrementarVersion()
+	return nil
+}
+
+func (r *Repositorio[T]) Restaurar(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entidad, existe := r.entidades[id]
+	if !existe {
+		return fmt.Errorf("%w: %s", ErrEntidadNoEncontrada, id)
+	}
+	if !entidad.EstaEliminado() {
+		return fmt.Errorf("%w: %s", ErrEntidadNoEliminada, id)
+	}
+	entidad.Restaurar()
+	entidad.incrementarVersion()
+	return nil
+}
+
+// Purgar borra físicamente las entidades eliminadas hace más de la retención
+// y devuelve sus IDs
+func (r *Repositorio[T]) Purgar(retencion time.Duration, ahora time.Time) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limite := ahora.Add(-retencion)
+	purgados := []string{}
+	for id, entidad := range r.entidades {
+		if fecha, eliminada := entidad.FechaEliminacion(); eliminada && !fecha.After(limite) {
+			delete(r.entidades, id)
+			purgados = append(purgados, id)
+		}
+	}
+	sort.Strings(purgados)
+	return purgados
+}
+
+// descartar borra una entidad recién agregada sin pasar por la eliminación
+// lógica; solo sirve para deshacer un Agregar cuyo alta no se completó
+func (r *Repositorio[T]) descartar(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entidades, id)
+}
+
+func (t Timestampable) FechaEliminacion() (time.Time, bool) {
+	if t.EliminadoEn == nil {
+		return time.Time{}, false
+	}
+	return *t.EliminadoEn, true
+}
+
+// ==============================================
+// OPERACIONES DEL E-COMMERCE SOBRE LOS REPOSITORIOS
+// ==============================================
+
+func (e *Ecommerce) EliminarUsuario(usuarioID string) error {
+	return e.usuarios.Eliminar(usuarioID)
+}
+
+func (e *Ecommerce) RestaurarUsuario(usuarioID string) error {
+	return e.usuarios.Restaurar(usuarioID)
+}
+
+// EliminarProducto lo oculta del catálogo y de nuevas compras; las órdenes
+// existentes conservan sus referencias
+func (e *Ecommerce) EliminarProducto(productoID string) error {
+	return e.productos.Eliminar(productoID)
+}
+
+func (e *Ecommerce) RestaurarProducto(productoID string) error {
+	return e.productos.Restaurar(productoID)
+}
+
+// PurgarEliminados borra definitivamente lo eliminado hace más de la retención
+func (e *Ecommerce) PurgarEliminados(retencion time.Duration) int {
+	ahora := time.Now()
+	total := len(e.usuarios.Purgar(retencion, ahora)) + len(e.ordenes.Purgar(retencion, ahora))
+	for _, id := range e.productos.Purgar(retencion, ahora) {
+		e.catalogo.Eliminar(id)
+		e.inventario.QuitarProducto(id)
+		total++
+	}
+	return total
+}
+
+// ==============================================
+// DEMOSTRACIÓN DE REPOSITORIOS
+// ==============================================
+
+func demostrarRepositorios(e *Ecommerce, producto *Producto) {
+	fmt.Println("\n🗃️ Repositorios con borrado lógico:")
+
+	// Dos "clientes" leen la misma versión y compiten por actualizar
+	version := producto.ObtenerVersion()
+	err := e.productos.Actualizar(producto.ID, version, func(p *Producto) error {
+		p.PrecioOferta = p.Precio * 0.9
+		return nil
+	})
+	fmt.Printf("  Primera actualización (v%d): %v\n", version, err)
+
+	err = e.productos.Actualizar(producto.ID, version, func(p *Producto) error {
+		p.PrecioOferta = p.Precio * 0.8
+		return nil
+	})
+	if errors.Is(err, ErrConflictoVersion) {
+		fmt.Printf("  ❌ Segunda actualización rechazada: %v\n", err)
+	}
+
+	if err := e.EliminarProducto(producto.ID); err != nil {
+		fmt.Printf("Error eliminando producto: %v\n", err)
+		return
+	}
+	if _, err := e.productos.Obtener(producto.ID); err != nil {
+		fmt.Printf("  🗑️ Tras eliminar: %v\n", err)
+	}
+	fmt.Printf("  Activos: %d, eliminados: %d\n", e.productos.Contar(), len(e.productos.ListarEliminados()))
+
+	if err := e.RestaurarProducto(producto.ID); err != nil {
+		fmt.Printf("Error restaurando producto: %v\n", err)
+	}
+	fmt.Printf("  ♻️ Restaurado %s (v%d)\n", producto.Nombre, producto.ObtenerVersion())
+
+	if err := e.EliminarProducto(producto.ID); err != nil {
+		fmt.Printf("Error eliminando producto: %v\n", err)
+	}
+	fmt.Printf("  Purgados con retención de 30 días: %d\n", e.PurgarEliminados(30*24*time.Hour))
+	fmt.Printf("  Purgados con retención cero: %d\n", e.PurgarEliminados(0))
+	_, enInventario := e.inventario.productos[producto.ID]
+	fmt.Printf("  ¿Sigue en el inventario tras purgar? %v\n", enInventario)
+}
@@ -0,0 +1,274 @@
+// Archivo: proyecto_ecommerce_reviews.go
+// Proyecto: Sistema de E-commerce - Moderación de reviews y compras verificadas
+// Demuestra: estados con tipos string, búsqueda de structs por puntero dentro
+// de slices, maps como conjuntos y cálculos ponderados
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ==============================================
+// ESTADOS DE MODERACIÓN
+// ==============================================
+
+type EstadoReview string
+
+const (
+	ReviewPendiente EstadoReview = "pendiente"
+	ReviewAprobada  EstadoReview = "aprobada"
+	ReviewRechazada EstadoReview = "rechazada"
+)
+
+// Parámetros de la calificación ponderada
+const (
+	pesoReviewVerificada   = 1.0
+	pesoReviewNoVerificada = 0.4
+	vidaMediaReview        = 180 * 24 * time.Hour
+)
+
+// ==============================================
+// OPERACIONES SOBRE REVIEWS DEL PRODUCTO
+// ==============================================
+
+func (p *Producto) buscarReview(reviewID string) (*Review, error) {
+	for i := range p.Reviews {
+		if p.Reviews[i].ID == reviewID {
+			return &p.Reviews[i], nil
+		}
+	}
+	return nil, errors.New("review no encontrada")
+}
+
+func (p Producto) ReviewDeUsuario(usuarioID string) (Review, bool) {
+	for _, review := range p.Reviews {
+		if review.UsuarioID == usuarioID && review.Estado != ReviewRechazada {
+			return review, true
+		}
+	}
+	return Review{}, false
+}
+
+func (p Producto) ReviewsAprobadas() []Review {
+	aprobadas := []Review{}
+	for _, review := range p.Reviews {
+		if review.Estado == ReviewAprobada {
+			aprobadas = append(aprobadas, review)
+		}
+	}
+	return aprobadas
+}
+
+// CalificacionPonderada promedia las reviews aprobadas dando menos peso a las
+// no verificadas y a las antiguas (el peso se reduce a la mitad cada vidaMediaReview)
+func (p Producto) CalificacionPonderada(ahora time.Time) float64 {
+	suma, pesos := 0.0, 0.0
+	for _, review := range p.Reviews {
+		if review.Estado != ReviewAprobada {
+			continue
+		}
+
+		peso := pesoReviewNoVerificada
+		if review.Verificado {
+			peso = pesoReviewVerificada
+		}
+		antiguedad := ahora.Sub(review.CreadoEn)
+		if antiguedad > 0 {
+			peso *= math.Pow(0.5, float64(antiguedad)/float64(vidaMediaReview))
+		}
+
+		suma += float64(review.Calificacion) * peso
+		pesos += peso
+	}
+
+	if pesos == 0 {
+		return 0
+	}
+	return suma / pesos
+}
+
+// ==============================================
+// FLUJO DE REVIEWS EN EL E-COMMERCE
+// ==============================================
+
+// ordenEntregadaCon busca una orden entregada del usuario que incluya el producto
+func (e *Ecommerce) ordenEntregadaCon(usuarioID, productoID string) (*Orden, bool) {
+	for _, orden := range e.ordenes.Listar() {
+		if orden.UsuarioID != usuarioID || orden.Estado != OrdenEntregada {
+			continue
+		}
+		for _, item := range orden.Items {
+			if item.ProductoID == productoID {
+				return orden, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// EnviarReview registra la review de un cliente como pendiente de moderación.
+// Se marca como compra verificada si existe una orden entregada con el producto.
+func (e *Ecommerce) EnviarReview(usuarioID, productoID string, calificacion int, titulo, comentario string) (Review, error) {
+	if !e.usuarios.Existe(usuarioID) {
+		return Review{}, errors.New("usuario no encontrado")
+	}
+	producto, err := e.productos.Obtener(productoID)
+	if err != nil {
+		return Review{}, errors.New("producto no encontrado")
+	}
+	if calificacion < 1 || calificacion > 5 {
+		return Review{}, errors.New("la calificación debe estar entre 1 y 5")
+	}
+	if strings.TrimSpace(comentario) == "" {
+		return Review{}, errors.New("comentario es requerido")
+	}
+	if _, yaExiste := producto.ReviewDeUsuario(usuarioID); yaExiste {
+		return Review{}, errors.New("el usuario ya tiene una review para este producto")
+	}
+
+	review := Review{
+		Identificable: Identificable{ID: generarID("REV")},
+		UsuarioID:     usuarioID,
+		Calificacion:  calificacion,
+		Titulo:        titulo,
+		Comentario:    comentario,
+		Estado:        ReviewPendiente,
+	}
+	if orden, verificada := e.ordenEntregadaCon(usuarioID, productoID); verificada {
+		review.Verificado = true
+		review.OrdenID = orden.ID
+	}
+
+	producto.agregarReview(review)
+	guardada, err := producto.buscarReview(review.ID)
+	if err != nil {
+		return Review{}, err
+	}
+	return *guardada, nil
+}
+
+// ReviewsPendientes devuelve las reviews en cola de moderación, de todos los productos
+func (e *Ecommerce) ReviewsPendientes() []Review {
+	pendientes := []Review{}
+	for _, producto := range e.productos.Listar() {
+		for _, review := range producto.Reviews {
+			if review.Estado == ReviewPendiente {
+				pendientes = append(pendientes, review)
+			}
+		}
+	}
+	return pendientes
+}
+
+func (e *Ecommerce) ModerarReview(productoID, reviewID string, aprobar bool, moderadorID, motivo string) error {
+	producto, err := e.productos.Obtener(productoID)
+	if err != nil {
+		return errors.New("producto no encontrado")
+	}
+	review, err := producto.buscarReview(reviewID)
+	if err != nil {
+		return err
+	}
+	if review.Estado != ReviewPendiente {
+		return fmt.Errorf("la review ya fue moderada (%s)", review.Estado)
+	}
+
+	if aprobar {
+		review.Estado = ReviewAprobada
+	} else {
+		if strings.TrimSpace(motivo) == "" {
+			return errors.New("el rechazo requiere un motivo")
+		}
+		review.Estado = ReviewRechazada
+		review.MotivoRechazo = motivo
+	}
+	review.ModeradoPor = moderadorID
+	review.MarcarActualizado()
+	producto.registrarCambio()
+	return nil
+}
+
+// VotarUtil suma un voto de "útil" a una review aprobada; cada usuario vota una vez
+func (e *Ecommerce) VotarUtil(productoID, reviewID, usuarioID string) error {
+	producto, err := e.productos.Obtener(productoID)
+	if err != nil {
+		return errors.New("producto no encontrado")
+	}
+	review, err := producto.buscarReview(reviewID)
+	if err != nil {
+		return err
+	}
+	if review.Estado != ReviewAprobada {
+		return errors.New("solo se puede votar reviews aprobadas")
+	}
+	if review.UsuarioID == usuarioID {
+		return errors.New("no puedes votar tu propia review")
+	}
+	if review.votantesUtil == nil {
+		review.votantesUtil = make(map[string]bool)
+	}
+	if review.votantesUtil[usuarioID] {
+		return errors.New("el usuario ya votó esta review")
+	}
+
+	review.votantesUtil[usuarioID] = true
+	review.Util++
+	producto.registrarCambio()
+	return nil
+}
+
+// ==============================================
+// DEMOSTRACIÓN DE MODERACIÓN
+// ==============================================
+
+func demostrarReviews(e *Ecommerce, compradorID, visitanteID string, producto *Producto) {
+	fmt.Println("\n📝 Reviews con moderación:")
+
+	verificada, err := e.EnviarReview(compradorID, producto.ID, 4, "Muy buena", "Rinde muy bien en juegos exigentes")
+	if err != nil {
+		fmt.Printf("Error enviando review: %v\n", err)
+		return
+	}
+	fmt.Printf("  Review de comprador: estado=%s verificada=%t\n", verificada.Estado, verificada.Verificado)
+
+	if _, err := e.EnviarReview(compradorID, producto.ID, 5, "Otra", "Segunda opinión"); err != nil {
+		fmt.Printf("  ❌ Segunda review rechazada: %v\n", err)
+	}
+
+	noVerificada, err := e.EnviarReview(visitanteID, producto.ID, 2, "No me convence", "Parece cara")
+	if err != nil {
+		fmt.Printf("Error enviando review: %v\n", err)
+		return
+	}
+	fmt.Printf("  Review de visitante: estado=%s verificada=%t\n", noVerificada.Estado, noVerificada.Verificado)
+	fmt.Printf("  Pendientes de moderación: %d\n", len(e.ReviewsPendientes()))
+
+	if err := e.ModerarReview(producto.ID, verificada.ID, true, "moderador", ""); err != nil {
+		fmt.Printf("Error moderando: %v\n", err)
+	}
+	if err := e.ModerarReview(producto.ID, noVerificada.ID, true, "moderador", ""); err != nil {
+		fmt.Printf("Error moderando: %v\n", err)
+	}
+
+	if err := e.VotarUtil(producto.ID, verificada.ID, visitanteID); err != nil {
+		fmt.Printf("Error votando: %v\n", err)
+	}
+	if err := e.VotarUtil(producto.ID, verificada.ID, visitanteID); err != nil {
+		fmt.Printf("  ❌ Voto duplicado: %v\n", err)
+	}
+
+	for _, review := range producto.ReviewsAprobadas() {
+		insignia := ""
+		if review.Verificado {
+			insignia = " ✔ compra verificada"
+		}
+		fmt.Printf("  ⭐%d %q (%d útil)%s\n", review.Calificacion, review.Titulo, review.Util, insignia)
+	}
+	fmt.Printf("  Promedio simple: %.2f | ponderado: %.2f\n",
+		producto.CalificacionPromedio(), producto.CalificacionPonderada(time.Now()))
+}
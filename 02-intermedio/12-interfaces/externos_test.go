@@ -0,0 +1,209 @@
+// 🧪 Tests de Integración: Plugins externos por JSON-RPC
+// Archivo: externos_test.go
+// Ejecutar con: go test -v proyecto_plugins*.go externos_test.go
+//
+// El binario de test hace de plugin: cuando se relanza con GODEEP_PLUGIN
+// definido, TestMain sirve el plugin por stdio en vez de ejecutar los tests
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	if serveExternalPluginFromEnv() {
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// ==========================================
+// 📦 FRAMING
+// ==========================================
+
+func TestReadFrame_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	for _, id := range []int64{1, 2} {
+		if err := writeFrame(&buf, rpcRequest{JSONRPC: "2.0", ID: id, Method: "ping"}); err != nil {
+			t.Fatalf("Expected no error writing frame, got: %v", err)
+		}
+	}
+
+	reader := bufio.NewReader(&buf)
+	for _, id := range []int64{1, 2} {
+		body, err := readFrame(reader)
+		if err != nil {
+			t.Fatalf("Expected no error reading frame %d, got: %v", id, err)
+		}
+		if want := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"ping"}`, id); string(body) != want {
+			t.Errorf("Expected body %s, got %s", want, body)
+		}
+	}
+	if _, err := readFrame(reader); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last frame, got: %v", err)
+	}
+}
+
+func TestReadFrame_RejectsInvalidFrames(t *testing.T) {
+	cases := map[string]string{
+		"sin cabecera": "X-Other: 1\r\n\r\n{}",
+		"no numérica":  "Content-Length: abc\r\n\r\n{}",
+		"negativa":     "Content-Length: -1\r\n\r\n{}",
+		"demasiado":    fmt.Sprintf("Content-Length: %d\r\n\r\n", rpcMaxFrameSize+1),
+		"truncado":     "Content-Length: 10\r\n\r\n{}",
+	}
+	for name, frame := range cases {
+		if _, err := readFrame(bufio.NewReader(strings.NewReader(frame))); err == nil {
+			t.Errorf("%s: expected an error for %q", name, frame)
+		}
+	}
+}
+
+// ==========================================
+// 🤝 HANDSHAKE
+// ==========================================
+
+// servePipe conecta un rpcClient con ServeExternalPlugin en el mismo proceso
+func servePipe(t *testing.T, plugin PluginInfo) (*rpcClient, <-chan error) {
+	t.Helper()
+	requestsR, requestsW := io.Pipe()
+	responsesR, responsesW := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		err := ServeExternalPlugin(plugin, requestsR, responsesW)
+		responsesW.Close()
+		served <- err
+	}()
+	t.Cleanup(func() { requestsW.Close() })
+	return newRPCClient(responsesR, requestsW), served
+}
+
+func newUppercase() *UppercaseProcessor {
+	return &UppercaseProcessor{BaseProcessor{name: "UppercaseProcessor", version: "1.2.0"}}
+}
+
+func TestServeExternalPlugin_Handshake(t *testing.T) {
+	client, served := servePipe(t, newUppercase())
+	ctx := context.Background()
+
+	var info HandshakeResult
+	err := client.Call(ctx, "handshake", HandshakeRequest{ProtocolVersions: []int{99, rpcProtocolVersion}}, &info)
+	if err != nil {
+		t.Fatalf("Expected handshake to succeed, got: %v", err)
+	}
+	if info.ProtocolVersion != rpcProtocolVersion || info.Name != "UppercaseProcessor" || info.Version != "1.2.0" {
+		t.Errorf("Unexpected handshake result: %+v", info)
+	}
+	if info.Capability != CapabilityProcessor || len(info.Formats) != 1 || info.Formats[0] != "text" {
+		t.Errorf("Expected processor capability with text format, got %s %v", info.Capability, info.Formats)
+	}
+
+	var rpcErr *rpcError
+	err = client.Call(ctx, "handshake", HandshakeRequest{ProtocolVersions: []int{99}}, &info)
+	if !errors.As(err, &rpcErr) {
+		t.Errorf("Expected an rpc error for unsupported versions, got: %v", err)
+	}
+
+	if err := client.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Fatalf("Expected shutdown to succeed, got: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Expected the server to stop cleanly after shutdown, got: %v", err)
+	}
+}
+
+// ==========================================
+// ♻️ SUPERVISIÓN DEL PROCESO
+// ==========================================
+
+func launchUppercase(t *testing.T, maxRestarts int) *externalProcessor {
+	t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Expected to locate the test binary, got: %v", err)
+	}
+	plugin, err := LaunchExternalPlugin(ExternalPluginConfig{
+		Path:           self,
+		Env:            []string{rpcPluginEnv + "=uppercase"},
+		CallTimeout:    2 * time.Second,
+		HealthInterval: 20 * time.Millisecond,
+		MaxRestarts:    maxRestarts,
+		RestartBackoff: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Expected the plugin to launch, got: %v", err)
+	}
+	t.Cleanup(func() { plugin.Shutdown() })
+	if err := plugin.Initialize(nil); err != nil {
+		t.Fatalf("Expected Initialize to succeed, got: %v", err)
+	}
+	return plugin.(*externalProcessor)
+}
+
+// killAndWait mata el proceso actual y espera a que el PID cambie
+func killAndWait(t *testing.T, plugin *externalProcessor) int {
+	t.Helper()
+	old := plugin.PID()
+	if proc, err := os.FindProcess(old); err == nil {
+		proc.Kill()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for plugin.PID() == old {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the supervisor to react to the crash of pid %d", old)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return plugin.PID()
+}
+
+func TestExternalPlugin_RestartsAfterCrash(t *testing.T) {
+	plugin := launchUppercase(t, 2)
+
+	if pid := killAndWait(t, plugin); pid == 0 {
+		t.Fatal("Expected a new process after the crash")
+	}
+	out, err := plugin.Process([]byte("hola"), "text")
+	if err != nil || string(out) != "HOLA" {
+		t.Errorf("Expected HOLA from the restarted process, got %q (err: %v)", out, err)
+	}
+	if plugin.Restarts() != 1 {
+		t.Errorf("Expected 1 restart, got %d", plugin.Restarts())
+	}
+}
+
+func TestExternalPlugin_ReenableAfterRestartLimitIsSupervised(t *testing.T) {
+	plugin := launchUppercase(t, 1)
+
+	// El primer crash consume el único reinicio; con el segundo el
+	// supervisor se rinde y deja el plugin apagado
+	killAndWait(t, plugin)
+	if pid := killAndWait(t, plugin); pid != 0 {
+		t.Fatalf("Expected no process after reaching the restart limit, got pid %d", pid)
+	}
+	if plugin.IsEnabled() {
+		t.Error("Expected the plugin to be disabled after reaching the restart limit")
+	}
+
+	// Rehabilitarlo debe lanzar también un supervisor nuevo que reinicie
+	// el proceso tras otro crash
+	if err := plugin.Initialize(nil); err != nil {
+		t.Fatalf("Expected Initialize to relaunch the process, got: %v", err)
+	}
+	if pid := killAndWait(t, plugin); pid == 0 {
+		t.Fatal("Expected the new supervisor to restart the process")
+	}
+	if out, err := plugin.Process([]byte("otra vez"), "text"); err != nil || string(out) != "OTRA VEZ" {
+		t.Errorf("Expected OTRA VEZ after the restart, got %q (err: %v)", out, err)
+	}
+}
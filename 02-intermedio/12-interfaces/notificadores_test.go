@@ -0,0 +1,579 @@
+// 🧪 Tests de Integración: Notificadores SMTP, Webhook y dispatcher
+// Archivo: notificadores_test.go
+// Ejecutar con: go test -v proyecto_plugins*.go notificadores_test.go
+
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ==========================================
+// 🧰 SERVIDOR SMTP FALSO
+// ==========================================
+
+type receivedEmail struct {
+	From     string
+	To       []string
+	Data     string
+	AuthUser string
+	AuthPass string
+	UsedTLS  bool
+}
+
+// fakeSMTPServer implementa lo justo de RFC 5321 para una sesión: EHLO,
+// STARTTLS, AUTH PLAIN, MAIL, RCPT, DATA y QUIT
+type fakeSMTPServer struct {
+	listener   net.Listener
+	tlsConfig  *tls.Config
+	username   string
+	password   string
+	rejectRcpt string
+	tempFail   bool
+
+	mu     sync.Mutex
+	emails []receivedEmail
+	wg     sync.WaitGroup
+}
+
+func newFakeSMTPServer(t *testing.T, serverTLS *tls.Config) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &fakeSMTPServer{listener: listener, tlsConfig: serverTLS, username: "bot", password: "s3creta"}
+	server.wg.Add(1)
+	go server.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		server.wg.Wait()
+	})
+	return server
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) received() []receivedEmail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedEmail{}, s.emails...)
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.session(conn)
+		}()
+	}
+}
+
+func (s *fakeSMTPServer) session(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	reply := func(lines ...string) {
+		for _, line := range lines {
+			io.WriteString(conn, line+"\r\n")
+		}
+	}
+
+	var email receivedEmail
+	authenticated := false
+	reply("220 fake.smtp ESMTP listo")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch {
+		case verb == "EHLO" || verb == "HELO":
+			if email.UsedTLS {
+				reply("250-fake.smtp", "250 AUTH PLAIN")
+			} else {
+				reply("250-fake.smtp", "250 STARTTLS")
+			}
+		case verb == "STARTTLS":
+			reply("220 listo para TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, reader = tlsConn, bufio.NewReader(tlsConn)
+			email.UsedTLS = true
+		case verb == "AUTH":
+			fields := strings.Fields(line)
+			if len(fields) != 3 || !strings.EqualFold(fields[1], "PLAIN") {
+				reply("504 mecanismo no soportado")
+				continue
+			}
+			decoded, _ := base64.StdEncoding.DecodeString(fields[2])
+			parts := strings.Split(string(decoded), "\x00")
+			if len(parts) == 3 {
+				email.AuthUser, email.AuthPass = parts[1], parts[2]
+			}
+			if email.AuthUser != s.username || email.AuthPass != s.password {
+				reply("535 5.7.8 credenciales inválidas")
+				continue
+			}
+			authenticated = true
+			reply("235 autenticado")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			if !authenticated {
+				reply("530 autenticación requerida")
+				continue
+			}
+			if s.tempFail {
+				reply("451 4.3.0 inténtelo más tarde")
+				continue
+			}
+			email.From = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			reply("250 OK")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			to := strings.Trim(line[len("RCPT TO:"):], "<> ")
+			if s.rejectRcpt != "" && to == s.rejectRcpt {
+				reply("550 5.1.1 buzón inexistente")
+				continue
+			}
+			email.To = append(email.To, to)
+			reply("250 OK")
+		case verb == "DATA":
+			reply("354 terminar con <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			email.Data = data.String()
+			s.mu.Lock()
+			s.emails = append(s.emails, email)
+			s.mu.Unlock()
+			reply("250 en cola")
+		case verb == "QUIT":
+			reply("221 adiós")
+			return
+		default:
+			reply("502 comando no implementado")
+		}
+	}
+}
+
+// newTestTLS genera un certificado autofirmado para 127.0.0.1 y devuelve
+// la configuración del servidor y la de un cliente que confía en él
+func newTestTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generando clave: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake.smtp"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creando certificado: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client := &tls.Config{RootCAs: pool}
+	return server, client
+}
+
+func newTestEmailNotifier(t *testing.T, server *fakeSMTPServer, clientTLS *tls.Config, password string) *EmailNotifier {
+	t.Helper()
+	notifier := NewEmailNotifier()
+	notifier.SetTLSConfig(clientTLS)
+	err := notifier.Initialize(map[string]interface{}{
+		"smtp_server": "127.0.0.1",
+		"port":        server.port(),
+		"username":    "bot",
+		"password":    password,
+		"from":        "Go Deep <alertas@go-deep.dev>",
+		"timeout":     2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return notifier
+}
+
+// ==========================================
+// 🧪 TESTS SMTP
+// ==========================================
+
+func TestEmailNotifier_SendsOverSTARTTLSWithAuth(t *testing.T) {
+	// Arrange
+	serverTLS, clientTLS := newTestTLS(t)
+	server := newFakeSMTPServer(t, serverTLS)
+	notifier := newTestEmailNotifier(t, server, clientTLS, "s3creta")
+	message := Message{ID: "msg-1", Subject: "Despliegue completado ✅", Body: "Versión 2.1 en producción.\nSin incidencias.", Type: EMAIL}
+
+	// Act
+	err := notifier.Send("ana@example.com", message)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	emails := server.received()
+	if len(emails) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(emails))
+	}
+	email := emails[0]
+	if !email.UsedTLS {
+		t.Error("Expected the session to be upgraded with STARTTLS")
+	}
+	if email.AuthUser != "bot" || email.AuthPass != "s3creta" {
+		t.Errorf("Expected AUTH PLAIN bot/s3creta, got %s/%s", email.AuthUser, email.AuthPass)
+	}
+	if email.From != "alertas@go-deep.dev" || len(email.To) != 1 || email.To[0] != "ana@example.com" {
+		t.Errorf("Unexpected envelope: from=%s to=%v", email.From, email.To)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(email.Data))
+	if err != nil {
+		t.Fatalf("Received data is not a valid RFC 5322 message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if subject != message.Subject {
+		t.Errorf("Expected subject %q, got %q", message.Subject, subject)
+	}
+	if got := parsed.Header.Get("Message-ID"); got != "<msg-1@go-deep.dev>" {
+		t.Errorf("Expected Message-ID <msg-1@go-deep.dev>, got %s", got)
+	}
+	if status := notifier.GetDeliveryStatus("msg-1"); !status.Delivered {
+		t.Errorf("Expected delivered status, got %+v", status)
+	}
+}
+
+func TestEmailNotifier_RejectedRecipientIsPermanent(t *testing.T) {
+	serverTLS, clientTLS := newTestTLS(t)
+	server := newFakeSMTPServer(t, serverTLS)
+	server.rejectRcpt = "nadie@example.com"
+	notifier := newTestEmailNotifier(t, server, clientTLS, "s3creta")
+
+	err := notifier.Send("nadie@example.com", Message{ID: "msg-2", Subject: "Hola", Type: EMAIL})
+
+	if !errors.Is(err, ErrPermanentFailure) {
+		t.Errorf("Expected permanent failure for 550, got: %v", err)
+	}
+	if len(server.received()) != 0 {
+		t.Error("Expected no email to be accepted")
+	}
+}
+
+func TestEmailNotifier_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		tempFail  bool
+		permanent bool
+	}{
+		{name: "wrong password", password: "incorrecta", permanent: true},
+		{name: "temporary failure", password: "s3creta", tempFail: true, permanent: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverTLS, clientTLS := newTestTLS(t)
+			server := newFakeSMTPServer(t, serverTLS)
+			server.tempFail = tt.tempFail
+			notifier := newTestEmailNotifier(t, server, clientTLS, tt.password)
+
+			err := notifier.Send("ana@example.com", Message{ID: "msg-3", Subject: "Hola", Type: EMAIL})
+
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if errors.Is(err, ErrPermanentFailure) != tt.permanent {
+				t.Errorf("Expected permanent=%t, got error: %v", tt.permanent, err)
+			}
+		})
+	}
+}
+
+func TestEmailNotifier_RefusesServerWithoutTrustedCertificate(t *testing.T) {
+	serverTLS, _ := newTestTLS(t)
+	server := newFakeSMTPServer(t, serverTLS)
+	notifier := newTestEmailNotifier(t, server, &tls.Config{}, "s3creta")
+
+	err := notifier.Send("ana@example.com", Message{ID: "msg-4", Subject: "Hola", Type: EMAIL})
+
+	if err == nil {
+		t.Fatal("Expected the TLS handshake to fail with an untrusted certificate")
+	}
+	if len(server.received()) != 0 {
+		t.Error("Expected no email to be sent without a trusted TLS session")
+	}
+}
+
+// ==========================================
+// 🧪 TESTS WEBHOOK
+// ==========================================
+
+func TestWebhookNotifier_SendsSignedSlackPayload(t *testing.T) {
+	// Arrange
+	var received WebhookPayload
+	var verifyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = VerifyWebhookSignature("clave-compartida", r.Header.Get(WebhookTimestampHeader),
+			r.Header.Get(WebhookSignatureHeader), body, 5*time.Minute, time.Now())
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewSlackNotifier()
+	err := notifier.Initialize(map[string]interface{}{"webhook_url": server.URL, "secret": "clave-compartida", "channel": "#alertas"})
+	if err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	// Act
+	err = notifier.Send("#deploys", Message{ID: "wh-1", Subject: "Deploy", Body: "v2.1 listo", Type: SLACK, Priority: 2})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if verifyErr != nil {
+		t.Errorf("Expected a valid signature, got: %v", verifyErr)
+	}
+	if received.Channel != "#deploys" || received.MessageID != "wh-1" || received.Text != "*Deploy*\nv2.1 listo" {
+		t.Errorf("Unexpected payload: %+v", received)
+	}
+}
+
+func TestWebhookNotifier_StatusClassification(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantErr   bool
+		permanent bool
+	}{
+		{status: http.StatusNoContent},
+		{status: http.StatusTooManyRequests, wantErr: true},
+		{status: http.StatusBadGateway, wantErr: true},
+		{status: http.StatusBadRequest, wantErr: true, permanent: true},
+		{status: http.StatusNotFound, wantErr: true, permanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			notifier := NewWebhookNotifier("Hook", server.URL, "")
+
+			err := notifier.Send("usuario-1", Message{ID: "wh-2", Body: "hola", Type: PUSH})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%t, got: %v", tt.wantErr, err)
+			}
+			if errors.Is(err, ErrPermanentFailure) != tt.permanent {
+				t.Errorf("Expected permanent=%t, got: %v", tt.permanent, err)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_ReloadWhileSending(t *testing.T) {
+	// Arrange: cada envío debe firmarse con un secreto completo, el viejo o el nuevo
+	secrets := []string{"secreto-a", "secreto-b"}
+	var mu sync.Mutex
+	var invalid int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		valid := false
+		for _, secret := range secrets {
+			err := VerifyWebhookSignature(secret, r.Header.Get(WebhookTimestampHeader),
+				r.Header.Get(WebhookSignatureHeader), body, time.Minute, time.Now())
+			valid = valid || err == nil
+		}
+		if !valid {
+			mu.Lock()
+			invalid++
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	notifier := NewWebhookNotifier("Hook", server.URL, secrets[0])
+
+	// Act: recargas en caliente mientras se envía (go test -race detecta los accesos sin lock)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			notifier.Initialize(map[string]interface{}{"secret": secrets[i%2], "channel": "#canal-" + strconv.Itoa(i)})
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if err := notifier.Send("#deploys", Message{ID: "wh-" + strconv.Itoa(i), Body: "hola", Type: PUSH}); err != nil {
+			t.Errorf("Expected send %d to succeed, got: %v", i, err)
+		}
+	}
+	wg.Wait()
+
+	// Assert
+	if invalid != 0 {
+		t.Errorf("Expected every request to carry a valid signature, got %d invalid", invalid)
+	}
+}
+
+func TestVerifyWebhookSignature_RejectsTamperingAndReplays(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"text":"hola"}`)
+	timestamp := now.Unix()
+	signature := SignWebhook("secreto", timestamp, body)
+	header := func(ts int64) string { return strconv.FormatInt(ts, 10) }
+
+	if err := VerifyWebhookSignature("secreto", header(timestamp), signature, body, time.Minute, now); err != nil {
+		t.Errorf("Expected valid signature, got: %v", err)
+	}
+	if err := VerifyWebhookSignature("secreto", header(timestamp), signature, []byte(`{"text":"adiós"}`), time.Minute, now); !errors.Is(err, ErrWebhookSignature) {
+		t.Errorf("Expected tampered body to be rejected, got: %v", err)
+	}
+	if err := VerifyWebhookSignature("otro", header(timestamp), signature, body, time.Minute, now); !errors.Is(err, ErrWebhookSignature) {
+		t.Errorf("Expected wrong secret to be rejected, got: %v", err)
+	}
+	if err := VerifyWebhookSignature("secreto", header(timestamp), signature, body, time.Minute, now.Add(10*time.Minute)); !errors.Is(err, ErrWebhookSignature) {
+		t.Errorf("Expected replayed request to be rejected, got: %v", err)
+	}
+}
+
+// ==========================================
+// 🧪 TESTS DISPATCHER
+// ==========================================
+
+func TestNotificationDispatcher_RecoversAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	deliveriesPath := filepath.Join(dir, "deliveries.jsonl")
+	lettersPath := filepath.Join(dir, "dead-letters.jsonl")
+
+	// Estado que deja un proceso que cae con un mensaje esperando su
+	// segundo intento, otro ya entregado y una carta en la dead-letter queue
+	store, err := OpenFileDeliveryStore(deliveriesPath)
+	if err != nil {
+		t.Fatalf("Expected the delivery store to open, got: %v", err)
+	}
+	retrying := Message{ID: "retrying-1", Type: EMAIL, Priority: 1}
+	done := Message{ID: "done-1", Type: EMAIL, Priority: 1}
+	store.Track(PendingDelivery{Message: retrying, Recipient: "ana@example.com", Notifier: "Mailer"})
+	store.Append(retrying.ID, DeliveryStatus{State: DeliveryRetrying, Attempt: 1, Notifier: "Mailer"})
+	store.Track(PendingDelivery{Message: done, Recipient: "ana@example.com", Notifier: "Mailer"})
+	store.Append(done.ID, DeliveryStatus{State: DeliveryDelivered, Delivered: true, Attempt: 1, Notifier: "Mailer"})
+	store.Close()
+
+	letters, err := OpenFileDeadLetterStore(lettersPath)
+	if err != nil {
+		t.Fatalf("Expected the dead-letter store to open, got: %v", err)
+	}
+	dead := Message{ID: "dead-1", Type: EMAIL, Priority: 2}
+	letters.Put(DeadLetter{Message: dead, Recipient: "luis@example.com", Notifier: "Mailer", Attempts: 3})
+	letters.Close()
+
+	torn, _ := os.OpenFile(deliveriesPath, os.O_WRONLY|os.O_APPEND, 0)
+	torn.WriteString(`{"message_id":"retrying-1","status":{"sta`)
+	torn.Close()
+
+	store, err = OpenFileDeliveryStore(deliveriesPath)
+	if err != nil {
+		t.Fatalf("Expected the torn last line to be dropped, got: %v", err)
+	}
+	defer store.Close()
+	letters, err = OpenFileDeadLetterStore(lettersPath)
+	if err != nil {
+		t.Fatalf("Expected the dead-letter store to reopen, got: %v", err)
+	}
+	defer letters.Close()
+	if got := letters.List(); len(got) != 1 || got[0].Message.ID != dead.ID {
+		t.Fatalf("Expected dead-1 to survive the restart, got %+v", got)
+	}
+
+	manager := NewPluginManager()
+	manager.RegisterPlugin(&flakyNotifier{BaseProcessor: BaseProcessor{name: "Mailer", version: "1.0.0"}, failures: map[string]int{}})
+	manager.InitializeAll()
+	dispatcher := NewNotificationDispatcher(manager, store, letters, DispatcherConfig{Workers: 1, MaxAttempts: 3})
+
+	if n := dispatcher.Recover(); n != 1 {
+		t.Fatalf("Expected 1 recovered delivery, got %d", n)
+	}
+	if err := dispatcher.Redrive(dead.ID); err != nil {
+		t.Fatalf("Expected dead-1 to be redriven, got: %v", err)
+	}
+	dispatcher.Start()
+	dispatcher.Close()
+
+	if status, _ := dispatcher.Status(retrying.ID); !status.Delivered || status.Attempt != 2 {
+		t.Errorf("Expected retrying-1 delivered on attempt 2, got %+v", status)
+	}
+	if status, _ := dispatcher.Status(dead.ID); !status.Delivered {
+		t.Errorf("Expected dead-1 delivered after redrive, got %+v", status)
+	}
+	if pending := store.Pending(); len(pending) != 0 {
+		t.Errorf("Expected no pending deliveries, got %+v", pending)
+	}
+
+	reopened, err := OpenFileDeadLetterStore(lettersPath)
+	if err != nil {
+		t.Fatalf("Expected the dead-letter store to reopen, got: %v", err)
+	}
+	defer reopened.Close()
+	if got := reopened.List(); len(got) != 0 {
+		t.Errorf("Expected the redriven letter to stay out after reopening, got %+v", got)
+	}
+}
@@ -5,11 +5,15 @@
 package main
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -117,9 +121,13 @@ type Message struct {
 }
 
 type DeliveryStatus struct {
-	Delivered bool
-	Timestamp time.Time
-	Error     string
+	Delivered   bool
+	Timestamp   time.Time
+	Error       string
+	State       DeliveryState
+	Attempt     int
+	Notifier    string
+	NextAttempt time.Time
 }
 
 // Interface para notificadores
@@ -134,78 +142,49 @@ type Notifier interface {
 // SISTEMA CORE - GESTIÓN DE PLUGINS
 // ==============================================
 
+// PluginManager guarda los plugins por nombre; las capacidades se descubren
+// por type assertion con Get y All (proyecto_plugins_registry.go)
 type PluginManager struct {
-	plugins        map[string]PluginInfo
-	processors     map[string]DataProcessor
-	loggers        map[string]Logger
-	authenticators map[string]Authenticator
-	notifiers      map[string]Notifier
-	mu             sync.RWMutex
+	plugins       map[string]PluginInfo
+	states        map[string]PluginState
+	initOrder     []string
+	configs       map[string]pluginSettings
+	configApplied bool
+	pipelineSteps []PipelineStep
+	handlers      map[int]func(PluginEvent)
+	nextHandler   int
+	mu            sync.RWMutex
+	// lifecycle serializa startPlugin, stopPlugin y ShutdownAll; Initialize
+	// y Shutdown corren sin pm.mu tomado
+	lifecycle sync.Mutex
+	watchers  map[*ConfigWatcher]struct{} // los detiene ShutdownAll
 }
 
 func NewPluginManager() *PluginManager {
 	return &PluginManager{
-		plugins:        make(map[string]PluginInfo),
-		processors:     make(map[string]DataProcessor),
-		loggers:        make(map[string]Logger),
-		authenticators: make(map[string]Authenticator),
-		notifiers:      make(map[string]Notifier),
+		plugins:  make(map[string]PluginInfo),
+		states:   make(map[string]PluginState),
+		configs:  make(map[string]pluginSettings),
+		handlers: make(map[int]func(PluginEvent)),
+		watchers: make(map[*ConfigWatcher]struct{}),
 	}
 }
 
+// RegisterPlugin solo registra el plugin; la inicialización ocurre en
+// InitializeAll, una vez que se conocen todas las dependencias
 func (pm *PluginManager) RegisterPlugin(plugin PluginInfo) error {
 	pm.mu.Lock()
-	defer pm.mu.Unlock()
-
 	name := plugin.Name()
-	pm.plugins[name] = plugin
-
-	// Registrar en categoría específica según tipo
-	if processor, ok := plugin.(DataProcessor); ok {
-		pm.processors[name] = processor
-	}
-	if logger, ok := plugin.(Logger); ok {
-		pm.loggers[name] = logger
+	if _, exists := pm.plugins[name]; exists {
+		pm.mu.Unlock()
+		return fmt.Errorf("plugin %s ya registrado", name)
 	}
-	if auth, ok := plugin.(Authenticator); ok {
-		pm.authenticators[name] = auth
-	}
-	if notifier, ok := plugin.(Notifier); ok {
-		pm.notifiers[name] = notifier
-	}
-
-	return plugin.Initialize(map[string]interface{}{
-		"plugin_name": name,
-		"timestamp":   time.Now(),
-	})
-}
-
-func (pm *PluginManager) GetProcessor(name string) (DataProcessor, bool) {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
-	processor, exists := pm.processors[name]
-	return processor, exists
-}
-
-func (pm *PluginManager) GetLogger(name string) (Logger, bool) {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
-	logger, exists := pm.loggers[name]
-	return logger, exists
-}
-
-func (pm *PluginManager) GetAuthenticator(name string) (Authenticator, bool) {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
-	auth, exists := pm.authenticators[name]
-	return auth, exists
-}
+	pm.plugins[name] = plugin
+	pm.states[name] = StateRegistered
+	pm.mu.Unlock()
 
-func (pm *PluginManager) GetNotifier(name string) (Notifier, bool) {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
-	notifier, exists := pm.notifiers[name]
-	return notifier, exists
+	pm.emit(EventRegistered, plugin)
+	return nil
 }
 
 func (pm *PluginManager) ListPlugins() []string {
@@ -230,7 +209,9 @@ type BaseProcessor struct {
 	description  string
 	author       string
 	dependencies []string
-	enabled      bool
+	// Lo escribe el manager al habilitar o deshabilitar y lo lee Get desde
+	// cualquier goroutine
+	enabled atomic.Bool
 }
 
 func (bp *BaseProcessor) Name() string           { return bp.name }
@@ -238,15 +219,15 @@ func (bp *BaseProcessor) Version() string        { return bp.version }
 func (bp *BaseProcessor) Description() string    { return bp.description }
 func (bp *BaseProcessor) Author() string         { return bp.author }
 func (bp *BaseProcessor) Dependencies() []string { return bp.dependencies }
-func (bp *BaseProcessor) IsEnabled() bool        { return bp.enabled }
+func (bp *BaseProcessor) IsEnabled() bool        { return bp.enabled.Load() }
 
 func (bp *BaseProcessor) Initialize(config map[string]interface{}) error {
-	bp.enabled = true
+	bp.enabled.Store(true)
 	return nil
 }
 
 func (bp *BaseProcessor) Shutdown() error {
-	bp.enabled = false
+	bp.enabled.Store(false)
 	return nil
 }
 
@@ -298,7 +279,9 @@ func (jp *JSONProcessor) Validate(data []byte, format string) error {
 	return json.Unmarshal(data, &jsonData)
 }
 
-// XML Processor (simulado)
+// XML Processor: valida con encoding/xml y devuelve el documento intacto. El
+// árbol que se usa para convertir entre formatos pierde atributos, el orden de
+// los elementos y el texto mixto, así que no se reescribe a partir de él
 type XMLProcessor struct {
 	BaseProcessor
 }
@@ -307,7 +290,7 @@ func NewXMLProcessor() *XMLProcessor {
 	return &XMLProcessor{
 		BaseProcessor: BaseProcessor{
 			name:        "XMLProcessor",
-			version:     "1.0.0",
+			version:     "1.1.0",
 			description: "Procesador de datos XML",
 			author:      "Go Deep Team",
 		},
@@ -323,9 +306,10 @@ func (xp *XMLProcessor) Process(data []byte, format string) ([]byte, error) {
 		return nil, fmt.Errorf("formato no soportado: %s", format)
 	}
 
-	// Simulación de procesamiento XML
-	processed := []byte(fmt.Sprintf("<!-- Procesado por XMLProcessor -->\n%s", string(data)))
-	return processed, nil
+	if _, _, err := decodeXML(data); err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
 func (xp *XMLProcessor) Validate(data []byte, format string) error {
@@ -333,13 +317,8 @@ func (xp *XMLProcessor) Validate(data []byte, format string) error {
 		return fmt.Errorf("formato no soportado: %s", format)
 	}
 
-	// Validación básica de XML
-	content := string(data)
-	if !strings.Contains(content, "<") || !strings.Contains(content, ">") {
-		return fmt.Errorf("formato XML inválido")
-	}
-
-	return nil
+	_, _, err := decodeXML(data)
+	return err
 }
 
 // ==============================================
@@ -349,20 +328,29 @@ func (xp *XMLProcessor) Validate(data []byte, format string) error {
 // Base común para loggers
 type BaseLogger struct {
 	BaseProcessor
-	level LogLevel
+	configMu sync.RWMutex // nivel, encoder y sampler cambian con una recarga en caliente
+	level    LogLevel
+	encoder  LogEncoder
+	color    bool // el formato text usa colores
+	sampler  *Sampler
 }
 
 func (bl *BaseLogger) SetLevel(level LogLevel) {
+	bl.configMu.Lock()
+	defer bl.configMu.Unlock()
 	bl.level = level
 }
 
 func (bl *BaseLogger) GetLevel() LogLevel {
+	bl.configMu.RLock()
+	defer bl.configMu.RUnlock()
 	return bl.level
 }
 
 // Console Logger
 type ConsoleLogger struct {
 	BaseLogger
+	out io.Writer
 }
 
 func NewConsoleLogger() *ConsoleLogger {
@@ -370,52 +358,37 @@ func NewConsoleLogger() *ConsoleLogger {
 		BaseLogger: BaseLogger{
 			BaseProcessor: BaseProcessor{
 				name:        "ConsoleLogger",
-				version:     "1.0.0",
+				version:     "1.1.0",
 				description: "Logger que envía output a la consola",
 				author:      "Go Deep Team",
 			},
-			level: INFO,
+			level:   INFO,
+			encoder: TextEncoder{Color: true},
+			color:   true,
 		},
+		out: os.Stdout,
 	}
 }
 
 func (cl *ConsoleLogger) Log(level LogLevel, message string, fields map[string]interface{}) {
-	if level < cl.level {
+	record, encoder, ok := cl.prepare(level, message, fields)
+	if !ok {
 		return
 	}
-
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	levelStr := level.String()
-
-	// Colores para diferentes niveles
-	var color string
-	switch level {
-	case DEBUG:
-		color = "\033[36m" // Cyan
-	case INFO:
-		color = "\033[32m" // Green
-	case WARN:
-		color = "\033[33m" // Yellow
-	case ERROR:
-		color = "\033[31m" // Red
-	case FATAL:
-		color = "\033[35m" // Magenta
-	}
-	reset := "\033[0m"
-
-	fmt.Printf("%s[%s] %s%s%s: %s", timestamp, levelStr, color, levelStr, reset, message)
-
-	if len(fields) > 0 {
-		fieldsJSON, _ := json.Marshal(fields)
-		fmt.Printf(" | Fields: %s", string(fieldsJSON))
-	}
-	fmt.Println()
+	cl.out.Write(encoder.Encode(record))
 }
 
-// File Logger (simulado)
+// File Logger: escritura asíncrona sobre un archivo con rotación
 type FileLogger struct {
 	BaseLogger
-	filename string
+	filename   string
+	rotation   RotationConfig
+	bufferSize int
+	dropPolicy DropPolicy
+	file       *RotatingFile
+	writer     *AsyncWriter
+	closed     bool // tras Shutdown no se reabre el archivo hasta otro Initialize
+	mu         sync.Mutex
 }
 
 func NewFileLogger() *FileLogger {
@@ -423,52 +396,90 @@ func NewFileLogger() *FileLogger {
 		BaseLogger: BaseLogger{
 			BaseProcessor: BaseProcessor{
 				name:        "FileLogger",
-				version:     "1.0.0",
+				version:     "1.1.0",
 				description: "Logger que escribe a archivos",
 				author:      "Go Deep Team",
 			},
-			level: INFO,
+			level:   INFO,
+			encoder: JSONEncoder{},
 		},
-		filename: "app.log",
+		filename:   "app.log",
+		rotation:   RotationConfig{MaxSize: 10 << 20, Interval: 24 * time.Hour, MaxBackups: 7, Compress: true},
+		bufferSize: 1024,
+		dropPolicy: DropNewest,
 	}
 }
 
 func (fl *FileLogger) Log(level LogLevel, message string, fields map[string]interface{}) {
-	if level < fl.level {
+	fl.mu.Lock()
+	if fl.closed {
+		fl.mu.Unlock()
 		return
 	}
-
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	logEntry := fmt.Sprintf("[%s] %s: %s", timestamp, level.String(), message)
-
-	if len(fields) > 0 {
-		fieldsJSON, _ := json.Marshal(fields)
-		logEntry += fmt.Sprintf(" | %s", string(fieldsJSON))
+	record, encoder, ok := fl.prepare(level, message, fields)
+	if !ok {
+		fl.mu.Unlock()
+		return
+	}
+	writer, err := fl.writerLocked()
+	var line []byte
+	if err == nil {
+		line = encoder.Encode(record)
 	}
+	fl.mu.Unlock()
 
-	// Simulamos escritura a archivo
-	fmt.Printf("📄 [FileLogger -> %s] %s\n", fl.filename, logEntry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FileLogger: %v\n", err)
+		return
+	}
+	// Un Shutdown concurrente cierra el writer y esta escritura se descarta
+	writer.Write(line)
 }
 
 // ==============================================
 // IMPLEMENTACIÓN: AUTHENTICATORS
 // ==============================================
 
-// JWT Authenticator (simulado)
+// JWT Authenticator: firma con la clave activa del KeySet y delega las
+// credenciales en un UserStore
 type JWTAuthenticator struct {
 	BaseProcessor
-	secretKey string
+	keys     *KeySet
+	users    UserStore
+	refresh  *refreshStore
+	configMu sync.RWMutex // una recarga en caliente no se cruza con una emisión
+	jwtSettings
+	now func() time.Time
+}
+
+// jwtSettings son los claims y plazos configurables del JWTAuthenticator
+type jwtSettings struct {
+	issuer     string
+	audience   string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	clockSkew  time.Duration
 }
 
-func NewJWTAuthenticator() *JWTAuthenticator {
+func NewJWTAuthenticator(users UserStore, keys *KeySet) *JWTAuthenticator {
 	return &JWTAuthenticator{
 		BaseProcessor: BaseProcessor{
 			name:        "JWTAuthenticator",
-			version:     "1.0.0",
-			description: "Autenticador basado en JWT",
+			version:     "2.0.0",
+			description: "Autenticador basado en JWT (HS256, RS256, ES256)",
 			author:      "Go Deep Team",
 		},
-		secretKey: "super-secret-key",
+		keys:    keys,
+		users:   users,
+		refresh: newRefreshStore(),
+		jwtSettings: jwtSettings{
+			issuer:     "go-deep",
+			audience:   "go-deep-api",
+			accessTTL:  15 * time.Minute,
+			refreshTTL: 30 * 24 * time.Hour,
+			clockSkew:  30 * time.Second,
+		},
+		now: time.Now,
 	}
 }
 
@@ -483,155 +494,117 @@ func (ja *JWTAuthenticator) Authenticate(credentials map[string]string) (User, e
 		return User{}, fmt.Errorf("password requerido")
 	}
 
-	// Simulación de autenticación
-	if username == "admin" && password == "secret" {
-		return User{
-			ID:       "1",
-			Username: username,
-			Email:    "admin@example.com",
-			Roles:    []string{"admin", "user"},
-		}, nil
-	}
-
-	if username == "user" && password == "pass" {
-		return User{
-			ID:       "2",
-			Username: username,
-			Email:    "user@example.com",
-			Roles:    []string{"user"},
-		}, nil
-	}
-
-	return User{}, fmt.Errorf("credenciales inválidas")
+	return ja.users.VerifyCredentials(username, password)
 }
 
+// ValidateToken verifica firma y claims, y que el usuario siga existiendo
 func (ja *JWTAuthenticator) ValidateToken(token string) (User, error) {
-	// Simulación de validación de token
-	if token == "valid-jwt-token" {
-		return User{
-			ID:       "1",
-			Username: "admin",
-			Email:    "admin@example.com",
-			Roles:    []string{"admin", "user"},
-		}, nil
+	claims, err := VerifyToken(ja.keys, token, ja.verifyOptions())
+	if err != nil {
+		return User{}, err
 	}
-
-	return User{}, fmt.Errorf("token inválido")
+	return ja.users.GetUser(claims.Subject)
 }
 
+// RefreshToken recibe un refresh token y emite un nuevo access token
 func (ja *JWTAuthenticator) RefreshToken(token string) (string, error) {
-	user, err := ja.ValidateToken(token)
+	userID, err := ja.refresh.lookup(token, ja.now())
+	if err != nil {
+		return "", err
+	}
+	user, err := ja.users.GetUser(userID)
 	if err != nil {
 		return "", err
 	}
 
-	// Simulación de refresh
-	newToken := fmt.Sprintf("new-jwt-token-for-%s", user.Username)
-	return newToken, nil
+	access, _, err := ja.issueAccessToken(ja.settings(), user)
+	return access, err
 }
 
 // ==============================================
 // IMPLEMENTACIÓN: NOTIFIERS
 // ==============================================
 
-// Email Notifier
+// Email Notifier: SMTP con STARTTLS y AUTH PLAIN (ver proyecto_plugins_smtp.go)
 type EmailNotifier struct {
 	BaseProcessor
+	deliveryTracker
+	configMu sync.RWMutex // una recarga en caliente no se cruza con un envío
+	smtpSettings
+}
+
+// smtpSettings es la configuración del EmailNotifier; cada envío trabaja
+// sobre una copia
+type smtpSettings struct {
 	smtpServer string
 	port       int
+	tlsMode    string
+	tlsConfig  *tls.Config
+	username   string
+	password   string
+	from       string
+	timeout    time.Duration
 }
 
 func NewEmailNotifier() *EmailNotifier {
 	return &EmailNotifier{
 		BaseProcessor: BaseProcessor{
 			name:        "EmailNotifier",
-			version:     "1.0.0",
+			version:     "1.1.0",
 			description: "Notificador vía email",
 			author:      "Go Deep Team",
 		},
-		smtpServer: "smtp.example.com",
-		port:       587,
+		smtpSettings: smtpSettings{
+			port:    587,
+			tlsMode: SMTPStartTLS,
+			from:    "no-reply@go-deep.dev",
+			timeout: 10 * time.Second,
+		},
 	}
 }
 
-func (en *EmailNotifier) Send(recipient string, message Message) error {
-	// Simulación de envío de email
-	fmt.Printf("📧 [EmailNotifier] Enviando email a: %s\n", recipient)
-	fmt.Printf("   Asunto: %s\n", message.Subject)
-	fmt.Printf("   Mensaje: %s\n", message.Body)
-	fmt.Printf("   Servidor: %s:%d\n", en.smtpServer, en.port)
-
-	return nil
-}
-
 func (en *EmailNotifier) SupportedTypes() []MessageType {
 	return []MessageType{EMAIL}
 }
 
-func (en *EmailNotifier) GetDeliveryStatus(messageID string) DeliveryStatus {
-	return DeliveryStatus{
-		Delivered: true,
-		Timestamp: time.Now(),
-		Error:     "",
-	}
-}
-
-// Slack Notifier
+// Slack Notifier: webhook con el formato de Slack (ver proyecto_plugins_webhook.go)
 type SlackNotifier struct {
-	BaseProcessor
-	webhookURL string
-	channel    string
+	*WebhookNotifier
 }
 
 func NewSlackNotifier() *SlackNotifier {
-	return &SlackNotifier{
-		BaseProcessor: BaseProcessor{
-			name:        "SlackNotifier",
-			version:     "1.0.0",
-			description: "Notificador vía Slack",
-			author:      "Go Deep Team",
-		},
-		webhookURL: "https://hooks.slack.com/services/...",
-		channel:    "#general",
-	}
-}
-
-func (sn *SlackNotifier) Send(recipient string, message Message) error {
-	// Simulación de envío a Slack
-	fmt.Printf("💬 [SlackNotifier] Enviando a Slack\n")
-	fmt.Printf("   Canal: %s\n", sn.channel)
-	fmt.Printf("   Mensaje: %s\n", message.Body)
-	fmt.Printf("   Webhook: %s\n", sn.webhookURL)
-
-	return nil
-}
-
-func (sn *SlackNotifier) SupportedTypes() []MessageType {
-	return []MessageType{SLACK}
-}
-
-func (sn *SlackNotifier) GetDeliveryStatus(messageID string) DeliveryStatus {
-	return DeliveryStatus{
-		Delivered: true,
-		Timestamp: time.Now(),
-		Error:     "",
-	}
+	webhook := NewWebhookNotifier("SlackNotifier", "", "")
+	webhook.version = "1.1.0"
+	webhook.description = "Notificador vía Slack"
+	webhook.channel = "#general"
+	return &SlackNotifier{WebhookNotifier: webhook}
 }
 
 // ==============================================
 // SISTEMA DE PIPELINE DE PROCESAMIENTO
 // ==============================================
 
+// ProcessingPipeline solo guarda la definición del grafo: cada ejecución
+// devuelve su salida en el PipelineReport, así que varias pueden correr a la
+// vez sobre el mismo pipeline
 type ProcessingPipeline struct {
 	manager *PluginManager
 	steps   []PipelineStep
 }
 
+// PipelineStep es un nodo del grafo; DependsOn vacío lo convierte en raíz y
+// recibe la entrada del pipeline
 type PipelineStep struct {
 	Name       string
 	PluginName string
 	PluginType string
 	Config     map[string]interface{}
+	DependsOn  []string
+	When       *StepCondition
+	Timeout    time.Duration
+	OnError    ErrorPolicy
+	MaxRetries int
+	RetryDelay time.Duration
 }
 
 func NewProcessingPipeline(manager *PluginManager) *ProcessingPipeline {
@@ -641,62 +614,24 @@ func NewProcessingPipeline(manager *PluginManager) *ProcessingPipeline {
 	}
 }
 
+// AddStep encadena el paso después del último agregado
 func (pp *ProcessingPipeline) AddStep(name, pluginName, pluginType string, config map[string]interface{}) {
-	pp.steps = append(pp.steps, PipelineStep{
+	step := PipelineStep{
 		Name:       name,
 		PluginName: pluginName,
 		PluginType: pluginType,
 		Config:     config,
-	})
-}
-
-func (pp *ProcessingPipeline) Execute(data []byte, format string) error {
-	fmt.Printf("🚀 Iniciando pipeline de procesamiento...\n")
-	fmt.Printf("📊 Datos de entrada: %d bytes, formato: %s\n\n", len(data), format)
-
-	currentData := data
-
-	for i, step := range pp.steps {
-		fmt.Printf("📍 Paso %d: %s (Plugin: %s)\n", i+1, step.Name, step.PluginName)
-
-		switch step.PluginType {
-		case "processor":
-			if processor, exists := pp.manager.GetProcessor(step.PluginName); exists {
-				processed, err := processor.Process(currentData, format)
-				if err != nil {
-					return fmt.Errorf("error en paso %d: %v", i+1, err)
-				}
-				currentData = processed
-				fmt.Printf("   ✅ Procesado exitosamente (%d bytes)\n", len(processed))
-			}
-
-		case "logger":
-			if logger, exists := pp.manager.GetLogger(step.PluginName); exists {
-				logger.Log(INFO, fmt.Sprintf("Pipeline paso %d completado", i+1), map[string]interface{}{
-					"step":      step.Name,
-					"data_size": len(currentData),
-				})
-			}
-
-		case "notifier":
-			if notifier, exists := pp.manager.GetNotifier(step.PluginName); exists {
-				message := Message{
-					ID:      fmt.Sprintf("pipeline-%d", time.Now().Unix()),
-					Subject: "Pipeline Step Completed",
-					Body:    fmt.Sprintf("Completado paso: %s", step.Name),
-					Type:    EMAIL,
-				}
-				notifier.Send("admin@example.com", message)
-			}
-		}
-
-		fmt.Println()
 	}
+	if len(pp.steps) > 0 {
+		step.DependsOn = []string{pp.steps[len(pp.steps)-1].Name}
+	}
+	pp.steps = append(pp.steps, step)
+}
 
-	fmt.Printf("🎉 Pipeline completado exitosamente!\n")
-	fmt.Printf("📤 Datos finales: %d bytes\n\n", len(currentData))
-
-	return nil
+// Add agrega el paso tal cual, con sus dependencias, condición y políticas
+func (pp *ProcessingPipeline) Add(step PipelineStep) *ProcessingPipeline {
+	pp.steps = append(pp.steps, step)
+	return pp
 }
 
 // ==============================================
@@ -704,20 +639,35 @@ func (pp *ProcessingPipeline) Execute(data []byte, format string) error {
 // ==============================================
 
 func main() {
+	// Cuando el binario se relanza como plugin externo solo sirve RPC por stdio
+	if serveExternalPluginFromEnv() {
+		return
+	}
+
 	fmt.Println("🔌 SISTEMA DE PLUGINS MODULARES")
-	fmt.Println("===============================\n")
+	fmt.Println("===============================")
+	fmt.Println()
 
 	// Crear manager y registrar plugins
 	manager := NewPluginManager()
 
 	// Registrar procesadores
 	fmt.Println("📦 Registrando plugins...")
+	users := NewInMemoryUserStore()
+	users.AddUser(User{ID: "1", Username: "admin", Email: "admin@example.com", Roles: []string{"admin", "user"}}, "secret")
+	users.AddUser(User{ID: "2", Username: "user", Email: "user@example.com", Roles: []string{"user"}}, "pass")
+
+	keys := NewKeySet()
+	if signingKey, err := GenerateECKey("es256-1"); err == nil {
+		keys.Add(signingKey)
+	}
+
 	plugins := []PluginInfo{
 		NewJSONProcessor(),
 		NewXMLProcessor(),
 		NewConsoleLogger(),
 		NewFileLogger(),
-		NewJWTAuthenticator(),
+		NewJWTAuthenticator(users, keys),
 		NewEmailNotifier(),
 		NewSlackNotifier(),
 	}
@@ -730,16 +680,31 @@ func main() {
 		}
 	}
 
+	if err := manager.InitializeAll(); err != nil {
+		log.Printf("Error inicializando plugins: %v", err)
+	}
+	fmt.Printf("🔗 Orden de inicialización: %s\n", strings.Join(manager.InitializationOrder(), " → "))
+
 	fmt.Printf("\n📋 Total de plugins registrados: %d\n", len(manager.ListPlugins()))
 	fmt.Println()
 
+	demoLifecycle()
+	demoExternalPlugins()
+	demoConfigReload()
+	demoJWT()
+	demoFormatChain()
+	demoStructuredLogging()
+	demoNotificationDispatcher()
+	demoPipelineDAG()
+	demoCapabilityRegistry()
+
 	// Demostración 1: Procesamiento de datos
 	fmt.Println("🔄 DEMO 1: Procesamiento de Datos")
 	fmt.Println("=================================")
 
 	jsonData := `{"name":"John","age":30,"active":true}`
 
-	if processor, exists := manager.GetProcessor("JSONProcessor"); exists {
+	if processor, exists := Get[DataProcessor](manager, "JSONProcessor"); exists {
 		fmt.Printf("📊 Procesando JSON con %s...\n", processor.Name())
 
 		if err := processor.Validate([]byte(jsonData), "json"); err != nil {
@@ -761,7 +726,7 @@ func main() {
 	fmt.Println("📝 DEMO 2: Sistema de Logging")
 	fmt.Println("=============================")
 
-	if logger, exists := manager.GetLogger("ConsoleLogger"); exists {
+	if logger, exists := Get[Logger](manager, "ConsoleLogger"); exists {
 		logger.Log(INFO, "Sistema iniciado correctamente", map[string]interface{}{
 			"plugins": len(manager.ListPlugins()),
 			"tiempo":  time.Now(),
@@ -782,7 +747,7 @@ func main() {
 	fmt.Println("🔐 DEMO 3: Sistema de Autenticación")
 	fmt.Println("===================================")
 
-	if auth, exists := manager.GetAuthenticator("JWTAuthenticator"); exists {
+	if auth, exists := Get[Authenticator](manager, "JWTAuthenticator"); exists {
 		// Autenticación exitosa
 		credentials := map[string]string{
 			"username": "admin",
@@ -795,13 +760,21 @@ func main() {
 		} else {
 			fmt.Printf("✅ Usuario autenticado: %s (%s)\n", user.Username, user.Email)
 			fmt.Printf("   Roles: %v\n", user.Roles)
+		}
 
-			// Validar token
-			token := "valid-jwt-token"
-			if validUser, err := auth.ValidateToken(token); err == nil {
-				fmt.Printf("✅ Token válido para: %s\n", validUser.Username)
+		// Emitir y validar tokens
+		if jwtAuth, ok := auth.(*JWTAuthenticator); ok {
+			pair, err := jwtAuth.Login("admin", "secret")
+			if err != nil {
+				fmt.Printf("❌ Login falló: %v\n", err)
+			} else if validUser, err := auth.ValidateToken(pair.AccessToken); err == nil {
+				fmt.Printf("✅ Token válido para: %s (expira %s)\n", validUser.Username, pair.ExpiresAt.Format("15:04:05"))
 			}
 		}
+
+		if _, err := auth.ValidateToken("valid-jwt-token"); err != nil {
+			fmt.Printf("❌ Token inventado rechazado: %v\n", err)
+		}
 	}
 	fmt.Println()
 
@@ -818,12 +791,12 @@ func main() {
 	}
 
 	// Enviar por email
-	if emailNotifier, exists := manager.GetNotifier("EmailNotifier"); exists {
+	if emailNotifier, exists := Get[Notifier](manager, "EmailNotifier"); exists {
 		emailNotifier.Send("admin@example.com", message)
 	}
 
 	// Enviar por Slack
-	if slackNotifier, exists := manager.GetNotifier("SlackNotifier"); exists {
+	if slackNotifier, exists := Get[Notifier](manager, "SlackNotifier"); exists {
 		slackMessage := message
 		slackMessage.Type = SLACK
 		slackNotifier.Send("#general", slackMessage)
@@ -840,16 +813,27 @@ func main() {
 	pipeline.AddStep("Notificar admin", "EmailNotifier", "notifier", nil)
 
 	complexJSON := `{"users":[{"id":1,"name":"Ana"},{"id":2,"name":"Carlos"}],"total":2}`
-	pipeline.Execute([]byte(complexJSON), "json")
+	report, err := pipeline.Execute([]byte(complexJSON), "json")
+	if err != nil {
+		fmt.Printf("❌ Pipeline falló: %v\n", err)
+	}
+	if report != nil {
+		fmt.Println(report)
+	}
+	fmt.Println()
 
 	// Resumen final
 	fmt.Println("📊 RESUMEN DEL SISTEMA")
 	fmt.Println("=====================")
 	fmt.Printf("🔌 Total plugins: %d\n", len(manager.ListPlugins()))
-	fmt.Printf("📊 Procesadores: %d\n", len(manager.processors))
-	fmt.Printf("📝 Loggers: %d\n", len(manager.loggers))
-	fmt.Printf("🔐 Autenticadores: %d\n", len(manager.authenticators))
-	fmt.Printf("📢 Notificadores: %d\n", len(manager.notifiers))
+	fmt.Printf("📊 Procesadores: %d\n", len(All[DataProcessor](manager)))
+	fmt.Printf("📝 Loggers: %d\n", len(All[Logger](manager)))
+	fmt.Printf("🔐 Autenticadores: %d\n", len(All[Authenticator](manager)))
+	fmt.Printf("📢 Notificadores: %d\n", len(All[Notifier](manager)))
+
+	if err := manager.ShutdownAll(2 * time.Second); err != nil {
+		log.Printf("Error apagando plugins: %v", err)
+	}
 
 	fmt.Println("\n✅ Sistema de plugins funcionando correctamente!")
 	fmt.Println("🎉 Demostración completada exitosamente!")
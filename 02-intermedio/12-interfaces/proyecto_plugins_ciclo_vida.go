@@ -0,0 +1,617 @@
+// Archivo: proyecto_plugins_ciclo_vida.go
+// Proyecto: Sistema de Plugins - Ciclo de vida ordenado por dependencias
+// Demuestra: ordenamiento topológico, detección de ciclos, restricciones de
+// versión semver y apagado con timeouts y errores agregados
+
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ==============================================
+// ESTADOS DEL CICLO DE VIDA
+// ==============================================
+
+type PluginState string
+
+const (
+	StateRegistered  PluginState = "registered"
+	StateInitialized PluginState = "initialized"
+	StateFailed      PluginState = "failed"
+	StateStopped     PluginState = "stopped"
+	StateDisabled    PluginState = "disabled"
+)
+
+var (
+	ErrMissingDependency = errors.New("dependencia no registrada")
+	ErrDependencyCycle   = errors.New("ciclo de dependencias")
+	ErrVersionConstraint = errors.New("versión incompatible")
+	ErrShutdownTimeout   = errors.New("timeout en shutdown")
+)
+
+// ==============================================
+// VERSIONES SEMÁNTICAS
+// ==============================================
+
+type SemVer struct {
+	Major, Minor, Patch int
+	PreRelease          string
+}
+
+func ParseSemVer(s string) (SemVer, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i] // los metadatos de build no afectan la precedencia
+	}
+
+	var v SemVer
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.PreRelease = s[i+1:]
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 || parts[0] == "" {
+		return SemVer{}, fmt.Errorf("versión inválida: %q", s)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return SemVer{}, fmt.Errorf("versión inválida: %q", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// Compare devuelve -1, 0 o 1; una pre-release es menor que su versión final
+func (v SemVer) Compare(o SemVer) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.PreRelease == o.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case o.PreRelease == "":
+		return -1
+	}
+	return comparePreRelease(v.PreRelease, o.PreRelease)
+}
+
+// comparePreRelease sigue las reglas de SemVer: identificadores separados por
+// puntos; los numéricos se comparan como números y van antes que los
+// alfanuméricos, y con prefijo común gana el que tiene más identificadores
+// (alpha.10 > alpha.9 > alpha)
+func comparePreRelease(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.ParseUint(pa[i], 10, 64)
+		nb, errB := strconv.ParseUint(pb[i], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return cmp.Compare(na, nb)
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(pa[i], pb[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(pa), len(pb))
+}
+
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	return s
+}
+
+type comparator struct {
+	op      string
+	version SemVer
+}
+
+func (c comparator) matches(v SemVer) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// VersionConstraint es una disyunción (||) de conjunciones de comparadores,
+// p. ej. ">=1.2.0 <2.0.0 || ^3.1"
+type VersionConstraint struct {
+	raw  string
+	sets [][]comparator
+}
+
+func ParseVersionConstraint(s string) (VersionConstraint, error) {
+	c := VersionConstraint{raw: strings.TrimSpace(s)}
+	if c.raw == "" || c.raw == "*" {
+		return c, nil
+	}
+
+	for _, alternative := range strings.Split(c.raw, "||") {
+		var set []comparator
+		for _, term := range strings.Fields(alternative) {
+			comps, err := parseComparator(term)
+			if err != nil {
+				return VersionConstraint{}, err
+			}
+			set = append(set, comps...)
+		}
+		if len(set) == 0 {
+			return VersionConstraint{}, fmt.Errorf("restricción vacía en %q", s)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+func parseComparator(term string) ([]comparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, candidate) {
+			op = candidate
+			break
+		}
+	}
+	raw := strings.TrimPrefix(term, op)
+	v, err := ParseSemVer(raw)
+	if err != nil {
+		return nil, err
+	}
+	precision := len(strings.Split(strings.SplitN(strings.TrimPrefix(raw, "v"), "-", 2)[0], "."))
+
+	switch op {
+	case "^":
+		// Compatible con la primera componente distinta de cero
+		upper := SemVer{Major: v.Major + 1}
+		if v.Major == 0 && precision > 1 {
+			upper = SemVer{Minor: v.Minor + 1}
+			if v.Minor == 0 && precision > 2 {
+				upper = SemVer{Patch: v.Patch + 1}
+			}
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case "~":
+		upper := SemVer{Major: v.Major, Minor: v.Minor + 1}
+		if precision == 1 {
+			upper = SemVer{Major: v.Major + 1}
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case "":
+		op = "="
+	}
+	return []comparator{{op, v}}, nil
+}
+
+func (c VersionConstraint) Allows(v SemVer) bool {
+	if len(c.sets) == 0 {
+		return true
+	}
+	for _, set := range c.sets {
+		ok := true
+		for _, comp := range set {
+			if !comp.matches(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c VersionConstraint) String() string {
+	if c.raw == "" {
+		return "*"
+	}
+	return c.raw
+}
+
+// Dependency es una entrada de PluginInfo.Dependencies(): "Nombre" o
+// "Nombre <restricción>", p. ej. "ConsoleLogger ^1.0"
+type Dependency struct {
+	Name       string
+	Constraint VersionConstraint
+}
+
+func ParseDependency(s string) (Dependency, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Dependency{}, errors.New("dependencia vacía")
+	}
+	constraint, err := ParseVersionConstraint(strings.Join(fields[1:], " "))
+	if err != nil {
+		return Dependency{}, fmt.Errorf("dependencia %q: %w", s, err)
+	}
+	return Dependency{Name: fields[0], Constraint: constraint}, nil
+}
+
+// ==============================================
+// RESOLUCIÓN DEL ORDEN DE INICIALIZACIÓN
+// ==============================================
+
+// resolveOrder valida dependencias y versiones y devuelve un orden topológico
+// estable (alfabético entre plugins independientes)
+func resolveOrder(plugins map[string]PluginInfo) ([]string, error) {
+	graph := make(map[string][]string, len(plugins))
+	var errs []error
+
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, raw := range plugins[name].Dependencies() {
+			dep, err := ParseDependency(raw)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			target, exists := plugins[dep.Name]
+			if !exists {
+				errs = append(errs, fmt.Errorf("%s requiere %s: %w", name, dep.Name, ErrMissingDependency))
+				continue
+			}
+			version, err := ParseSemVer(target.Version())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", dep.Name, err))
+				continue
+			}
+			if !dep.Constraint.Allows(version) {
+				errs = append(errs, fmt.Errorf("%s requiere %s %s, encontrada %s: %w",
+					name, dep.Name, dep.Constraint, version, ErrVersionConstraint))
+				continue
+			}
+			graph[name] = append(graph[name], dep.Name)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// DFS con colores: gris = en la pila actual, negro = ya ordenado
+	const (
+		white = iota
+		gray
+		black
+	)
+	color := make(map[string]int, len(names))
+	order := make([]string, 0, len(names))
+	var stack []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch color[name] {
+		case gray:
+			start := 0
+			for i, n := range stack {
+				if n == name {
+					start = i
+				}
+			}
+			cycle := append(append([]string{}, stack[start:]...), name)
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+		case black:
+			return nil
+		}
+
+		color[name] = gray
+		stack = append(stack, name)
+		deps := append([]string{}, graph[name]...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		color[name] = black
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// ==============================================
+// INICIALIZACIÓN Y APAGADO DEL MANAGER
+// ==============================================
+
+// InitializeAll inicializa los plugins pendientes respetando sus dependencias.
+// Si un plugin falla, los que dependen de él no se inicializan.
+func (pm *PluginManager) InitializeAll() error {
+	pm.mu.Lock()
+	order, err := resolveOrder(pm.plugins)
+	if err != nil {
+		pm.mu.Unlock()
+		return err
+	}
+	pm.mu.Unlock()
+
+	var errs []error
+	for _, name := range order {
+		if pm.State(name) == StateInitialized {
+			continue
+		}
+		if !pm.pluginEnabled(name) {
+			pm.setState(name, StateDisabled)
+			continue
+		}
+		if err := pm.startPlugin(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// startPlugin inicializa un plugin si todas sus dependencias están activas
+func (pm *PluginManager) startPlugin(name string) error {
+	pm.lifecycle.Lock()
+	defer pm.lifecycle.Unlock()
+
+	plugin, exists := pm.GetPlugin(name)
+	if !exists {
+		return fmt.Errorf("plugin %s no registrado", name)
+	}
+	// Otra llamada concurrente pudo haberlo iniciado mientras se esperaba
+	if pm.State(name) == StateInitialized {
+		return nil
+	}
+	for _, raw := range plugin.Dependencies() {
+		dep, _ := ParseDependency(raw)
+		if pm.State(dep.Name) != StateInitialized {
+			pm.setState(name, StateFailed)
+			return fmt.Errorf("%s no inicializado: dependencia %s no disponible", name, dep.Name)
+		}
+	}
+
+	if err := plugin.Initialize(pm.initConfig(name)); err != nil {
+		pm.setState(name, StateFailed)
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	pm.mu.Lock()
+	pm.states[name] = StateInitialized
+	pm.initOrder = append(pm.initOrder, name)
+	pm.mu.Unlock()
+	pm.emit(EventEnabled, plugin)
+	return nil
+}
+
+// stopPlugin apaga un plugin activo y lo saca del orden de apagado; queda
+// deshabilitado o detenido según su configuración
+func (pm *PluginManager) stopPlugin(name string) error {
+	pm.lifecycle.Lock()
+	defer pm.lifecycle.Unlock()
+	return pm.stopPluginLocked(name)
+}
+
+// stopPluginLocked es stopPlugin para quien ya tiene pm.lifecycle
+func (pm *PluginManager) stopPluginLocked(name string) error {
+	plugin, exists := pm.GetPlugin(name)
+	if !exists {
+		return fmt.Errorf("plugin %s no registrado", name)
+	}
+
+	var err error
+	wasActive := pm.State(name) == StateInitialized
+	if wasActive {
+		err = plugin.Shutdown()
+	}
+
+	pm.mu.Lock()
+	for i, n := range pm.initOrder {
+		if n == name {
+			pm.initOrder = append(pm.initOrder[:i], pm.initOrder[i+1:]...)
+			break
+		}
+	}
+	pm.states[name] = StateStopped
+	if s, configured := pm.configs[name]; configured && !s.enabled {
+		pm.states[name] = StateDisabled
+	}
+	pm.mu.Unlock()
+
+	if wasActive {
+		pm.emit(EventDisabled, plugin)
+	}
+	return err
+}
+
+// ShutdownAll apaga los plugins en orden inverso al de inicialización. Cada
+// Shutdown tiene su propio timeout; un plugin que no responde se da por
+// abandonado, queda en StateFailed y el apagado continúa con el siguiente.
+func (pm *PluginManager) ShutdownAll(timeout time.Duration) error {
+	// Los watchers se detienen antes de tomar lifecycle: una recarga en
+	// curso lo necesita para terminar, y después no deben volver a
+	// arrancar plugins ya apagados
+	pm.mu.RLock()
+	watchers := make([]*ConfigWatcher, 0, len(pm.watchers))
+	for w := range pm.watchers {
+		watchers = append(watchers, w)
+	}
+	pm.mu.RUnlock()
+	for _, w := range watchers {
+		w.Stop()
+	}
+
+	pm.lifecycle.Lock()
+	defer pm.lifecycle.Unlock()
+
+	pm.mu.Lock()
+	order := append([]string{}, pm.initOrder...)
+	pm.initOrder = nil
+	pm.mu.Unlock()
+
+	var errs []error
+	for i := len(order) - 1; i >= 0; i-- {
+		name := order[i]
+		plugin, exists := pm.GetPlugin(name)
+		if !exists {
+			continue
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- plugin.Shutdown() }()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			pm.setState(name, StateStopped)
+			pm.emit(EventDisabled, plugin)
+		case <-time.After(timeout):
+			errs = append(errs, fmt.Errorf("%s: %w (%v)", name, ErrShutdownTimeout, timeout))
+			pm.setState(name, StateFailed)
+			pm.emit(EventFailed, plugin)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// initConfig combina la configuración declarada para el plugin con los
+// valores que el manager siempre provee
+func (pm *PluginManager) initConfig(name string) map[string]interface{} {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	config := make(map[string]interface{}, len(pm.configs[name].config)+2)
+	for key, value := range pm.configs[name].config {
+		config[key] = value
+	}
+	config["plugin_name"] = name
+	config["timestamp"] = time.Now()
+	return config
+}
+
+func (pm *PluginManager) State(name string) PluginState {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.states[name]
+}
+
+func (pm *PluginManager) setState(name string, state PluginState) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.states[name] = state
+}
+
+func (pm *PluginManager) GetPlugin(name string) (PluginInfo, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	plugin, exists := pm.plugins[name]
+	return plugin, exists
+}
+
+// InitializationOrder devuelve el orden en que se inicializaron los plugins
+func (pm *PluginManager) InitializationOrder() []string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return append([]string{}, pm.initOrder...)
+}
+
+// ==============================================
+// DEMOSTRACIÓN DEL CICLO DE VIDA
+// ==============================================
+
+// slowPlugin simula un plugin cuyo Shutdown tarda más que el timeout
+type slowPlugin struct {
+	BaseProcessor
+	delay time.Duration
+}
+
+func (sp *slowPlugin) Shutdown() error {
+	time.Sleep(sp.delay)
+	return sp.BaseProcessor.Shutdown()
+}
+
+func demoLifecycle() {
+	fmt.Println("🔗 DEMO: Ciclo de Vida con Dependencias")
+	fmt.Println("======================================")
+
+	newPlugin := func(name, version string, deps ...string) *BaseProcessor {
+		return &BaseProcessor{name: name, version: version, dependencies: deps}
+	}
+
+	// Dependencias faltantes y versiones incompatibles se reportan juntas
+	broken := NewPluginManager()
+	broken.RegisterPlugin(newPlugin("Cache", "1.0.0", "Storage ^2.0"))
+	broken.RegisterPlugin(newPlugin("Storage", "1.4.2"))
+	broken.RegisterPlugin(newPlugin("API", "1.0.0", "Auth"))
+	if err := broken.InitializeAll(); err != nil {
+		fmt.Printf("❌ Errores de resolución:\n   %s\n",
+			strings.ReplaceAll(err.Error(), "\n", "\n   "))
+	}
+
+	cyclic := NewPluginManager()
+	cyclic.RegisterPlugin(newPlugin("A", "1.0.0", "B"))
+	cyclic.RegisterPlugin(newPlugin("B", "1.0.0", "C >=1.0.0"))
+	cyclic.RegisterPlugin(newPlugin("C", "1.0.0", "A"))
+	if err := cyclic.InitializeAll(); errors.Is(err, ErrDependencyCycle) {
+		fmt.Printf("❌ %v\n", err)
+	}
+
+	// Los identificadores numéricos de una pre-release se comparan como números
+	alpha9, _ := ParseSemVer("1.0.0-alpha.9")
+	alpha10, _ := ParseSemVer("1.0.0-alpha.10")
+	fmt.Printf("🔢 %s < %s: %v\n", alpha9, alpha10, alpha9.Compare(alpha10) < 0)
+
+	ok := NewPluginManager()
+	ok.RegisterPlugin(newPlugin("API", "2.1.0", "Auth ~1.2", "Metrics"))
+	ok.RegisterPlugin(newPlugin("Auth", "1.2.5", "Storage >=1.0.0 <2.0.0"))
+	ok.RegisterPlugin(newPlugin("Storage", "1.4.2"))
+	ok.RegisterPlugin(&slowPlugin{BaseProcessor: BaseProcessor{name: "Metrics", version: "0.3.0"}, delay: 200 * time.Millisecond})
+	if err := ok.InitializeAll(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+	fmt.Printf("✅ Orden de inicialización: %s\n", strings.Join(ok.InitializationOrder(), " → "))
+
+	if err := ok.ShutdownAll(50 * time.Millisecond); err != nil {
+		fmt.Printf("⚠️ Shutdown con errores: %v\n", err)
+	}
+	fmt.Printf("🛑 Estado tras shutdown: API %s, Metrics %s\n\n", ok.State("API"), ok.State("Metrics"))
+}
@@ -0,0 +1,710 @@
+// Archivo: proyecto_plugins_config.go
+// Proyecto: Sistema de Plugins - Configuración declarativa y recarga en caliente
+// Demuestra: decodificación JSON/YAML a structs, validación por esquema con
+// interfaces opcionales, y un watcher por polling que solo reinicia lo que cambió
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// FORMATO DEL ARCHIVO DE CONFIGURACIÓN
+// ==============================================
+
+var ErrInvalidConfig = errors.New("configuración inválida")
+
+type PluginsConfig struct {
+	Plugins  []PluginConfig       `json:"plugins"`
+	Pipeline []PipelineStepConfig `json:"pipeline"`
+}
+
+type PluginConfig struct {
+	Name    string                 `json:"name"`
+	Enabled *bool                  `json:"enabled"` // nil equivale a true; sin entrada, deshabilitado
+	Config  map[string]interface{} `json:"config"`
+}
+
+// PipelineStepConfig describe un paso; sin depends_on el paso sigue al
+// anterior del archivo y con "depends_on: []" es una raíz del grafo
+type PipelineStepConfig struct {
+	Name         string                 `json:"name"`
+	Plugin       string                 `json:"plugin"`
+	Type         string                 `json:"type"`
+	Config       map[string]interface{} `json:"config"`
+	DependsOn    []string               `json:"depends_on"`
+	WhenFormat   []string               `json:"when_format"`
+	WhenContains string                 `json:"when_contains"`
+	Timeout      string                 `json:"timeout"`
+	OnError      string                 `json:"on_error"`
+	Retries      int                    `json:"retries"`
+}
+
+// pluginSettings es la configuración ya validada y con defaults aplicados
+type pluginSettings struct {
+	enabled bool
+	config  map[string]interface{}
+}
+
+// LoadPluginsConfig lee un archivo .json, .yaml o .yml
+func LoadPluginsConfig(path string) (*PluginsConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePluginsConfig(data, strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+func ParsePluginsConfig(data []byte, format string) (*PluginsConfig, error) {
+	switch strings.ToLower(format) {
+	case "json":
+	case "yaml", "yml":
+		tree, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+		// El árbol YAML tiene la misma forma que el de JSON: se reutilizan los tags
+		if data, err = json.Marshal(tree); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	default:
+		return nil, fmt.Errorf("formato de configuración no soportado: %q", format)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var cfg PluginsConfig
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return &cfg, nil
+}
+
+// ==============================================
+// ESQUEMAS DE CONFIGURACIÓN
+// ==============================================
+
+type ConfigField struct {
+	Type     string // string, int, float, bool, duration, list
+	Required bool
+	Default  interface{}
+	Enum     []string
+	Min      *float64
+	Max      *float64
+}
+
+type ConfigSchema map[string]ConfigField
+
+// ConfigurablePlugin es opcional: los plugins sin esquema aceptan cualquier mapa
+type ConfigurablePlugin interface {
+	ConfigSchema() ConfigSchema
+}
+
+func floatPtr(f float64) *float64 { return &f }
+
+// Validate comprueba tipos, rangos y valores permitidos, rechaza claves
+// desconocidas y devuelve una copia normalizada con los defaults aplicados
+func (s ConfigSchema) Validate(config map[string]interface{}) (map[string]interface{}, error) {
+	var errs []error
+	normalized := make(map[string]interface{}, len(s))
+
+	for key := range config {
+		if _, known := s[key]; !known {
+			errs = append(errs, fmt.Errorf("%s: clave desconocida", key))
+		}
+	}
+
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field := s[key]
+		raw, present := config[key]
+		if !present || raw == nil {
+			switch {
+			case field.Default != nil:
+				raw = field.Default
+			case field.Required:
+				errs = append(errs, fmt.Errorf("%s: campo requerido", key))
+				continue
+			default:
+				continue
+			}
+		}
+
+		value, err := field.normalize(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		normalized[key] = value
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return normalized, nil
+}
+
+func (f ConfigField) normalize(raw interface{}) (interface{}, error) {
+	switch f.Type {
+	case "string":
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("se esperaba string, se obtuvo %T", raw)
+		}
+		if len(f.Enum) > 0 && !containsString(f.Enum, s) {
+			return nil, fmt.Errorf("valor %q no permitido (opciones: %s)", s, strings.Join(f.Enum, ", "))
+		}
+		return s, nil
+
+	case "int", "float":
+		var n float64
+		switch v := raw.(type) {
+		case float64:
+			n = v
+		case int:
+			n = float64(v)
+		default:
+			return nil, fmt.Errorf("se esperaba número, se obtuvo %T", raw)
+		}
+		if f.Min != nil && n < *f.Min {
+			return nil, fmt.Errorf("%v es menor que el mínimo %v", n, *f.Min)
+		}
+		if f.Max != nil && n > *f.Max {
+			return nil, fmt.Errorf("%v es mayor que el máximo %v", n, *f.Max)
+		}
+		if f.Type == "float" {
+			return n, nil
+		}
+		if n != float64(int(n)) {
+			return nil, fmt.Errorf("se esperaba un entero, se obtuvo %v", n)
+		}
+		return int(n), nil
+
+	case "bool":
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("se esperaba bool, se obtuvo %T", raw)
+		}
+		return b, nil
+
+	case "duration":
+		if d, ok := raw.(time.Duration); ok {
+			return d, nil
+		}
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("se esperaba una duración como \"5s\", se obtuvo %T", raw)
+		}
+		return time.ParseDuration(s)
+
+	case "list":
+		items, ok := raw.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("se esperaba lista, se obtuvo %T", raw)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("tipo de esquema desconocido %q", f.Type)
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// ==============================================
+// APLICACIÓN DE LA CONFIGURACIÓN AL MANAGER
+// ==============================================
+
+// ApplyConfig valida todo el archivo antes de tocar ningún plugin: si hay un
+// error, la configuración anterior sigue vigente. Devuelve los plugins cuya
+// configuración cambió; solo esos se reinician. Los plugins registrados que
+// no aparecen en el archivo quedan deshabilitados con su configuración por
+// defecto, también si se quitaron en una recarga.
+func (pm *PluginManager) ApplyConfig(cfg *PluginsConfig) ([]string, error) {
+	settings, steps, err := pm.validateConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pm.mu.Lock()
+	order, err := resolveOrder(pm.plugins)
+	pm.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Se deshabilita en orden inverso y se (re)inicializa en orden de dependencias
+	var changed, toStart []string
+	var errs []error
+	for i := len(order) - 1; i >= 0; i-- {
+		name := order[i]
+		next, configured := settings[name]
+		if !configured {
+			continue
+		}
+
+		pm.mu.Lock()
+		prev, had := pm.configs[name]
+		pm.configs[name] = next
+		state := pm.states[name]
+		pm.mu.Unlock()
+
+		if had && reflect.DeepEqual(prev, next) {
+			continue
+		}
+		changed = append(changed, name)
+
+		// Los plugins nunca iniciados tomarán la configuración en InitializeAll
+		if state == StateRegistered {
+			continue
+		}
+		if err := pm.stopPlugin(name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		if next.enabled {
+			toStart = append([]string{name}, toStart...)
+		}
+	}
+	for _, name := range toStart {
+		if err := pm.startPlugin(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	pm.mu.Lock()
+	pm.pipelineSteps = steps
+	pm.configApplied = true
+	pm.mu.Unlock()
+
+	sort.Strings(changed)
+	return changed, errors.Join(errs...)
+}
+
+func (pm *PluginManager) validateConfig(cfg *PluginsConfig) (map[string]pluginSettings, []PipelineStep, error) {
+	var errs []error
+	settings := make(map[string]pluginSettings, len(cfg.Plugins))
+	listed := make(map[string]bool, len(cfg.Plugins))
+
+	for _, pc := range cfg.Plugins {
+		if listed[pc.Name] {
+			errs = append(errs, fmt.Errorf("plugin %q configurado dos veces", pc.Name))
+			continue
+		}
+		listed[pc.Name] = true
+		plugin, exists := pm.GetPlugin(pc.Name)
+		if !exists {
+			errs = append(errs, fmt.Errorf("plugin %q no registrado", pc.Name))
+			continue
+		}
+
+		config := pc.Config
+		if configurable, ok := plugin.(ConfigurablePlugin); ok {
+			normalized, err := configurable.ConfigSchema().Validate(pc.Config)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", pc.Name, err))
+				continue
+			}
+			config = normalized
+		}
+		settings[pc.Name] = pluginSettings{enabled: pc.Enabled == nil || *pc.Enabled, config: config}
+	}
+	for _, name := range pm.ListPlugins() {
+		if !listed[name] {
+			plugin, _ := pm.GetPlugin(name)
+			settings[name] = pluginSettings{enabled: false, config: defaultPluginConfig(plugin)}
+		}
+	}
+
+	// Un plugin habilitado no puede depender de uno deshabilitado
+	for name, s := range settings {
+		if !s.enabled {
+			continue
+		}
+		plugin, _ := pm.GetPlugin(name)
+		for _, raw := range plugin.Dependencies() {
+			dep, err := ParseDependency(raw)
+			if err == nil && !pm.enabledIn(settings, dep.Name) {
+				errs = append(errs, fmt.Errorf("%s depende de %s, que está deshabilitado", name, dep.Name))
+			}
+		}
+	}
+
+	var steps []PipelineStep
+	previous := ""
+	for i, sc := range cfg.Pipeline {
+		name := sc.Name
+		if name == "" {
+			name = sc.Plugin
+		}
+		dependsOn := sc.DependsOn
+		if dependsOn == nil && previous != "" {
+			dependsOn = []string{previous}
+		}
+		previous = name
+
+		plugin, exists := pm.GetPlugin(sc.Plugin)
+		if !exists {
+			errs = append(errs, fmt.Errorf("pipeline paso %d: plugin %q no registrado", i+1, sc.Plugin))
+			continue
+		}
+		if !pluginHasType(plugin, sc.Type) {
+			errs = append(errs, fmt.Errorf("pipeline paso %d: %s no es de tipo %q", i+1, sc.Plugin, sc.Type))
+			continue
+		}
+		if !pm.enabledIn(settings, sc.Plugin) {
+			errs = append(errs, fmt.Errorf("pipeline paso %d: %s está deshabilitado", i+1, sc.Plugin))
+			continue
+		}
+		step, err := pipelineStepFromConfig(name, dependsOn, sc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pipeline paso %d: %w", i+1, err))
+			continue
+		}
+		steps = append(steps, step)
+	}
+	if len(errs) == 0 {
+		if _, err := planSteps(steps); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+	}
+	return settings, steps, nil
+}
+
+func pipelineStepFromConfig(name string, dependsOn []string, sc PipelineStepConfig) (PipelineStep, error) {
+	step := PipelineStep{
+		Name:       name,
+		PluginName: sc.Plugin,
+		PluginType: sc.Type,
+		Config:     sc.Config,
+		DependsOn:  dependsOn,
+		OnError:    ErrorPolicy(sc.OnError),
+		MaxRetries: sc.Retries,
+	}
+	if sc.OnError != "" && !containsString(errorPolicyValues, sc.OnError) {
+		return step, fmt.Errorf("on_error %q inválido (valores: %s)", sc.OnError, strings.Join(errorPolicyValues, ", "))
+	}
+	if sc.Retries < 0 || (sc.Retries > 0 && step.OnError != OnErrorRetry) {
+		return step, fmt.Errorf("retries requiere on_error: retry y un valor positivo")
+	}
+	if sc.Timeout != "" {
+		timeout, err := time.ParseDuration(sc.Timeout)
+		if err != nil || timeout <= 0 {
+			return step, fmt.Errorf("timeout %q inválido", sc.Timeout)
+		}
+		step.Timeout = timeout
+	}
+	if len(sc.WhenFormat) > 0 || sc.WhenContains != "" {
+		step.When = &StepCondition{Formats: sc.WhenFormat, Contains: sc.WhenContains}
+	}
+	return step, nil
+}
+
+// enabledIn consulta primero la configuración nueva y después la vigente
+func (pm *PluginManager) enabledIn(settings map[string]pluginSettings, name string) bool {
+	if s, ok := settings[name]; ok {
+		return s.enabled
+	}
+	return pm.pluginEnabled(name)
+}
+
+// pluginEnabled: sin archivo aplicado todo plugin está habilitado; con
+// archivo, solo los que aparecen en él
+func (pm *PluginManager) pluginEnabled(name string) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	if s, configured := pm.configs[name]; configured {
+		return s.enabled
+	}
+	return !pm.configApplied
+}
+
+// defaultPluginConfig es la configuración de un plugin sin entrada en el
+// archivo: los defaults de su esquema, o ninguna si no tiene
+func defaultPluginConfig(plugin PluginInfo) map[string]interface{} {
+	if configurable, ok := plugin.(ConfigurablePlugin); ok {
+		if defaults, err := configurable.ConfigSchema().Validate(nil); err == nil {
+			return defaults
+		}
+	}
+	return nil
+}
+
+// PipelineFromConfig construye el pipeline con los pasos del último
+// archivo aplicado
+func (pm *PluginManager) PipelineFromConfig() *ProcessingPipeline {
+	pipeline := NewProcessingPipeline(pm)
+	pm.mu.RLock()
+	pipeline.steps = append(pipeline.steps, pm.pipelineSteps...)
+	pm.mu.RUnlock()
+	return pipeline
+}
+
+// ==============================================
+// WATCHER DEL ARCHIVO DE CONFIGURACIÓN
+// ==============================================
+
+// ConfigWatcher revisa el archivo periódicamente y compara su contenido por
+// hash; la fecha de modificación no basta con escrituras en el mismo segundo
+type ConfigWatcher struct {
+	path     string
+	interval time.Duration
+	manager  *PluginManager
+	onReload func(changed []string, err error)
+	lastHash [sha256.Size]byte
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// WatchConfig empieza a vigilar path; se asume que el contenido actual ya
+// fue aplicado. onReload recibe el resultado de cada recarga.
+func (pm *PluginManager) WatchConfig(path string, interval time.Duration, onReload func([]string, error)) (*ConfigWatcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &ConfigWatcher{
+		path:     path,
+		interval: interval,
+		manager:  pm,
+		onReload: onReload,
+		lastHash: sha256.Sum256(data),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	pm.mu.Lock()
+	pm.watchers[w] = struct{}{}
+	pm.mu.Unlock()
+	go w.run()
+	return w, nil
+}
+
+func (w *ConfigWatcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+func (w *ConfigWatcher) check() {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		// Un editor puede reemplazar el archivo; se reintenta en el siguiente tick
+		return
+	}
+	hash := sha256.Sum256(data)
+	if hash == w.lastHash {
+		return
+	}
+	// Se recuerda aunque falle, para reportar un archivo inválido una sola vez
+	w.lastHash = hash
+
+	cfg, err := ParsePluginsConfig(data, strings.TrimPrefix(filepath.Ext(w.path), "."))
+	if err != nil {
+		w.onReload(nil, err)
+		return
+	}
+	w.onReload(w.manager.ApplyConfig(cfg))
+}
+
+// Stop espera a que termine la recarga en curso; se puede llamar más de
+// una vez, y ShutdownAll lo llama por su cuenta
+func (w *ConfigWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		w.manager.mu.Lock()
+		delete(w.manager.watchers, w)
+		w.manager.mu.Unlock()
+	})
+	<-w.done
+}
+
+// ==============================================
+// CONFIGURACIÓN DE LOS PLUGINS INCLUIDOS
+// ==============================================
+
+func ParseLogLevel(s string) (LogLevel, error) {
+	for level := DEBUG; level <= FATAL; level++ {
+		if strings.EqualFold(level.String(), s) {
+			return level, nil
+		}
+	}
+	return INFO, fmt.Errorf("nivel de log desconocido: %q", s)
+}
+
+var logLevelNames = []string{"debug", "info", "warn", "error", "fatal"}
+
+func (bl *BaseLogger) ConfigSchema() ConfigSchema {
+	return ConfigSchema{
+		"level":  {Type: "string", Default: "info", Enum: logLevelNames},
+		"format": {Type: "string", Enum: []string{"text", "json", "logfmt"}},
+	}
+}
+
+func (bl *BaseLogger) Initialize(config map[string]interface{}) error {
+	bl.configMu.Lock()
+	defer bl.configMu.Unlock()
+
+	level, encoder := bl.level, bl.encoder
+	if raw, ok := config["level"].(string); ok {
+		parsed, err := ParseLogLevel(raw)
+		if err != nil {
+			return err
+		}
+		level = parsed
+	}
+	if format, ok := config["format"].(string); ok {
+		parsed, err := encoderForFormat(format, bl.color)
+		if err != nil {
+			return err
+		}
+		encoder = parsed
+	}
+	bl.level, bl.encoder = level, encoder
+	return bl.BaseProcessor.Initialize(config)
+}
+
+// ==============================================
+// DEMOSTRACIÓN DE CONFIGURACIÓN DECLARATIVA
+// ==============================================
+
+const demoConfigYAML = `# Plugins habilitados y su configuración
+plugins:
+  - name: JSONProcessor
+  - name: ConsoleLogger
+    config:
+      level: warn
+  - name: FileLogger
+    config:
+      filename: "plugins.log"
+  - name: EmailNotifier
+    config:
+      smtp_server: smtp.go-deep.dev
+      port: 2525
+  - name: SlackNotifier
+    enabled: false
+    config:
+      webhook_url: https://hooks.slack.com/services/demo
+
+pipeline:
+  - name: Procesar JSON
+    plugin: JSONProcessor
+    type: processor
+    timeout: 2s
+  - name: Log resultado
+    plugin: ConsoleLogger
+    type: logger
+    on_error: skip
+`
+
+func demoConfigReload() {
+	fmt.Println("🗂️ DEMO: Configuración Declarativa y Recarga en Caliente")
+	fmt.Println("======================================================")
+
+	dir, err := os.MkdirTemp("", "plugins-config-*")
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plugins.yaml")
+	if err := os.WriteFile(path, []byte(demoConfigYAML), 0o644); err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+
+	manager := NewPluginManager()
+	consoleLogger := NewConsoleLogger()
+	for _, plugin := range []PluginInfo{NewJSONProcessor(), consoleLogger, NewFileLogger(), NewEmailNotifier(), NewSlackNotifier()} {
+		manager.RegisterPlugin(plugin)
+	}
+
+	cfg, err := LoadPluginsConfig(path)
+	if err == nil {
+		_, err = manager.ApplyConfig(cfg)
+	}
+	if err == nil {
+		err = manager.InitializeAll()
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	fmt.Printf("✅ Inicializados: %s\n", strings.Join(manager.InitializationOrder(), ", "))
+	fmt.Printf("   SlackNotifier: %s | nivel ConsoleLogger: %s\n", manager.State("SlackNotifier"), consoleLogger.GetLevel())
+	fmt.Printf("   Pasos del pipeline: %d\n", len(manager.PipelineFromConfig().steps))
+
+	reloads := make(chan error, 1)
+	watcher, err := manager.WatchConfig(path, 20*time.Millisecond, func(changed []string, err error) {
+		if err == nil {
+			fmt.Printf("🔄 Recargado, plugins reiniciados: %s\n", strings.Join(changed, ", "))
+		}
+		reloads <- err
+	})
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	defer watcher.Stop()
+
+	// Cambian el nivel del logger y se habilita Slack; Email no se toca
+	updated := strings.NewReplacer("level: warn", "level: debug", "enabled: false", "enabled: true").Replace(demoConfigYAML)
+	os.WriteFile(path, []byte(updated), 0o644)
+	if err := <-reloads; err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+	fmt.Printf("   SlackNotifier: %s | nivel ConsoleLogger: %s\n", manager.State("SlackNotifier"), consoleLogger.GetLevel())
+
+	// Un archivo inválido se rechaza completo y se conserva la configuración vigente
+	invalid := strings.NewReplacer("port: 2525", "port: 70000", "level: debug", "level: verbose").Replace(updated)
+	os.WriteFile(path, []byte(invalid), 0o644)
+	if err := <-reloads; errors.Is(err, ErrInvalidConfig) {
+		fmt.Printf("❌ Recarga rechazada:\n   %s\n", strings.ReplaceAll(err.Error(), "\n", "\n   "))
+	}
+	fmt.Printf("   Nivel vigente de ConsoleLogger: %s\n", consoleLogger.GetLevel())
+
+	// Quitar una entrada deshabilita el plugin y devuelve su configuración a los defaults
+	withoutEmail := strings.Replace(updated, "  - name: EmailNotifier\n    config:\n      smtp_server: smtp.go-deep.dev\n      port: 2525\n", "", 1)
+	os.WriteFile(path, []byte(withoutEmail), 0o644)
+	if err := <-reloads; err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+	fmt.Printf("   EmailNotifier: %s\n", manager.State("EmailNotifier"))
+
+	manager.ShutdownAll(time.Second)
+	fmt.Println()
+}
@@ -0,0 +1,657 @@
+// Archivo: proyecto_plugins_dag.go
+// Proyecto: Sistema de Plugins - Pipeline como grafo de pasos
+// Demuestra: orden topológico, ramas en paralelo con goroutines que se unen,
+// context para timeouts y cancelación, y políticas de error por paso
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// ==============================================
+// CONDICIONES Y POLÍTICAS DE ERROR
+// ==============================================
+
+type ErrorPolicy string
+
+const (
+	OnErrorFail  ErrorPolicy = "fail"  // aborta el pipeline (por defecto)
+	OnErrorSkip  ErrorPolicy = "skip"  // el paso deja pasar su entrada sin cambios
+	OnErrorRetry ErrorPolicy = "retry" // reintenta MaxRetries veces y después falla
+)
+
+var (
+	ErrStepTimeout    = errors.New("el paso excedió su timeout")
+	ErrPipelineGraph  = errors.New("grafo del pipeline inválido")
+	errorPolicyValues = []string{string(OnErrorFail), string(OnErrorSkip), string(OnErrorRetry)}
+)
+
+// StepCondition decide con los datos que llegan al paso si se ejecuta; si no
+// se cumple, el paso se omite y su entrada pasa tal cual a los siguientes
+type StepCondition struct {
+	Formats  []string                              // formatos de entrada admitidos
+	Contains string                                // subcadena que deben contener los datos
+	Match    func(data []byte, format string) bool // condición arbitraria
+}
+
+func (c *StepCondition) allows(data []byte, format string) bool {
+	if c == nil {
+		return true
+	}
+	if len(c.Formats) > 0 && !containsString(c.Formats, format) {
+		return false
+	}
+	if c.Contains != "" && !strings.Contains(string(data), c.Contains) {
+		return false
+	}
+	return c.Match == nil || c.Match(data, format)
+}
+
+// ==============================================
+// VALIDACIÓN DEL GRAFO
+// ==============================================
+
+// planSteps comprueba nombres y dependencias y devuelve los índices en orden
+// topológico; a igualdad, se respeta el orden en que se agregaron los pasos
+func planSteps(steps []PipelineStep) ([]int, error) {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		if step.Name == "" {
+			return nil, fmt.Errorf("%w: paso %d sin nombre", ErrPipelineGraph, i+1)
+		}
+		if _, dup := index[step.Name]; dup {
+			return nil, fmt.Errorf("%w: paso %q duplicado", ErrPipelineGraph, step.Name)
+		}
+		index[step.Name] = i
+	}
+
+	pending := make([]int, len(steps))
+	dependents := make([][]int, len(steps))
+	for i, step := range steps {
+		for _, dep := range step.DependsOn {
+			j, exists := index[dep]
+			if !exists {
+				return nil, fmt.Errorf("%w: %q depende de %q, que no existe", ErrPipelineGraph, step.Name, dep)
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	var ready, order []int
+	for i := range steps {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		current := ready[0]
+		ready = ready[1:]
+		order = append(order, current)
+		for _, next := range dependents[current] {
+			if pending[next]--; pending[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+	if len(order) != len(steps) {
+		var cycle []string
+		for i, n := range pending {
+			if n > 0 {
+				cycle = append(cycle, steps[i].Name)
+			}
+		}
+		return nil, fmt.Errorf("%w: ciclo entre %s", ErrPipelineGraph, strings.Join(cycle, ", "))
+	}
+	return order, nil
+}
+
+// sinkSteps son los pasos de los que no depende nadie: su salida es la del pipeline
+func sinkSteps(steps []PipelineStep) []int {
+	used := make(map[string]bool)
+	for _, step := range steps {
+		for _, dep := range step.DependsOn {
+			used[dep] = true
+		}
+	}
+	var sinks []int
+	for i, step := range steps {
+		if !used[step.Name] {
+			sinks = append(sinks, i)
+		}
+	}
+	return sinks
+}
+
+// ValidateChain recorre el grafo sin ejecutarlo propagando el conjunto de
+// formatos que puede llegar a cada paso: los pasos condicionales o con
+// política skip pueden dejar pasar su entrada, y las uniones producen JSON.
+// Devuelve el formato de salida, o los posibles separados por "|"
+func (pp *ProcessingPipeline) ValidateChain(format string) (string, error) {
+	order, err := planSteps(pp.steps)
+	if err != nil {
+		return "", err
+	}
+	index := make(map[string]int, len(pp.steps))
+	for i, step := range pp.steps {
+		index[step.Name] = i
+	}
+
+	outputs := make([][]string, len(pp.steps))
+	for _, i := range order {
+		step := pp.steps[i]
+		inputs := []string{format}
+		switch len(step.DependsOn) {
+		case 0:
+		case 1:
+			inputs = outputs[index[step.DependsOn[0]]]
+		default:
+			inputs = []string{FormatJSON}
+		}
+
+		var produced []string
+		switch step.PluginType {
+		case "processor":
+			processor, exists := Get[DataProcessor](pp.manager, step.PluginName)
+			if !exists {
+				return "", fmt.Errorf("%w: paso %q: procesador %s no registrado o deshabilitado",
+					ErrIncompatibleChain, step.Name, step.PluginName)
+			}
+			for _, in := range inputs {
+				if step.When != nil && len(step.When.Formats) > 0 && !containsString(step.When.Formats, in) {
+					continue
+				}
+				next, err := processorOutput(processor, in)
+				if err != nil {
+					return "", fmt.Errorf("paso %q: %w", step.Name, err)
+				}
+				produced = appendFormat(produced, next)
+			}
+			if step.When != nil || step.OnError == OnErrorSkip {
+				for _, in := range inputs {
+					produced = appendFormat(produced, in)
+				}
+			}
+		case "logger", "notifier":
+			plugin, exists := pp.manager.GetPlugin(step.PluginName)
+			if !exists || !plugin.IsEnabled() || !pluginHasType(plugin, step.PluginType) {
+				return "", fmt.Errorf("%w: paso %q: %s no es un %s habilitado",
+					ErrIncompatibleChain, step.Name, step.PluginName, step.PluginType)
+			}
+			produced = inputs
+		default:
+			return "", fmt.Errorf("%w: paso %q: tipo de paso desconocido %q",
+				ErrIncompatibleChain, step.Name, step.PluginType)
+		}
+		outputs[i] = produced
+	}
+
+	sinks := sinkSteps(pp.steps)
+	switch len(sinks) {
+	case 0:
+		return format, nil
+	case 1:
+		return strings.Join(outputs[sinks[0]], "|"), nil
+	default:
+		return FormatJSON, nil
+	}
+}
+
+func appendFormat(formats []string, format string) []string {
+	if containsString(formats, format) {
+		return formats
+	}
+	return append(formats, format)
+}
+
+// ==============================================
+// REPORTE DE EJECUCIÓN
+// ==============================================
+
+type StepOutcome string
+
+const (
+	OutcomeOK        StepOutcome = "ok"
+	OutcomeSkipped   StepOutcome = "omitido"
+	OutcomeFailed    StepOutcome = "fallido"
+	OutcomeTimeout   StepOutcome = "timeout"
+	OutcomeCancelled StepOutcome = "cancelado"
+)
+
+type StepReport struct {
+	Name         string
+	Plugin       string
+	Outcome      StepOutcome
+	Attempts     int
+	Start        time.Time
+	Duration     time.Duration
+	InputSize    int
+	InputFormat  string
+	OutputSize   int
+	OutputFormat string
+	Detail       string
+	Err          error
+}
+
+type PipelineReport struct {
+	Steps        []StepReport
+	Duration     time.Duration
+	Output       []byte
+	OutputFormat string
+	Err          error
+}
+
+func (r *PipelineReport) Step(name string) (StepReport, bool) {
+	for _, step := range r.Steps {
+		if step.Name == name {
+			return step, true
+		}
+	}
+	return StepReport{}, false
+}
+
+// String muestra el reporte como tabla, en el orden en que se agregaron los pasos
+func (r *PipelineReport) String() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PASO\tPLUGIN\tRESULTADO\tINTENTOS\tDURACIÓN\tENTRADA\tSALIDA\tDETALLE")
+	for _, s := range r.Steps {
+		input, output := "-", "-"
+		if s.InputFormat != "" {
+			input = fmt.Sprintf("%d B %s", s.InputSize, s.InputFormat)
+		}
+		if s.OutputFormat != "" {
+			output = fmt.Sprintf("%d B %s", s.OutputSize, s.OutputFormat)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%v\t%s\t%s\t%s\n", s.Name, s.Plugin, s.Outcome, s.Attempts,
+			s.Duration.Round(time.Microsecond), input, output, s.Detail)
+	}
+	w.Flush()
+
+	if r.Err != nil {
+		fmt.Fprintf(&sb, "Resultado: ❌ %v (%v)", r.Err, r.Duration.Round(time.Microsecond))
+	} else {
+		fmt.Fprintf(&sb, "Resultado: ✅ %d B %s (%v)", len(r.Output), r.OutputFormat, r.Duration.Round(time.Microsecond))
+	}
+	return sb.String()
+}
+
+// ==============================================
+// EJECUCIÓN
+// ==============================================
+
+type stepResult struct {
+	data   []byte
+	format string
+	ok     bool
+}
+
+func (pp *ProcessingPipeline) Execute(data []byte, format string) (*PipelineReport, error) {
+	return pp.ExecuteContext(context.Background(), data, format)
+}
+
+// ExecuteContext valida el grafo y lanza una goroutine por paso que espera a
+// sus dependencias; las ramas independientes corren en paralelo. El primer
+// paso que falla con política fail cancela el resto
+func (pp *ProcessingPipeline) ExecuteContext(ctx context.Context, data []byte, format string) (*PipelineReport, error) {
+	if _, err := pp.ValidateChain(format); err != nil {
+		return nil, err
+	}
+	order, _ := planSteps(pp.steps)
+	index := make(map[string]int, len(pp.steps))
+	for i, step := range pp.steps {
+		index[step.Name] = i
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	report := &PipelineReport{Steps: make([]StepReport, len(pp.steps))}
+	results := make([]stepResult, len(pp.steps))
+	done := make([]chan struct{}, len(pp.steps))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var failOnce sync.Once
+	fail := func(step PipelineStep, err error) {
+		failOnce.Do(func() {
+			report.Err = fmt.Errorf("paso %q: %w", step.Name, err)
+			cancel()
+		})
+	}
+
+	started := time.Now()
+	var wg sync.WaitGroup
+	for _, i := range order {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+			step := pp.steps[i]
+			rep := &report.Steps[i]
+			rep.Name, rep.Plugin, rep.Outcome = step.Name, step.PluginName, OutcomeCancelled
+
+			for _, dep := range step.DependsOn {
+				select {
+				case <-done[index[dep]]:
+				case <-ctx.Done():
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			for _, dep := range step.DependsOn {
+				if !results[index[dep]].ok {
+					return
+				}
+			}
+
+			rep.Start = time.Now()
+			input, inputFormat, err := stepInput(pp.steps, step, index, results, data, format)
+			if err != nil {
+				rep.Outcome, rep.Err, rep.Detail = OutcomeFailed, err, err.Error()
+				rep.Duration = time.Since(rep.Start)
+				fail(step, err)
+				return
+			}
+			rep.InputSize, rep.InputFormat = len(input), inputFormat
+
+			result := stepResult{data: input, format: inputFormat, ok: true}
+			switch {
+			case !step.When.allows(input, inputFormat):
+				rep.Outcome, rep.Detail = OutcomeSkipped, "condición no cumplida"
+			default:
+				output, outputFormat, attempts, err := pp.runStep(ctx, step, input, inputFormat)
+				rep.Attempts = attempts
+				switch {
+				case err == nil:
+					rep.Outcome = OutcomeOK
+					result.data, result.format = output, outputFormat
+				case step.OnError == OnErrorSkip && ctx.Err() == nil:
+					rep.Outcome, rep.Err, rep.Detail = OutcomeSkipped, err, err.Error()
+				case errors.Is(err, ErrStepTimeout):
+					rep.Outcome, rep.Err, rep.Detail = OutcomeTimeout, err, err.Error()
+					result.ok = false
+				case ctx.Err() != nil:
+					rep.Outcome = OutcomeCancelled
+					result.ok = false
+				default:
+					rep.Outcome, rep.Err, rep.Detail = OutcomeFailed, err, err.Error()
+					result.ok = false
+				}
+				if !result.ok && rep.Outcome != OutcomeCancelled {
+					fail(step, err)
+				}
+			}
+			rep.Duration = time.Since(rep.Start)
+			if result.ok {
+				rep.OutputSize, rep.OutputFormat = len(result.data), result.format
+			}
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+	report.Duration = time.Since(started)
+
+	if report.Err == nil && ctx.Err() != nil {
+		report.Err = ctx.Err()
+	}
+	if report.Err != nil {
+		return report, report.Err
+	}
+
+	output, outputFormat, err := joinResults(pp.steps, sinkSteps(pp.steps), results, data, format)
+	if err != nil {
+		report.Err = err
+		return report, err
+	}
+	report.Output, report.OutputFormat = output, outputFormat
+	return report, nil
+}
+
+// stepInput es la entrada del pipeline para las raíces, la salida de la
+// dependencia si hay una sola, o la unión en JSON si hay varias
+func stepInput(steps []PipelineStep, step PipelineStep, index map[string]int, results []stepResult, data []byte, format string) ([]byte, string, error) {
+	deps := make([]int, len(step.DependsOn))
+	for k, dep := range step.DependsOn {
+		deps[k] = index[dep]
+	}
+	if len(deps) == 0 {
+		return data, format, nil
+	}
+	if len(deps) == 1 {
+		return results[deps[0]].data, results[deps[0]].format, nil
+	}
+	return joinResults(steps, deps, results, data, format)
+}
+
+// joinResults une varias salidas en un objeto JSON con una clave por paso;
+// con una sola salida la devuelve sin tocarla
+func joinResults(steps []PipelineStep, from []int, results []stepResult, data []byte, format string) ([]byte, string, error) {
+	switch len(from) {
+	case 0:
+		return data, format, nil
+	case 1:
+		return results[from[0]].data, results[from[0]].format, nil
+	}
+
+	joined := make(map[string]interface{}, len(from))
+	for _, i := range from {
+		tree, err := decodeFormat(results[i].data, results[i].format)
+		if err != nil {
+			return nil, "", fmt.Errorf("uniendo ramas: %w", err)
+		}
+		joined[steps[i].Name] = tree
+	}
+	output, err := encodeFormat(joined, FormatJSON)
+	return output, FormatJSON, err
+}
+
+// runStep aplica la política de reintentos; los errores permanentes no se
+// reintentan
+func (pp *ProcessingPipeline) runStep(ctx context.Context, step PipelineStep, data []byte, format string) ([]byte, string, int, error) {
+	attempts := 1
+	if step.OnError == OnErrorRetry {
+		attempts += step.MaxRetries
+	}
+	for attempt := 1; ; attempt++ {
+		output, outputFormat, err := pp.runAttempt(ctx, step, data, format)
+		if err == nil {
+			return output, outputFormat, attempt, nil
+		}
+		if attempt >= attempts || errors.Is(err, ErrPermanentFailure) || ctx.Err() != nil {
+			return nil, "", attempt, err
+		}
+		select {
+		case <-time.After(step.RetryDelay * time.Duration(attempt)):
+		case <-ctx.Done():
+			return nil, "", attempt, ctx.Err()
+		}
+	}
+}
+
+// runAttempt ejecuta el plugin con el timeout del paso; los plugins no
+// reciben context, así que al vencer el timeout se abandona su resultado
+func (pp *ProcessingPipeline) runAttempt(ctx context.Context, step PipelineStep, data []byte, format string) ([]byte, string, error) {
+	attemptCtx := ctx
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+
+	type attemptResult struct {
+		data   []byte
+		format string
+		err    error
+	}
+	finished := make(chan attemptResult, 1)
+	go func() {
+		output, outputFormat, err := pp.invoke(step, data, format)
+		finished <- attemptResult{output, outputFormat, err}
+	}()
+
+	select {
+	case r := <-finished:
+		return r.data, r.format, r.err
+	case <-attemptCtx.Done():
+		if ctx.Err() == nil {
+			return nil, "", fmt.Errorf("%w (%v)", ErrStepTimeout, step.Timeout)
+		}
+		return nil, "", ctx.Err()
+	}
+}
+
+func (pp *ProcessingPipeline) invoke(step PipelineStep, data []byte, format string) ([]byte, string, error) {
+	switch step.PluginType {
+	case "processor":
+		processor, exists := Get[DataProcessor](pp.manager, step.PluginName)
+		if !exists {
+			return nil, "", fmt.Errorf("%w: procesador %s no registrado o deshabilitado", ErrPermanentFailure, step.PluginName)
+		}
+		nextFormat, err := processorOutput(processor, format)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %w", ErrPermanentFailure, err)
+		}
+		processed, err := processor.Process(data, format)
+		if err != nil {
+			return nil, "", err
+		}
+		return processed, nextFormat, nil
+
+	case "logger":
+		logger, exists := Get[Logger](pp.manager, step.PluginName)
+		if !exists {
+			return nil, "", fmt.Errorf("%w: logger %s no registrado o deshabilitado", ErrPermanentFailure, step.PluginName)
+		}
+		logger.Log(INFO, fmt.Sprintf("Pipeline paso %s completado", step.Name), map[string]interface{}{
+			"step":      step.Name,
+			"data_size": len(data),
+			"format":    format,
+		})
+		return data, format, nil
+
+	case "notifier":
+		notifier, exists := Get[Notifier](pp.manager, step.PluginName)
+		if !exists {
+			return nil, "", fmt.Errorf("%w: notificador %s no registrado o deshabilitado", ErrPermanentFailure, step.PluginName)
+		}
+		recipient, _ := step.Config["recipient"].(string)
+		if recipient == "" {
+			recipient = "admin@example.com"
+		}
+		message := Message{
+			ID:      fmt.Sprintf("pipeline-%s-%d", step.Name, time.Now().UnixNano()),
+			Subject: "Pipeline Step Completed",
+			Body:    fmt.Sprintf("Completado paso: %s (%d bytes, %s)", step.Name, len(data), format),
+		}
+		types := notifier.SupportedTypes()
+		if len(types) == 0 {
+			return nil, "", fmt.Errorf("%w: %s no declara tipos de mensaje", ErrPermanentFailure, step.PluginName)
+		}
+		message.Type = types[0]
+		return data, format, notifier.Send(recipient, message)
+	}
+	return nil, "", fmt.Errorf("%w: tipo de paso desconocido %q", ErrPermanentFailure, step.PluginType)
+}
+
+// ==============================================
+// DEMOSTRACIÓN DEL PIPELINE EN GRAFO
+// ==============================================
+
+// demoProcessor deja pasar los datos después de una espera y puede fallar
+// las primeras veces que se le llama
+type demoProcessor struct {
+	BaseProcessor
+	delay     time.Duration
+	failFirst int32
+	calls     atomic.Int32
+}
+
+func newDemoProcessor(name string, delay time.Duration, failFirst int32) *demoProcessor {
+	return &demoProcessor{
+		BaseProcessor: BaseProcessor{name: name, version: "1.0.0", description: "Procesador de demostración"},
+		delay:         delay,
+		failFirst:     failFirst,
+	}
+}
+
+func (dp *demoProcessor) SupportedFormats() []string                { return allFormats }
+func (dp *demoProcessor) Validate(data []byte, format string) error { return nil }
+
+func (dp *demoProcessor) Process(data []byte, format string) ([]byte, error) {
+	time.Sleep(dp.delay)
+	if call := dp.calls.Add(1); call <= dp.failFirst {
+		return nil, fmt.Errorf("servicio de enriquecimiento no disponible (intento %d)", call)
+	}
+	return data, nil
+}
+
+func demoPipelineDAG() {
+	fmt.Println("🕸️ DEMO: Pipeline en Grafo con Ramas Paralelas")
+	fmt.Println("==============================================")
+
+	manager := NewPluginManager()
+	manager.RegisterPlugin(NewJSONProcessor())
+	for _, target := range []string{FormatXML, FormatYAML, FormatCSV} {
+		converter, _ := NewFormatConverter(target)
+		manager.RegisterPlugin(converter)
+	}
+	manager.RegisterPlugin(newDemoProcessor("Enriquecer", 0, 2))
+	manager.RegisterPlugin(newDemoProcessor("Auditoría", 200*time.Millisecond, 0))
+	manager.InitializeAll()
+
+	// Formatear se abre en cuatro ramas que se unen en un objeto JSON
+	build := func(auditPolicy ErrorPolicy) *ProcessingPipeline {
+		pipeline := NewProcessingPipeline(manager)
+		pipeline.Add(PipelineStep{Name: "Formatear", PluginName: "JSONProcessor", PluginType: "processor"})
+		pipeline.Add(PipelineStep{Name: "XML", PluginName: "ToXML", PluginType: "processor", DependsOn: []string{"Formatear"}})
+		pipeline.Add(PipelineStep{Name: "Enriquecer", PluginName: "Enriquecer", PluginType: "processor",
+			DependsOn: []string{"Formatear"}, OnError: OnErrorRetry, MaxRetries: 2, RetryDelay: 5 * time.Millisecond})
+		pipeline.Add(PipelineStep{Name: "CSV", PluginName: "ToCSV", PluginType: "processor",
+			DependsOn: []string{"Formatear"}, When: &StepCondition{Contains: `"export":"csv"`}})
+		pipeline.Add(PipelineStep{Name: "Auditoría", PluginName: "Auditoría", PluginType: "processor",
+			DependsOn: []string{"Formatear"}, Timeout: 50 * time.Millisecond, OnError: auditPolicy})
+		pipeline.Add(PipelineStep{Name: "A YAML", PluginName: "ToYAML", PluginType: "processor",
+			DependsOn: []string{"XML", "Enriquecer", "CSV", "Auditoría"}})
+		return pipeline
+	}
+
+	input := []byte(`[{"id":1,"name":"Ana"},{"id":2,"name":"Carlos"}]`)
+	if format, err := build(OnErrorSkip).ValidateChain(FormatJSON); err == nil {
+		fmt.Printf("✅ Grafo válido, formato de salida: %s\n", format)
+	}
+
+	report, err := build(OnErrorSkip).Execute(input, FormatJSON)
+	if err == nil {
+		fmt.Println("📋 Auditoría con política skip:")
+		fmt.Println(report)
+	}
+
+	// Con política fail el timeout aborta el pipeline y cancela lo pendiente
+	report, err = build(OnErrorFail).Execute(input, FormatJSON)
+	if errors.Is(err, ErrStepTimeout) {
+		fmt.Println("📋 Auditoría con política fail:")
+		fmt.Println(report)
+	}
+
+	// Las dependencias circulares se rechazan antes de ejecutar
+	cyclic := NewProcessingPipeline(manager)
+	cyclic.Add(PipelineStep{Name: "A", PluginName: "ToXML", PluginType: "processor", DependsOn: []string{"B"}})
+	cyclic.Add(PipelineStep{Name: "B", PluginName: "ToYAML", PluginType: "processor", DependsOn: []string{"A"}})
+	if _, err := cyclic.Execute(input, FormatJSON); errors.Is(err, ErrPipelineGraph) {
+		fmt.Printf("❌ %v\n", err)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,883 @@
+// Archivo: proyecto_plugins_externos.go
+// Proyecto: Sistema de Plugins - Plugins fuera de proceso vía JSON-RPC por stdio
+// Demuestra: proxies que implementan las mismas interfaces que los plugins
+// compilados, framing sobre io.Reader/io.Writer, supervisión de procesos
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ==============================================
+// PROTOCOLO: JSON-RPC 2.0 CON CABECERA Content-Length
+// ==============================================
+
+const (
+	rpcProtocolVersion = 1
+	rpcPluginEnv       = "GODEEP_PLUGIN"
+	// rpcMaxFrameSize acota la memoria que un plugin puede hacer reservar al
+	// host con una cabecera Content-Length arbitraria
+	rpcMaxFrameSize = 16 << 20
+)
+
+// Capacidades que puede exponer un plugin externo
+const (
+	CapabilityProcessor     = "processor"
+	CapabilityNotifier      = "notifier"
+	CapabilityAuthenticator = "authenticator"
+)
+
+var ErrPluginUnavailable = errors.New("plugin externo no disponible")
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return fmt.Sprintf("rpc %d: %s", e.Code, e.Message) }
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+func writeFrame(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || length < 0 {
+				return nil, fmt.Errorf("cabecera Content-Length inválida: %q", value)
+			}
+			if length > rpcMaxFrameSize {
+				return nil, fmt.Errorf("frame de %d bytes supera el máximo de %d", length, rpcMaxFrameSize)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("frame sin Content-Length")
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(r, body)
+	return body, err
+}
+
+// HandshakeRequest/Result negocian la versión del protocolo y describen el plugin
+type HandshakeRequest struct {
+	ProtocolVersions []int `json:"protocol_versions"`
+}
+
+type HandshakeResult struct {
+	ProtocolVersion int           `json:"protocol_version"`
+	Name            string        `json:"name"`
+	Version         string        `json:"version"`
+	Description     string        `json:"description"`
+	Author          string        `json:"author"`
+	Dependencies    []string      `json:"dependencies"`
+	Capability      string        `json:"capability"`
+	Formats         []string      `json:"formats,omitempty"`
+	MessageTypes    []MessageType `json:"message_types,omitempty"`
+}
+
+// ==============================================
+// CLIENTE RPC (LADO DEL HOST)
+// ==============================================
+
+type rpcClient struct {
+	w       io.Writer
+	wmu     sync.Mutex
+	nextID  int64
+	mu      sync.Mutex
+	pending map[int64]chan rpcResponse
+	done    chan struct{}
+	err     error
+}
+
+func newRPCClient(r io.Reader, w io.Writer) *rpcClient {
+	c := &rpcClient{
+		w:       w,
+		pending: make(map[int64]chan rpcResponse),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(r))
+	return c
+}
+
+func (c *rpcClient) readLoop(r *bufio.Reader) {
+	var err error
+	for {
+		var body []byte
+		if body, err = readFrame(r); err != nil {
+			break
+		}
+		var resp rpcResponse
+		if err = json.Unmarshal(body, &resp); err != nil {
+			break
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+
+	c.mu.Lock()
+	c.err = fmt.Errorf("%w: %v", ErrPluginUnavailable, err)
+	c.mu.Unlock()
+	close(c.done)
+}
+
+func (c *rpcClient) Call(ctx context.Context, method string, params, result interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	id := atomic.AddInt64(&c.nextID, 1)
+	ch := make(chan rpcResponse, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	c.wmu.Lock()
+	err = writeFrame(c.w, rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: raw})
+	c.wmu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPluginUnavailable, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			return json.Unmarshal(resp.Result, result)
+		}
+		return nil
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+// ==============================================
+// PROCESO SUPERVISADO
+// ==============================================
+
+type ExternalPluginConfig struct {
+	Path           string
+	Args           []string
+	Env            []string
+	CallTimeout    time.Duration
+	HealthInterval time.Duration
+	MaxRestarts    int
+	RestartBackoff time.Duration
+}
+
+type pluginProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	client *rpcClient
+	exited chan struct{}
+}
+
+func startPluginProcess(cfg ExternalPluginConfig) (*pluginProcess, error) {
+	cmd := exec.Command(cfg.Path, cfg.Args...)
+	cmd.Env = append(os.Environ(), cfg.Env...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("no se pudo lanzar %s: %w", cfg.Path, err)
+	}
+
+	proc := &pluginProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		client: newRPCClient(stdout, stdin),
+		exited: make(chan struct{}),
+	}
+	// Wait cierra stdout, así que solo se llama cuando el cliente terminó de leer
+	go func() {
+		<-proc.client.done
+		cmd.Wait()
+		close(proc.exited)
+	}()
+	return proc, nil
+}
+
+func (p *pluginProcess) kill() {
+	p.stdin.Close()
+	select {
+	case <-p.exited:
+	case <-time.After(time.Second):
+		p.cmd.Process.Kill()
+		<-p.exited
+	}
+}
+
+// ExternalPlugin implementa PluginInfo reenviando las llamadas al proceso hijo.
+// Los proxies por capacidad (externalProcessor, etc.) lo embeben.
+type ExternalPlugin struct {
+	cfg        ExternalPluginConfig
+	info       HandshakeResult
+	mu         sync.Mutex
+	proc       *pluginProcess
+	lastConfig map[string]interface{}
+	enabled    bool
+	restarts   int
+	stopping   bool
+	stopHealth chan struct{}
+}
+
+// LaunchExternalPlugin lanza el ejecutable, negocia el handshake y devuelve un
+// proxy que implementa la interface de la capacidad declarada por el plugin
+func LaunchExternalPlugin(cfg ExternalPluginConfig) (PluginInfo, error) {
+	if cfg.CallTimeout <= 0 {
+		cfg.CallTimeout = 5 * time.Second
+	}
+	if cfg.HealthInterval <= 0 {
+		cfg.HealthInterval = 2 * time.Second
+	}
+	if cfg.RestartBackoff <= 0 {
+		cfg.RestartBackoff = 100 * time.Millisecond
+	}
+
+	ep := &ExternalPlugin{cfg: cfg}
+	proc, info, err := ep.spawn()
+	if err != nil {
+		return nil, err
+	}
+	ep.proc = proc
+	ep.info = info
+
+	switch info.Capability {
+	case CapabilityProcessor:
+		return &externalProcessor{ep}, nil
+	case CapabilityNotifier:
+		return &externalNotifier{ep}, nil
+	case CapabilityAuthenticator:
+		return &externalAuthenticator{ep}, nil
+	}
+	proc.kill()
+	return nil, fmt.Errorf("capacidad no soportada: %q", info.Capability)
+}
+
+func (ep *ExternalPlugin) spawn() (*pluginProcess, HandshakeResult, error) {
+	proc, err := startPluginProcess(ep.cfg)
+	if err != nil {
+		return nil, HandshakeResult{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ep.cfg.CallTimeout)
+	defer cancel()
+
+	var info HandshakeResult
+	err = proc.client.Call(ctx, "handshake", HandshakeRequest{ProtocolVersions: []int{rpcProtocolVersion}}, &info)
+	if err == nil && info.ProtocolVersion != rpcProtocolVersion {
+		err = fmt.Errorf("versión de protocolo %d no soportada", info.ProtocolVersion)
+	}
+	if err == nil && ep.info.Name != "" && (info.Name != ep.info.Name || info.Capability != ep.info.Capability) {
+		err = fmt.Errorf("el plugin reiniciado cambió de identidad: %s/%s", info.Name, info.Capability)
+	}
+	if err != nil {
+		proc.kill()
+		return nil, HandshakeResult{}, fmt.Errorf("handshake con %s: %w", ep.cfg.Path, err)
+	}
+	return proc, info, nil
+}
+
+func (ep *ExternalPlugin) call(method string, params, result interface{}) error {
+	ep.mu.Lock()
+	proc := ep.proc
+	ep.mu.Unlock()
+	if proc == nil {
+		return ErrPluginUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ep.cfg.CallTimeout)
+	defer cancel()
+	return proc.client.Call(ctx, method, params, result)
+}
+
+func (ep *ExternalPlugin) Name() string           { return ep.info.Name }
+func (ep *ExternalPlugin) Version() string        { return ep.info.Version }
+func (ep *ExternalPlugin) Description() string    { return ep.info.Description }
+func (ep *ExternalPlugin) Author() string         { return ep.info.Author }
+func (ep *ExternalPlugin) Dependencies() []string { return ep.info.Dependencies }
+
+func (ep *ExternalPlugin) IsEnabled() bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.enabled
+}
+
+// Restarts devuelve cuántas veces se relanzó el proceso
+func (ep *ExternalPlugin) Restarts() int {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.restarts
+}
+
+// PID del proceso actual, 0 si no hay proceso vivo
+func (ep *ExternalPlugin) PID() int {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.proc == nil {
+		return 0
+	}
+	return ep.proc.cmd.Process.Pid
+}
+
+// Initialize relanza el proceso si no hay uno vivo (tras Shutdown o tras
+// agotar los reinicios), así el plugin se puede volver a habilitar
+func (ep *ExternalPlugin) Initialize(config map[string]interface{}) error {
+	started, err := ep.ensureProcess()
+	if err != nil {
+		return err
+	}
+	if err := ep.call("initialize", config, nil); err != nil {
+		if started != nil {
+			ep.mu.Lock()
+			if ep.proc == started {
+				ep.proc = nil
+			}
+			ep.mu.Unlock()
+			started.kill()
+		}
+		return err
+	}
+
+	ep.mu.Lock()
+	ep.lastConfig = config
+	ep.enabled = true
+	ep.stopping = false
+	if ep.stopHealth == nil {
+		ep.stopHealth = make(chan struct{})
+		go ep.supervise(ep.stopHealth)
+	}
+	ep.mu.Unlock()
+	return nil
+}
+
+// ensureProcess lanza un proceso nuevo si no hay ninguno y lo devuelve; nil si
+// ya había uno vivo. Un arranque explícito reinicia el contador de reinicios
+func (ep *ExternalPlugin) ensureProcess() (*pluginProcess, error) {
+	ep.mu.Lock()
+	alive := ep.proc != nil
+	ep.mu.Unlock()
+	if alive {
+		return nil, nil
+	}
+
+	proc, _, err := ep.spawn()
+	if err != nil {
+		return nil, err
+	}
+
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.proc != nil {
+		proc.kill()
+		return nil, nil
+	}
+	ep.proc = proc
+	ep.restarts = 0
+	return proc, nil
+}
+
+func (ep *ExternalPlugin) Shutdown() error {
+	ep.mu.Lock()
+	ep.stopping = true
+	ep.enabled = false
+	if ep.stopHealth != nil {
+		close(ep.stopHealth)
+		ep.stopHealth = nil
+	}
+	proc := ep.proc
+	ep.proc = nil
+	ep.mu.Unlock()
+
+	if proc == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ep.cfg.CallTimeout)
+	err := proc.client.Call(ctx, "shutdown", nil, nil)
+	cancel()
+	proc.kill()
+	return err
+}
+
+// supervise hace health checks periódicos y relanza el proceso si muere o
+// deja de responder. Al terminar por su cuenta deja stopHealth en nil para
+// que el próximo Initialize lance otro supervisor.
+func (ep *ExternalPlugin) supervise(stop chan struct{}) {
+	ticker := time.NewTicker(ep.cfg.HealthInterval)
+	defer ticker.Stop()
+	defer func() {
+		ep.mu.Lock()
+		ep.releaseSupervisor(stop)
+		ep.mu.Unlock()
+	}()
+
+	for {
+		ep.mu.Lock()
+		proc := ep.proc
+		if proc == nil {
+			ep.releaseSupervisor(stop)
+		}
+		ep.mu.Unlock()
+		if proc == nil {
+			return
+		}
+
+		var reason string
+		select {
+		case <-stop:
+			return
+		case <-proc.exited:
+			reason = "el proceso terminó"
+		case <-ticker.C:
+			if err := ep.call("ping", nil, nil); err != nil {
+				reason = fmt.Sprintf("health check falló: %v", err)
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		if err := ep.restart(proc, stop, reason); err != nil {
+			fmt.Fprintf(os.Stderr, "🔌 [%s] %v\n", ep.info.Name, err)
+			return
+		}
+	}
+}
+
+// releaseSupervisor olvida el supervisor de stop si sigue siendo el actual.
+// Se llama con ep.mu tomado.
+func (ep *ExternalPlugin) releaseSupervisor(stop chan struct{}) {
+	if ep.stopHealth == stop {
+		ep.stopHealth = nil
+	}
+}
+
+func (ep *ExternalPlugin) restart(old *pluginProcess, stop chan struct{}, reason string) error {
+	old.kill()
+
+	for {
+		ep.mu.Lock()
+		if ep.stopping || ep.proc != old {
+			ep.mu.Unlock()
+			return nil
+		}
+		if ep.restarts >= ep.cfg.MaxRestarts {
+			// Sin proceso ni supervisor en la misma sección crítica: un
+			// Initialize concurrente vuelve a lanzar los dos
+			ep.proc = nil
+			ep.enabled = false
+			ep.releaseSupervisor(stop)
+			ep.mu.Unlock()
+			return fmt.Errorf("%s; límite de %d reinicios alcanzado", reason, ep.cfg.MaxRestarts)
+		}
+		ep.restarts++
+		attempt := ep.restarts
+		config := ep.lastConfig
+		ep.mu.Unlock()
+
+		time.Sleep(ep.cfg.RestartBackoff * time.Duration(attempt))
+
+		proc, _, err := ep.spawn()
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), ep.cfg.CallTimeout)
+			err = proc.client.Call(ctx, "initialize", config, nil)
+			cancel()
+			if err != nil {
+				proc.kill()
+			}
+		}
+		if err != nil {
+			reason = err.Error()
+			continue
+		}
+
+		ep.mu.Lock()
+		if ep.stopping {
+			ep.mu.Unlock()
+			proc.kill()
+			return nil
+		}
+		ep.proc = proc
+		old = proc
+		ep.mu.Unlock()
+		fmt.Fprintf(os.Stderr, "🔌 [%s] reiniciado (intento %d): %s\n", ep.info.Name, attempt, reason)
+		return nil
+	}
+}
+
+// ==============================================
+// PROXIES POR CAPACIDAD
+// ==============================================
+
+type externalProcessor struct{ *ExternalPlugin }
+
+func (p *externalProcessor) SupportedFormats() []string { return p.info.Formats }
+
+func (p *externalProcessor) Process(data []byte, format string) ([]byte, error) {
+	var out struct {
+		Data []byte `json:"data"`
+	}
+	err := p.call("processor.process", map[string]interface{}{"data": data, "format": format}, &out)
+	return out.Data, err
+}
+
+func (p *externalProcessor) Validate(data []byte, format string) error {
+	return p.call("processor.validate", map[string]interface{}{"data": data, "format": format}, nil)
+}
+
+type externalNotifier struct{ *ExternalPlugin }
+
+func (n *externalNotifier) SupportedTypes() []MessageType { return n.info.MessageTypes }
+
+func (n *externalNotifier) Send(recipient string, message Message) error {
+	return n.call("notifier.send", map[string]interface{}{"recipient": recipient, "message": message}, nil)
+}
+
+func (n *externalNotifier) GetDeliveryStatus(messageID string) DeliveryStatus {
+	var status DeliveryStatus
+	if err := n.call("notifier.status", map[string]string{"message_id": messageID}, &status); err != nil {
+		return DeliveryStatus{Timestamp: time.Now(), Error: err.Error()}
+	}
+	return status
+}
+
+type externalAuthenticator struct{ *ExternalPlugin }
+
+func (a *externalAuthenticator) Authenticate(credentials map[string]string) (User, error) {
+	var user User
+	err := a.call("authenticator.authenticate", credentials, &user)
+	return user, err
+}
+
+func (a *externalAuthenticator) ValidateToken(token string) (User, error) {
+	var user User
+	err := a.call("authenticator.validate", map[string]string{"token": token}, &user)
+	return user, err
+}
+
+func (a *externalAuthenticator) RefreshToken(token string) (string, error) {
+	var out struct {
+		Token string `json:"token"`
+	}
+	err := a.call("authenticator.refresh", map[string]string{"token": token}, &out)
+	return out.Token, err
+}
+
+// LoadExternalPlugin lanza y registra un plugin externo; se inicializa junto
+// con el resto en InitializeAll
+func (pm *PluginManager) LoadExternalPlugin(cfg ExternalPluginConfig) (PluginInfo, error) {
+	plugin, err := LaunchExternalPlugin(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := pm.RegisterPlugin(plugin); err != nil {
+		plugin.Shutdown()
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// ==============================================
+// SERVIDOR RPC (LADO DEL PLUGIN)
+// ==============================================
+
+// ServeExternalPlugin expone un plugin compilado a través de r/w. Es lo que
+// ejecuta el binario del plugin en su main.
+func ServeExternalPlugin(plugin PluginInfo, r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		body, err := readFrame(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return err
+		}
+
+		result, err := dispatchRPC(plugin, req)
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+		if err != nil {
+			resp.Error = &rpcError{Code: -32000, Message: err.Error()}
+		} else if resp.Result, err = json.Marshal(result); err != nil {
+			resp.Error = &rpcError{Code: -32603, Message: err.Error()}
+		}
+		if err := writeFrame(w, resp); err != nil {
+			return err
+		}
+		if req.Method == "shutdown" {
+			return nil
+		}
+	}
+}
+
+func dispatchRPC(plugin PluginInfo, req rpcRequest) (interface{}, error) {
+	var params struct {
+		Data      []byte  `json:"data"`
+		Format    string  `json:"format"`
+		Recipient string  `json:"recipient"`
+		Message   Message `json:"message"`
+		MessageID string  `json:"message_id"`
+		Token     string  `json:"token"`
+		Versions  []int   `json:"protocol_versions"`
+	}
+	if len(req.Params) > 0 && req.Params[0] == '{' && req.Method != "initialize" && req.Method != "authenticator.authenticate" {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, fmt.Errorf("parámetros inválidos: %v", err)
+		}
+	}
+
+	processor, _ := plugin.(DataProcessor)
+	notifier, _ := plugin.(Notifier)
+	auth, _ := plugin.(Authenticator)
+
+	switch req.Method {
+	case "handshake":
+		supported := false
+		for _, v := range params.Versions {
+			supported = supported || v == rpcProtocolVersion
+		}
+		if !supported {
+			return nil, fmt.Errorf("ninguna versión de protocolo compatible en %v", params.Versions)
+		}
+		info := HandshakeResult{
+			ProtocolVersion: rpcProtocolVersion,
+			Name:            plugin.Name(),
+			Version:         plugin.Version(),
+			Description:     plugin.Description(),
+			Author:          plugin.Author(),
+			Dependencies:    plugin.Dependencies(),
+		}
+		switch {
+		case processor != nil:
+			info.Capability, info.Formats = CapabilityProcessor, processor.SupportedFormats()
+		case notifier != nil:
+			info.Capability, info.MessageTypes = CapabilityNotifier, notifier.SupportedTypes()
+		case auth != nil:
+			info.Capability = CapabilityAuthenticator
+		}
+		return info, nil
+	case "initialize":
+		config := map[string]interface{}{}
+		if err := json.Unmarshal(req.Params, &config); err != nil && string(req.Params) != "null" {
+			return nil, err
+		}
+		return nil, plugin.Initialize(config)
+	case "shutdown":
+		return nil, plugin.Shutdown()
+	case "ping":
+		return "pong", nil
+	}
+
+	switch {
+	case processor != nil && req.Method == "processor.process":
+		out, err := processor.Process(params.Data, params.Format)
+		return map[string][]byte{"data": out}, err
+	case processor != nil && req.Method == "processor.validate":
+		return nil, processor.Validate(params.Data, params.Format)
+	case notifier != nil && req.Method == "notifier.send":
+		return nil, notifier.Send(params.Recipient, params.Message)
+	case notifier != nil && req.Method == "notifier.status":
+		return notifier.GetDeliveryStatus(params.MessageID), nil
+	case auth != nil && req.Method == "authenticator.authenticate":
+		credentials := map[string]string{}
+		if err := json.Unmarshal(req.Params, &credentials); err != nil {
+			return nil, err
+		}
+		return auth.Authenticate(credentials)
+	case auth != nil && req.Method == "authenticator.validate":
+		return auth.ValidateToken(params.Token)
+	case auth != nil && req.Method == "authenticator.refresh":
+		token, err := auth.RefreshToken(params.Token)
+		return map[string]string{"token": token}, err
+	}
+	return nil, fmt.Errorf("método no soportado: %s", req.Method)
+}
+
+// ==============================================
+// PLUGIN DE EJEMPLO Y DEMOSTRACIÓN
+// ==============================================
+
+// UppercaseProcessor es el plugin que sirve el propio binario cuando se
+// relanza con GODEEP_PLUGIN=uppercase
+type UppercaseProcessor struct {
+	BaseProcessor
+}
+
+func (up *UppercaseProcessor) SupportedFormats() []string { return []string{"text"} }
+
+func (up *UppercaseProcessor) Process(data []byte, format string) ([]byte, error) {
+	if err := up.Validate(data, format); err != nil {
+		return nil, err
+	}
+	return []byte(strings.ToUpper(string(data))), nil
+}
+
+func (up *UppercaseProcessor) Validate(data []byte, format string) error {
+	if format != "text" {
+		return fmt.Errorf("formato no soportado: %s", format)
+	}
+	return nil
+}
+
+// serveExternalPluginFromEnv convierte el proceso en un plugin externo si
+// fue lanzado como tal; devuelve true si el proceso actuó como plugin
+func serveExternalPluginFromEnv() bool {
+	switch os.Getenv(rpcPluginEnv) {
+	case "":
+		return false
+	case "uppercase":
+		plugin := &UppercaseProcessor{BaseProcessor{
+			name:        "UppercaseProcessor",
+			version:     "1.2.0",
+			description: "Procesador externo que convierte texto a mayúsculas",
+			author:      "Go Deep Team",
+		}}
+		if err := ServeExternalPlugin(plugin, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "plugin uppercase: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "plugin desconocido: %s\n", os.Getenv(rpcPluginEnv))
+		os.Exit(1)
+	}
+	return true
+}
+
+func demoExternalPlugins() {
+	fmt.Println("🧩 DEMO: Plugins Externos (JSON-RPC por stdio)")
+	fmt.Println("=============================================")
+
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Printf("❌ No se pudo localizar el ejecutable: %v\n\n", err)
+		return
+	}
+
+	manager := NewPluginManager()
+	plugin, err := manager.LoadExternalPlugin(ExternalPluginConfig{
+		Path:           self,
+		Env:            []string{rpcPluginEnv + "=uppercase"},
+		CallTimeout:    2 * time.Second,
+		HealthInterval: 100 * time.Millisecond,
+		MaxRestarts:    3,
+	})
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	if err := manager.InitializeAll(); err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+
+	processor, _ := Get[DataProcessor](manager, plugin.Name())
+	external := processor.(*externalProcessor)
+	fmt.Printf("✅ %s v%s (pid %d) formatos: %v\n",
+		processor.Name(), processor.Version(), external.PID(), processor.SupportedFormats())
+
+	out, err := processor.Process([]byte("hola desde otro proceso"), "text")
+	fmt.Printf("📤 Resultado: %q (err: %v)\n", out, err)
+
+	// Simular un crash: el supervisor relanza el proceso y lo re-inicializa
+	oldPID := external.PID()
+	if proc, err := os.FindProcess(oldPID); err == nil {
+		proc.Kill()
+	}
+	for i := 0; i < 100 && external.PID() == oldPID; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	out, err = processor.Process([]byte("después del reinicio"), "text")
+	fmt.Printf("♻️ Reinicios: %d, nuevo pid %d → %q (err: %v)\n", external.Restarts(), external.PID(), out, err)
+
+	if err := processor.Validate([]byte("x"), "json"); err != nil {
+		fmt.Printf("❌ Error remoto propagado: %v\n", err)
+	}
+
+	// Deshabilitar apaga el proceso; volver a habilitarlo lanza uno nuevo
+	if err := manager.DisablePlugin(plugin.Name()); err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+	if err := manager.EnablePlugin(plugin.Name()); err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+	out, err = processor.Process([]byte("tras rehabilitar"), "text")
+	fmt.Printf("🔁 Rehabilitado con pid %d → %q (err: %v)\n", external.PID(), out, err)
+
+	if err := manager.ShutdownAll(3 * time.Second); err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	}
+	fmt.Println()
+}
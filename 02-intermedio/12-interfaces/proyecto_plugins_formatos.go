@@ -0,0 +1,536 @@
+// Archivo: proyecto_plugins_formatos.go
+// Proyecto: Sistema de Plugins - Conversión de formatos y encadenamiento
+// Demuestra: un modelo de datos común (map/slice/escalares) como punto de
+// encuentro entre JSON, XML, CSV y YAML, y validación de tipos de una cadena
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ==============================================
+// PROCESADORES CON FORMATO DE SALIDA
+// ==============================================
+
+const (
+	FormatJSON = "json"
+	FormatXML  = "xml"
+	FormatCSV  = "csv"
+	FormatYAML = "yaml"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("formato no soportado")
+	ErrIncompatibleChain = errors.New("cadena de procesadores incompatible")
+)
+
+var allFormats = []string{FormatJSON, FormatXML, FormatCSV, FormatYAML}
+
+// ChainableProcessor declara el formato que produce para cada formato de
+// entrada; los DataProcessor que no lo implementan conservan el formato
+type ChainableProcessor interface {
+	DataProcessor
+	OutputFormat(input string) (string, error)
+}
+
+func processorOutput(processor DataProcessor, input string) (string, error) {
+	if !containsString(processor.SupportedFormats(), input) {
+		return "", fmt.Errorf("%w: %s no acepta %s (acepta: %s)", ErrIncompatibleChain,
+			processor.Name(), input, strings.Join(processor.SupportedFormats(), ", "))
+	}
+	if chainable, ok := processor.(ChainableProcessor); ok {
+		return chainable.OutputFormat(input)
+	}
+	return input, nil
+}
+
+// FormatConverter convierte cualquiera de los formatos conocidos al formato destino
+type FormatConverter struct {
+	BaseProcessor
+	target string
+}
+
+func NewFormatConverter(target string) (*FormatConverter, error) {
+	if !containsString(allFormats, target) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, target)
+	}
+	return &FormatConverter{
+		BaseProcessor: BaseProcessor{
+			name:        "To" + strings.ToUpper(target),
+			version:     "1.0.0",
+			description: fmt.Sprintf("Convierte datos a %s", strings.ToUpper(target)),
+			author:      "Go Deep Team",
+		},
+		target: target,
+	}, nil
+}
+
+func (fc *FormatConverter) SupportedFormats() []string {
+	return allFormats
+}
+
+func (fc *FormatConverter) OutputFormat(input string) (string, error) {
+	return fc.target, nil
+}
+
+func (fc *FormatConverter) Process(data []byte, format string) ([]byte, error) {
+	tree, err := decodeFormat(data, format)
+	if err != nil {
+		return nil, err
+	}
+	return encodeFormat(tree, fc.target)
+}
+
+func (fc *FormatConverter) Validate(data []byte, format string) error {
+	_, err := decodeFormat(data, format)
+	return err
+}
+
+// ==============================================
+// DECODIFICACIÓN Y CODIFICACIÓN POR FORMATO
+// ==============================================
+
+// xmlRootName se usa al convertir a XML desde formatos sin elemento raíz
+const xmlRootName = "data"
+
+func decodeFormat(data []byte, format string) (interface{}, error) {
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var tree interface{}
+		if err := decoder.Decode(&tree); err != nil {
+			return nil, fmt.Errorf("error parseando JSON: %w", err)
+		}
+		return tree, nil
+	case FormatXML:
+		_, tree, err := decodeXML(data)
+		return tree, err
+	case FormatCSV:
+		return decodeCSV(data)
+	case FormatYAML:
+		tree, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parseando YAML: %w", err)
+		}
+		return tree, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+func encodeFormat(tree interface{}, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(tree, "", "  ")
+	case FormatXML:
+		return encodeXML(xmlRootName, tree)
+	case FormatCSV:
+		return encodeCSV(tree)
+	case FormatYAML:
+		return encodeYAML(tree), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// jsonNumberPattern es la gramática de número de JSON (RFC 8259); ParseFloat
+// acepta además ceros a la izquierda, NaN, Inf y hexadecimales
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// inferScalar da tipo a los textos de XML y CSV, que no lo tienen. Solo se
+// convierten a número los textos que JSON escribiría igual: "01234" sigue
+// siendo texto
+func inferScalar(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if jsonNumberPattern.MatchString(s) {
+		return json.Number(s)
+	}
+	return s
+}
+
+func scalarString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// ==============================================
+// CSV: LISTA DE OBJETOS PLANOS
+// ==============================================
+
+func decodeCSV(data []byte) (interface{}, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parseando CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("error parseando CSV: falta la cabecera")
+	}
+
+	header := records[0]
+	rows := make([]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			row[column] = decodeCSVCell(record[i])
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// decodeCSVCell recupera los valores anidados que encodeCSV guardó como JSON
+func decodeCSVCell(cell string) interface{} {
+	if strings.HasPrefix(cell, "[") || strings.HasPrefix(cell, "{") {
+		if nested, err := decodeFormat([]byte(cell), FormatJSON); err == nil {
+			return nested
+		}
+	}
+	return inferScalar(cell)
+}
+
+// encodeCSV exige una lista de objetos; los valores anidados se guardan como
+// JSON dentro de la celda
+func encodeCSV(tree interface{}) ([]byte, error) {
+	items, ok := tree.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("CSV requiere una lista de objetos, se obtuvo %T", tree)
+	}
+
+	columns := map[string]bool{}
+	for i, item := range items {
+		row, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("CSV requiere una lista de objetos: elemento %d es %T", i, item)
+		}
+		for key := range row {
+			columns[key] = true
+		}
+	}
+	header := make([]string, 0, len(columns))
+	for key := range columns {
+		header = append(header, key)
+	}
+	sort.Strings(header)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(header)
+	for _, item := range items {
+		row := item.(map[string]interface{})
+		record := make([]string, len(header))
+		for i, column := range header {
+			switch value := row[column].(type) {
+			case map[string]interface{}, []interface{}:
+				nested, err := json.Marshal(value)
+				if err != nil {
+					return nil, err
+				}
+				record[i] = string(nested)
+			default:
+				record[i] = scalarString(value)
+			}
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// ==============================================
+// XML: ELEMENTOS ANIDADOS, LISTAS COMO <item>
+// ==============================================
+
+var xmlNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// decodeXML devuelve el nombre del elemento raíz y su contenido; los
+// atributos se ignoran
+func decodeXML(data []byte) (string, interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", nil, fmt.Errorf("formato XML inválido: documento vacío")
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("formato XML inválido: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			tree, err := decodeXMLElement(decoder)
+			if err != nil {
+				return "", nil, fmt.Errorf("formato XML inválido: %w", err)
+			}
+			// Solo se permiten comentarios y espacios tras el elemento raíz
+			for {
+				token, err := decoder.Token()
+				if err == io.EOF {
+					return start.Name.Local, tree, nil
+				}
+				if err != nil {
+					return "", nil, fmt.Errorf("formato XML inválido: %w", err)
+				}
+				if _, extra := token.(xml.StartElement); extra {
+					return "", nil, fmt.Errorf("formato XML inválido: más de un elemento raíz")
+				}
+			}
+		}
+	}
+}
+
+func decodeXMLElement(decoder *xml.Decoder) (interface{}, error) {
+	children := map[string]interface{}{}
+	repeated := map[string]bool{}
+	var items []interface{}
+	var text strings.Builder
+	hasChildren := false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder)
+			if err != nil {
+				return nil, err
+			}
+			hasChildren = true
+			name := t.Name.Local
+			switch existing, exists := children[name]; {
+			case name == "item":
+				items = append(items, child)
+			case !exists:
+				children[name] = child
+			case repeated[name]:
+				children[name] = append(existing.([]interface{}), child)
+			default:
+				children[name] = []interface{}{existing, child}
+				repeated[name] = true
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			switch {
+			case !hasChildren:
+				return inferScalar(strings.TrimSpace(text.String())), nil
+			case len(children) == 0:
+				return items, nil
+			case items != nil:
+				children["item"] = items
+			}
+			return children, nil
+		}
+	}
+}
+
+func encodeXML(root string, tree interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := writeXMLElement(&buf, root, tree, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeXMLElement(buf *bytes.Buffer, name string, value interface{}, depth int) error {
+	if !xmlNamePattern.MatchString(name) {
+		return fmt.Errorf("%q no es un nombre de elemento XML válido", name)
+	}
+	pad := strings.Repeat("  ", depth)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			fmt.Fprintf(buf, "%s<%s/>\n", pad, name)
+			return nil
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(buf, "%s<%s>\n", pad, name)
+		for _, key := range keys {
+			if err := writeXMLElement(buf, key, v[key], depth+1); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(buf, "%s</%s>\n", pad, name)
+	case []interface{}:
+		fmt.Fprintf(buf, "%s<%s>\n", pad, name)
+		for _, item := range v {
+			if err := writeXMLElement(buf, "item", item, depth+1); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(buf, "%s</%s>\n", pad, name)
+	default:
+		fmt.Fprintf(buf, "%s<%s>", pad, name)
+		xml.EscapeText(buf, []byte(scalarString(v)))
+		fmt.Fprintf(buf, "</%s>\n", name)
+	}
+	return nil
+}
+
+// ==============================================
+// YAML: MISMO SUBCONJUNTO QUE LEE parseYAML
+// ==============================================
+
+func encodeYAML(tree interface{}) []byte {
+	var buf strings.Builder
+	switch tree.(type) {
+	case map[string]interface{}, []interface{}:
+		writeYAMLBlock(&buf, tree, 0)
+	default:
+		buf.WriteString(yamlScalar(tree) + "\n")
+	}
+	return []byte(buf.String())
+}
+
+func writeYAMLBlock(buf *strings.Builder, value interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			buf.WriteString(pad + yamlScalar(key) + ":")
+			if isYAMLBlock(v[key]) {
+				buf.WriteString("\n")
+				writeYAMLBlock(buf, v[key], indent+2)
+			} else {
+				buf.WriteString(" " + yamlInline(v[key]) + "\n")
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if isYAMLBlock(item) {
+				// El primer renglón del bloque va en la misma línea que el guion
+				var nested strings.Builder
+				writeYAMLBlock(&nested, item, indent+2)
+				buf.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+			} else {
+				buf.WriteString(pad + "- " + yamlInline(item) + "\n")
+			}
+		}
+	}
+}
+
+func isYAMLBlock(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+func yamlInline(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	}
+	return yamlScalar(value)
+}
+
+// yamlScalar pone comillas cuando el texto se leería como otro tipo o
+// contiene caracteres con significado en YAML
+func yamlScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		parsed, err := parseYAMLScalar(v)
+		if err != nil || parsed != v || v != strings.TrimSpace(v) ||
+			strings.ContainsAny(v, "#:\n\"'[]{},&*!|>%@`") || strings.HasPrefix(v, "- ") {
+			return strconv.Quote(v)
+		}
+		return v
+	}
+	return scalarString(value)
+}
+
+// ==============================================
+// DEMOSTRACIÓN DE CONVERSIÓN DE FORMATOS
+// ==============================================
+
+func demoFormatChain() {
+	fmt.Println("🔀 DEMO: Conversión y Encadenamiento de Formatos")
+	fmt.Println("================================================")
+
+	manager := NewPluginManager()
+	manager.RegisterPlugin(NewJSONProcessor())
+	manager.RegisterPlugin(NewXMLProcessor())
+	for _, target := range allFormats {
+		if converter, err := NewFormatConverter(target); err == nil {
+			manager.RegisterPlugin(converter)
+		}
+	}
+	manager.InitializeAll()
+
+	input := `[{"id":1,"name":"Ana","tags":["admin"]},{"id":2,"name":"Carlos: QA","tags":["qa","ops"]}]`
+
+	// JSON → XML → YAML → CSV → JSON, mostrando cada formato intermedio
+	current, data := FormatJSON, []byte(input)
+	for _, name := range []string{"ToXML", "XMLProcessor", "ToYAML", "ToCSV", "ToJSON"} {
+		processor, _ := Get[DataProcessor](manager, name)
+		next, err := processorOutput(processor, current)
+		if err == nil {
+			data, err = processor.Process(data, current)
+		}
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", name, err)
+			return
+		}
+		if next != current || name == "ToCSV" {
+			fmt.Printf("📄 %s (%s → %s):\n%s\n", name, current, next, strings.TrimSpace(string(data)))
+		}
+		current = next
+	}
+
+	// Una cadena mal formada se rechaza antes de procesar nada
+	pipeline := NewProcessingPipeline(manager)
+	pipeline.AddStep("A CSV", "ToCSV", "processor", nil)
+	pipeline.AddStep("Formatear JSON", "JSONProcessor", "processor", nil)
+	if _, err := pipeline.Execute([]byte(input), FormatJSON); errors.Is(err, ErrIncompatibleChain) {
+		fmt.Printf("❌ Pipeline rechazado: %v\n", err)
+	}
+
+	// Un objeto no tabular no se puede representar como CSV
+	converter, _ := Get[DataProcessor](manager, "ToCSV")
+	if _, err := converter.Process([]byte(`{"total":2}`), FormatJSON); err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+	fmt.Println()
+}
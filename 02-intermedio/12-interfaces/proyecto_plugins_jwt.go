@@ -0,0 +1,851 @@
+// Archivo: proyecto_plugins_jwt.go
+// Proyecto: Sistema de Plugins - Tokens JWT firmados con la biblioteca estándar
+// Demuestra: crypto/hmac, crypto/rsa y crypto/ecdsa detrás de una misma
+// abstracción de clave, rotación por kid, refresh tokens revocables y stores
+// de usuarios intercambiables
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// ERRORES DE AUTENTICACIÓN
+// ==============================================
+
+var (
+	ErrInvalidCredentials = errors.New("credenciales inválidas")
+	ErrUserNotFound       = errors.New("usuario no encontrado")
+	ErrTokenMalformed     = errors.New("token mal formado")
+	ErrTokenSignature     = errors.New("firma de token inválida")
+	ErrTokenExpired       = errors.New("token expirado")
+	ErrTokenNotYetValid   = errors.New("token aún no válido")
+	ErrTokenIssuer        = errors.New("emisor de token inválido")
+	ErrTokenAudience      = errors.New("audiencia de token inválida")
+	ErrTokenRevoked       = errors.New("token revocado")
+	ErrUnknownKey         = errors.New("clave de firma desconocida")
+)
+
+// ==============================================
+// CLAVES DE FIRMA Y KEY SET
+// ==============================================
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// SigningKey agrupa el material de una clave; las claves importadas desde un
+// JWKS solo tienen la parte pública y sirven únicamente para verificar
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	secret    []byte
+	private   crypto.Signer
+	public    crypto.PublicKey
+}
+
+func NewHMACKey(id string, secret []byte) (*SigningKey, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("el secreto HS256 debe tener al menos 32 bytes")
+	}
+	return &SigningKey{ID: id, Algorithm: AlgHS256, secret: append([]byte{}, secret...)}, nil
+}
+
+func GenerateRSAKey(id string) (*SigningKey, error) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: id, Algorithm: AlgRS256, private: private, public: &private.PublicKey}, nil
+}
+
+func GenerateECKey(id string) (*SigningKey, error) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: id, Algorithm: AlgES256, private: private, public: &private.PublicKey}, nil
+}
+
+func (k *SigningKey) canSign() bool {
+	return k.secret != nil || k.private != nil
+}
+
+func (k *SigningKey) sign(input []byte) ([]byte, error) {
+	digest := sha256.Sum256(input)
+	switch k.Algorithm {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case AlgRS256:
+		private, ok := k.private.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("clave %s sin parte privada", k.ID)
+		}
+		return rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, digest[:])
+	case AlgES256:
+		private, ok := k.private.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("clave %s sin parte privada", k.ID)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, private, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS usa r||s de longitud fija, no la codificación ASN.1
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	}
+	return nil, fmt.Errorf("algoritmo no soportado: %s", k.Algorithm)
+}
+
+func (k *SigningKey) verify(input, signature []byte) error {
+	digest := sha256.Sum256(input)
+	switch k.Algorithm {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		if hmac.Equal(mac.Sum(nil), signature) {
+			return nil
+		}
+	case AlgRS256:
+		if public, ok := k.public.(*rsa.PublicKey); ok &&
+			rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature) == nil {
+			return nil
+		}
+	case AlgES256:
+		public, ok := k.public.(*ecdsa.PublicKey)
+		if ok && len(signature) == 64 {
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			if ecdsa.Verify(public, digest[:], r, s) {
+				return nil
+			}
+		}
+	}
+	return ErrTokenSignature
+}
+
+// KeySet permite rotar claves: se firma con la activa y se verifica con
+// cualquiera que no haya sido retirada, identificándola por el kid
+type KeySet struct {
+	mu     sync.RWMutex
+	keys   map[string]*SigningKey
+	active string
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]*SigningKey)}
+}
+
+// Add incorpora la clave; la primera que puede firmar queda activa
+func (ks *KeySet) Add(key *SigningKey) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if key.ID == "" {
+		return fmt.Errorf("la clave necesita un kid")
+	}
+	if _, exists := ks.keys[key.ID]; exists {
+		return fmt.Errorf("clave %s ya existe", key.ID)
+	}
+	ks.keys[key.ID] = key
+	if ks.active == "" && key.canSign() {
+		ks.active = key.ID
+	}
+	return nil
+}
+
+func (ks *KeySet) Activate(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key, exists := ks.keys[kid]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	if !key.canSign() {
+		return fmt.Errorf("clave %s solo sirve para verificar", kid)
+	}
+	ks.active = kid
+	return nil
+}
+
+// Retire elimina una clave: los tokens firmados con ella dejan de ser válidos
+func (ks *KeySet) Retire(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if kid == ks.active {
+		return fmt.Errorf("no se puede retirar la clave activa %s", kid)
+	}
+	if _, exists := ks.keys[kid]; !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	delete(ks.keys, kid)
+	return nil
+}
+
+func (ks *KeySet) activeKey() (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, exists := ks.keys[ks.active]
+	if !exists {
+		return nil, fmt.Errorf("no hay clave activa para firmar")
+	}
+	return key, nil
+}
+
+func (ks *KeySet) lookup(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, exists := ks.keys[kid]
+	return key, exists
+}
+
+// ==============================================
+// PUBLICACIÓN DE CLAVES (JWKS)
+// ==============================================
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// JWKS publica las claves públicas; las HMAC son secretas y nunca se exportan
+func (ks *KeySet) JWKS() ([]byte, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := jwkSet{Keys: []jwk{}}
+	for _, key := range ks.keys {
+		entry := jwk{Kid: key.ID, Alg: key.Algorithm, Use: "sig"}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			entry.Kty = "RSA"
+			entry.N = b64.EncodeToString(public.N.Bytes())
+			entry.E = b64.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case *ecdsa.PublicKey:
+			entry.Kty, entry.Crv = "EC", "P-256"
+			x, y := make([]byte, 32), make([]byte, 32)
+			public.X.FillBytes(x)
+			public.Y.FillBytes(y)
+			entry.X, entry.Y = b64.EncodeToString(x), b64.EncodeToString(y)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, entry)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return json.MarshalIndent(set, "", "  ")
+}
+
+// ParseJWKS crea un KeySet de solo verificación, como el que tendría un
+// servicio que consume los tokens
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("JWKS inválido: %w", err)
+	}
+
+	ks := NewKeySet()
+	for _, entry := range set.Keys {
+		key := &SigningKey{ID: entry.Kid, Algorithm: entry.Alg}
+		switch {
+		case entry.Kty == "RSA" && entry.Alg == AlgRS256:
+			n, errN := b64.DecodeString(entry.N)
+			e, errE := b64.DecodeString(entry.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("clave RSA %s inválida", entry.Kid)
+			}
+			key.public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case entry.Kty == "EC" && entry.Alg == AlgES256 && entry.Crv == "P-256":
+			x, errX := b64.DecodeString(entry.X)
+			y, errY := b64.DecodeString(entry.Y)
+			if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+				return nil, fmt.Errorf("clave EC %s inválida", entry.Kid)
+			}
+			// ecdh valida que el punto pertenezca a la curva
+			point := append(append([]byte{4}, x...), y...)
+			if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+				return nil, fmt.Errorf("clave EC %s inválida: %w", entry.Kid, err)
+			}
+			key.public = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		default:
+			return nil, fmt.Errorf("clave %s: tipo %s/%s no soportado", entry.Kid, entry.Kty, entry.Alg)
+		}
+		if err := ks.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// ==============================================
+// CLAIMS, FIRMA Y VERIFICACIÓN
+// ==============================================
+
+// Audience acepta tanto "aud": "x" como "aud": ["x", "y"]
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	ID        string   `json:"jti,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	Email     string   `json:"email,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+type VerifyOptions struct {
+	Issuer    string
+	Audience  string
+	ClockSkew time.Duration
+	Now       time.Time
+}
+
+func SignToken(key *SigningKey, claims Claims) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: key.Algorithm, Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	signature, err := key.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + b64.EncodeToString(signature), nil
+}
+
+// VerifyToken comprueba la firma con la clave indicada por el kid. El
+// algoritmo lo decide la clave, no la cabecera, para que un token no pueda
+// pedir "none" o verificarse con HS256 usando una clave pública como secreto.
+func VerifyToken(keys *KeySet, token string, opts VerifyOptions) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrTokenMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, err
+	}
+	key, exists := keys.lookup(header.Kid)
+	if !exists {
+		return Claims{}, fmt.Errorf("%w: %q", ErrUnknownKey, header.Kid)
+	}
+	if header.Alg != key.Algorithm {
+		return Claims{}, fmt.Errorf("%w: algoritmo %s no corresponde a la clave %s", ErrTokenSignature, header.Alg, key.ID)
+	}
+
+	signature, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrTokenMalformed
+	}
+	if err := key.verify([]byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return Claims{}, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, err
+	}
+	return claims, claims.validate(opts)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := b64.DecodeString(segment)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+	}
+	return nil
+}
+
+func (c Claims) validate(opts VerifyOptions) error {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	skew := opts.ClockSkew
+
+	if c.ExpiresAt == 0 {
+		return fmt.Errorf("%w: falta exp", ErrTokenMalformed)
+	}
+	if now.After(time.Unix(c.ExpiresAt, 0).Add(skew)) {
+		return fmt.Errorf("%w desde %s", ErrTokenExpired, time.Unix(c.ExpiresAt, 0).Format(time.RFC3339))
+	}
+	if c.NotBefore != 0 && now.Add(skew).Before(time.Unix(c.NotBefore, 0)) {
+		return ErrTokenNotYetValid
+	}
+	if c.IssuedAt != 0 && now.Add(skew).Before(time.Unix(c.IssuedAt, 0)) {
+		return fmt.Errorf("%w: emitido en el futuro", ErrTokenNotYetValid)
+	}
+	if opts.Issuer != "" && c.Issuer != opts.Issuer {
+		return fmt.Errorf("%w: %q", ErrTokenIssuer, c.Issuer)
+	}
+	if opts.Audience != "" && !containsString(c.Audience, opts.Audience) {
+		return fmt.Errorf("%w: se esperaba %q", ErrTokenAudience, opts.Audience)
+	}
+	return nil
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(buf), nil
+}
+
+// ==============================================
+// STORES DE USUARIOS
+// ==============================================
+
+// UserStore desacopla al autenticador del origen de los usuarios
+type UserStore interface {
+	VerifyCredentials(username, password string) (User, error)
+	GetUser(id string) (User, error)
+}
+
+type storedUser struct {
+	user User
+	salt []byte
+	hash []byte
+}
+
+// InMemoryUserStore guarda las contraseñas derivadas con PBKDF2-SHA256
+type InMemoryUserStore struct {
+	mu         sync.RWMutex
+	byID       map[string]*storedUser
+	byUsername map[string]*storedUser
+	iterations int
+}
+
+func NewInMemoryUserStore() *InMemoryUserStore {
+	return &InMemoryUserStore{
+		byID:       make(map[string]*storedUser),
+		byUsername: make(map[string]*storedUser),
+		iterations: 100_000,
+	}
+}
+
+func (s *InMemoryUserStore) AddUser(user User, password string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	hash, err := pbkdf2.Key(sha256.New, password, salt, s.iterations, 32)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.byUsername[user.Username]; exists {
+		return fmt.Errorf("usuario %s ya existe", user.Username)
+	}
+	if _, exists := s.byID[user.ID]; exists {
+		return fmt.Errorf("usuario con ID %s ya existe", user.ID)
+	}
+	stored := &storedUser{user: user, salt: salt, hash: hash}
+	s.byID[user.ID] = stored
+	s.byUsername[user.Username] = stored
+	return nil
+}
+
+func (s *InMemoryUserStore) RemoveUser(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, exists := s.byID[id]; exists {
+		delete(s.byUsername, stored.user.Username)
+		delete(s.byID, id)
+	}
+}
+
+func (s *InMemoryUserStore) VerifyCredentials(username, password string) (User, error) {
+	s.mu.RLock()
+	stored, exists := s.byUsername[username]
+	s.mu.RUnlock()
+
+	// Con usuario inexistente se deriva igual, para no revelarlo por el tiempo
+	salt, expected := make([]byte, 16), make([]byte, 32)
+	if exists {
+		salt, expected = stored.salt, stored.hash
+	}
+	hash, err := pbkdf2.Key(sha256.New, password, salt, s.iterations, 32)
+	if err != nil {
+		return User{}, err
+	}
+	if !exists || subtle.ConstantTimeCompare(hash, expected) != 1 {
+		return User{}, ErrInvalidCredentials
+	}
+	return stored.user, nil
+}
+
+func (s *InMemoryUserStore) GetUser(id string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored, exists := s.byID[id]
+	if !exists {
+		return User{}, fmt.Errorf("%w: %s", ErrUserNotFound, id)
+	}
+	return stored.user, nil
+}
+
+// ==============================================
+// REFRESH TOKENS REVOCABLES
+// ==============================================
+
+type refreshEntry struct {
+	userID    string
+	expiresAt time.Time
+	revoked   bool
+}
+
+// refreshStore guarda solo el hash de cada token opaco
+type refreshStore struct {
+	mu      sync.Mutex
+	entries map[string]*refreshEntry
+}
+
+func newRefreshStore() *refreshStore {
+	return &refreshStore{entries: make(map[string]*refreshEntry)}
+}
+
+func refreshKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (rs *refreshStore) issue(userID string, expiresAt time.Time) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.entries[refreshKey(token)] = &refreshEntry{userID: userID, expiresAt: expiresAt}
+	return token, nil
+}
+
+func (rs *refreshStore) lookup(token string, now time.Time) (string, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	entry, exists := rs.entries[refreshKey(token)]
+	switch {
+	case !exists:
+		return "", fmt.Errorf("%w: refresh token desconocido", ErrTokenMalformed)
+	case entry.revoked:
+		return "", ErrTokenRevoked
+	case now.After(entry.expiresAt):
+		delete(rs.entries, refreshKey(token))
+		return "", ErrTokenExpired
+	}
+	return entry.userID, nil
+}
+
+func (rs *refreshStore) revoke(token string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	entry, exists := rs.entries[refreshKey(token)]
+	if !exists {
+		return fmt.Errorf("%w: refresh token desconocido", ErrTokenMalformed)
+	}
+	entry.revoked = true
+	return nil
+}
+
+func (rs *refreshStore) revokeUser(userID string) int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	revoked := 0
+	for _, entry := range rs.entries {
+		if entry.userID == userID && !entry.revoked {
+			entry.revoked = true
+			revoked++
+		}
+	}
+	return revoked
+}
+
+// ==============================================
+// OPERACIONES DEL JWT AUTHENTICATOR
+// ==============================================
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+func (ja *JWTAuthenticator) ConfigSchema() ConfigSchema {
+	return ConfigSchema{
+		"issuer":      {Type: "string", Default: "go-deep"},
+		"audience":    {Type: "string", Default: "go-deep-api"},
+		"access_ttl":  {Type: "duration", Default: "15m"},
+		"refresh_ttl": {Type: "duration", Default: "720h"},
+		"clock_skew":  {Type: "duration", Default: "30s"},
+	}
+}
+
+func (ja *JWTAuthenticator) Initialize(config map[string]interface{}) error {
+	if _, err := ja.keys.activeKey(); err != nil {
+		return err
+	}
+
+	ja.configMu.Lock()
+	defer ja.configMu.Unlock()
+	if issuer, ok := config["issuer"].(string); ok {
+		ja.issuer = issuer
+	}
+	if audience, ok := config["audience"].(string); ok {
+		ja.audience = audience
+	}
+	if ttl, ok := config["access_ttl"].(time.Duration); ok {
+		ja.accessTTL = ttl
+	}
+	if ttl, ok := config["refresh_ttl"].(time.Duration); ok {
+		ja.refreshTTL = ttl
+	}
+	if skew, ok := config["clock_skew"].(time.Duration); ok {
+		ja.clockSkew = skew
+	}
+	return ja.BaseProcessor.Initialize(config)
+}
+
+func (ja *JWTAuthenticator) settings() jwtSettings {
+	ja.configMu.RLock()
+	defer ja.configMu.RUnlock()
+	return ja.jwtSettings
+}
+
+// Login valida credenciales y emite un access token y un refresh token
+func (ja *JWTAuthenticator) Login(username, password string) (TokenPair, error) {
+	user, err := ja.users.VerifyCredentials(username, password)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	settings := ja.settings()
+	access, expiresAt, err := ja.issueAccessToken(settings, user)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := ja.refresh.issue(user.ID, ja.now().Add(settings.refreshTTL))
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresAt: expiresAt}, nil
+}
+
+func (ja *JWTAuthenticator) issueAccessToken(settings jwtSettings, user User) (string, time.Time, error) {
+	key, err := ja.keys.activeKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	jti, err := randomToken(12)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := ja.now()
+	expiresAt := now.Add(settings.accessTTL)
+	token, err := SignToken(key, Claims{
+		Issuer:    settings.issuer,
+		Subject:   user.ID,
+		Audience:  Audience{settings.audience},
+		ExpiresAt: expiresAt.Unix(),
+		NotBefore: now.Unix(),
+		IssuedAt:  now.Unix(),
+		ID:        jti,
+		Username:  user.Username,
+		Email:     user.Email,
+		Roles:     user.Roles,
+	})
+	return token, expiresAt, err
+}
+
+func (ja *JWTAuthenticator) verifyOptions() VerifyOptions {
+	settings := ja.settings()
+	return VerifyOptions{Issuer: settings.issuer, Audience: settings.audience, ClockSkew: settings.clockSkew, Now: ja.now()}
+}
+
+// RevokeRefreshToken invalida un refresh token concreto (logout)
+func (ja *JWTAuthenticator) RevokeRefreshToken(token string) error {
+	return ja.refresh.revoke(token)
+}
+
+// RevokeUserSessions invalida todos los refresh tokens de un usuario
+func (ja *JWTAuthenticator) RevokeUserSessions(userID string) int {
+	return ja.refresh.revokeUser(userID)
+}
+
+func (ja *JWTAuthenticator) Keys() *KeySet {
+	return ja.keys
+}
+
+// ==============================================
+// DEMOSTRACIÓN DE JWT
+// ==============================================
+
+func demoJWT() {
+	fmt.Println("🔑 DEMO: JWT Firmados y Rotación de Claves")
+	fmt.Println("==========================================")
+
+	users := NewInMemoryUserStore()
+	users.AddUser(User{ID: "42", Username: "ana", Email: "ana@example.com", Roles: []string{"editor"}}, "clave-larga")
+
+	keys := NewKeySet()
+	rsaKey, err := GenerateRSAKey("rsa-2026-01")
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	keys.Add(rsaKey)
+
+	// Reloj controlable para mostrar expiración y tolerancia
+	clock := time.Now()
+	auth := NewJWTAuthenticator(users, keys)
+	auth.now = func() time.Time { return clock }
+	auth.Initialize(map[string]interface{}{"access_ttl": time.Minute, "clock_skew": 5 * time.Second})
+
+	pair, err := auth.Login("ana", "clave-larga")
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	fmt.Printf("✅ Token RS256 emitido (%d bytes)\n", len(pair.AccessToken))
+	if _, err := auth.Login("ana", "incorrecta"); errors.Is(err, ErrInvalidCredentials) {
+		fmt.Printf("❌ Login con contraseña incorrecta: %v\n", err)
+	}
+
+	// Un servicio externo verifica solo con el JWKS publicado
+	jwks, _ := keys.JWKS()
+	remote, err := ParseJWKS(jwks)
+	if err == nil {
+		claims, err := VerifyToken(remote, pair.AccessToken, auth.verifyOptions())
+		fmt.Printf("🌐 Verificado con JWKS: sub=%s roles=%v err=%v\n", claims.Subject, claims.Roles, err)
+	}
+
+	// Un token alterado no pasa la verificación
+	parts := strings.Split(pair.AccessToken, ".")
+	forged, _ := json.Marshal(Claims{Subject: "42", Roles: []string{"admin"}, ExpiresAt: clock.Add(time.Hour).Unix()})
+	tampered := parts[0] + "." + b64.EncodeToString(forged) + "." + parts[2]
+	if _, err := auth.ValidateToken(tampered); errors.Is(err, ErrTokenSignature) {
+		fmt.Printf("🛡️ Token alterado rechazado: %v\n", err)
+	}
+
+	// Rotación: la clave nueva firma, la anterior sigue verificando
+	ecKey, _ := GenerateECKey("ec-2026-02")
+	keys.Add(ecKey)
+	keys.Activate(ecKey.ID)
+	newPair, _ := auth.Login("ana", "clave-larga")
+	_, errOld := auth.ValidateToken(pair.AccessToken)
+	_, errNew := auth.ValidateToken(newPair.AccessToken)
+	fmt.Printf("🔄 Tras rotar a ES256: token RS256 válido=%t, token ES256 válido=%t\n", errOld == nil, errNew == nil)
+	keys.Retire(rsaKey.ID)
+	if _, err := auth.ValidateToken(pair.AccessToken); errors.Is(err, ErrUnknownKey) {
+		fmt.Printf("🗑️ Clave RS256 retirada: %v\n", err)
+	}
+
+	// Expiración con tolerancia de reloj
+	clock = clock.Add(time.Minute + 3*time.Second)
+	_, err = auth.ValidateToken(newPair.AccessToken)
+	fmt.Printf("⏱️ 3s después de exp (tolerancia 5s): válido=%t\n", err == nil)
+	clock = clock.Add(10 * time.Second)
+	if _, err := auth.ValidateToken(newPair.AccessToken); errors.Is(err, ErrTokenExpired) {
+		fmt.Printf("⏱️ 13s después de exp: %v\n", err)
+	}
+
+	refreshed, err := auth.RefreshToken(newPair.RefreshToken)
+	if err == nil {
+		_, err = auth.ValidateToken(refreshed)
+	}
+	fmt.Printf("♻️ Nuevo access token vía refresh: válido=%t\n", err == nil)
+
+	auth.RevokeRefreshToken(newPair.RefreshToken)
+	if _, err := auth.RefreshToken(newPair.RefreshToken); errors.Is(err, ErrTokenRevoked) {
+		fmt.Printf("🚫 Refresh tras logout: %v\n", err)
+	}
+	fmt.Printf("🚫 Sesiones restantes revocadas: %d\n\n", auth.RevokeUserSessions("42"))
+}
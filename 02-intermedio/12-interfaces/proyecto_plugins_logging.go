@@ -0,0 +1,869 @@
+// Archivo: proyecto_plugins_logging.go
+// Proyecto: Sistema de Plugins - Backends de logging estructurado
+// Demuestra: encoders intercambiables detrás de una interfaz, io.Writer
+// componibles (rotación, escritura asíncrona), muestreo y un adaptador a log/slog
+
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ==============================================
+// REGISTROS Y ENCODERS
+// ==============================================
+
+type LogRecord struct {
+	Time    time.Time
+	Level   LogLevel
+	Logger  string
+	Message string
+	Fields  map[string]interface{}
+}
+
+type LogEncoder interface {
+	Encode(record LogRecord) []byte
+}
+
+func sortedFieldKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TextEncoder es el formato legible de consola, opcionalmente con colores
+type TextEncoder struct {
+	Color bool
+}
+
+var levelColors = map[LogLevel]string{
+	DEBUG: "\033[36m", // Cyan
+	INFO:  "\033[32m", // Green
+	WARN:  "\033[33m", // Yellow
+	ERROR: "\033[31m", // Red
+	FATAL: "\033[35m", // Magenta
+}
+
+func (e TextEncoder) Encode(record LogRecord) []byte {
+	level := record.Level.String()
+	if e.Color {
+		level = levelColors[record.Level] + level + "\033[0m"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s [%s] %s", record.Time.Format("2006-01-02 15:04:05"), level, record.Message)
+	if len(record.Fields) > 0 {
+		fieldsJSON, _ := json.Marshal(record.Fields)
+		fmt.Fprintf(&b, " | Fields: %s", fieldsJSON)
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// JSONEncoder escribe un objeto por línea; un campo que choque con las claves
+// reservadas se guarda con el prefijo "fields."
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(record LogRecord) []byte {
+	entry := map[string]interface{}{
+		"time":  record.Time.Format(time.RFC3339Nano),
+		"level": strings.ToLower(record.Level.String()),
+		"msg":   record.Message,
+	}
+	if record.Logger != "" {
+		entry["logger"] = record.Logger
+	}
+	for key, value := range record.Fields {
+		if _, reserved := entry[key]; reserved {
+			key = "fields." + key
+		}
+		entry[key] = value
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		line, _ = json.Marshal(map[string]string{
+			"time": entry["time"].(string), "level": "error", "msg": "error codificando log: " + err.Error(),
+		})
+	}
+	return append(line, '\n')
+}
+
+// LogfmtEncoder produce pares clave=valor, con los campos ordenados
+type LogfmtEncoder struct{}
+
+func (LogfmtEncoder) Encode(record LogRecord) []byte {
+	var b strings.Builder
+	writePair := func(key string, value interface{}) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(value))
+	}
+
+	writePair("time", record.Time.Format(time.RFC3339))
+	writePair("level", strings.ToLower(record.Level.String()))
+	if record.Logger != "" {
+		writePair("logger", record.Logger)
+	}
+	writePair("msg", record.Message)
+	for _, key := range sortedFieldKeys(record.Fields) {
+		writePair(key, record.Fields[key])
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+func logfmtValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case time.Time:
+		s = v.Format(time.RFC3339)
+	case time.Duration:
+		s = v.String()
+	case error:
+		s = v.Error()
+	case nil:
+		s = ""
+	case int, int64, float64, bool:
+		s = fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			s = fmt.Sprint(v)
+		} else {
+			s = string(data)
+		}
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func encoderForFormat(format string, color bool) (LogEncoder, error) {
+	switch format {
+	case "text":
+		return TextEncoder{Color: color}, nil
+	case "json":
+		return JSONEncoder{}, nil
+	case "logfmt":
+		return LogfmtEncoder{}, nil
+	}
+	return nil, fmt.Errorf("formato de log desconocido: %q", format)
+}
+
+// ==============================================
+// MUESTREO POR NIVEL
+// ==============================================
+
+// LevelSampling registra los primeros First mensajes iguales de cada
+// ventana y después uno de cada Thereafter (0 descarta el resto)
+type LevelSampling struct {
+	First      int
+	Thereafter int
+}
+
+// Sampler limita los mensajes repetidos; los niveles sin regla no se muestrean
+type Sampler struct {
+	mu          sync.Mutex
+	tick        time.Duration
+	rules       map[LogLevel]LevelSampling
+	windowStart time.Time
+	counts      map[string]int
+	dropped     map[LogLevel]int64
+}
+
+func NewSampler(tick time.Duration, rules map[LogLevel]LevelSampling) *Sampler {
+	return &Sampler{
+		tick:    tick,
+		rules:   rules,
+		counts:  make(map[string]int),
+		dropped: make(map[LogLevel]int64),
+	}
+}
+
+func (s *Sampler) Allow(level LogLevel, message string, now time.Time) bool {
+	rule, sampled := s.rules[level]
+	if !sampled {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.windowStart) >= s.tick {
+		s.windowStart = now
+		s.counts = make(map[string]int)
+	}
+	key := level.String() + "|" + message
+	s.counts[key]++
+	n := s.counts[key]
+
+	if n <= rule.First || (rule.Thereafter > 0 && (n-rule.First)%rule.Thereafter == 0) {
+		return true
+	}
+	s.dropped[level]++
+	return false
+}
+
+func (s *Sampler) Dropped() map[LogLevel]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dropped := make(map[LogLevel]int64, len(s.dropped))
+	for level, n := range s.dropped {
+		dropped[level] = n
+	}
+	return dropped
+}
+
+// ==============================================
+// ARCHIVOS CON ROTACIÓN
+// ==============================================
+
+type RotationConfig struct {
+	MaxSize    int64         // bytes; 0 desactiva la rotación por tamaño
+	Interval   time.Duration // 0 desactiva la rotación por tiempo
+	MaxBackups int           // 0 conserva todos
+	Compress   bool
+}
+
+// RotatingFile renombra el archivo activo a path.<timestamp> al rotar y,
+// si se pide, lo comprime con gzip. La compresión es síncrona: delante de
+// un AsyncWriter no bloquea a quien registra.
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	config   RotationConfig
+	file     *os.File
+	closed   bool
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+}
+
+func OpenRotatingFile(path string, config RotationConfig) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, config: config, now: time.Now}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file, rf.size, rf.openedAt = file, info.Size(), rf.now()
+	return nil
+}
+
+// Write nunca pierde el registro por una rotación fallida: si no se llegó a
+// abrir el archivo nuevo se reabre el actual y el error se devuelve junto
+// con la escritura
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.closed {
+		return 0, os.ErrClosed
+	}
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	var rotateErr error
+	bySize := rf.config.MaxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.config.MaxSize
+	byTime := rf.config.Interval > 0 && rf.now().Sub(rf.openedAt) >= rf.config.Interval
+	if bySize || byTime {
+		if rotateErr = rf.rotate(); rf.file == nil {
+			if err := rf.open(); err != nil {
+				return 0, errors.Join(rotateErr, err)
+			}
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, errors.Join(rotateErr, err)
+}
+
+// rotate deja rf.file en nil si falla antes de abrir el archivo nuevo
+func (rf *RotatingFile) rotate() error {
+	err := rf.file.Close()
+	rf.file = nil
+	if err != nil {
+		return err
+	}
+
+	stamp := rf.now().Format(backupStampLayout)
+	backup := rf.path + "." + stamp
+	for i := 1; fileExists(backup) || fileExists(backup+".gz"); i++ {
+		backup = fmt.Sprintf("%s.%s-%d", rf.path, stamp, i)
+	}
+	if err := os.Rename(rf.path, backup); err != nil {
+		return err
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+
+	if rf.config.Compress {
+		if err := gzipFile(backup); err != nil {
+			return err
+		}
+	}
+	return rf.prune()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// backupStampLayout es la marca de tiempo de los respaldos; si dos
+// rotaciones caen en el mismo milisegundo se añade "-N"
+const backupStampLayout = "20060102-150405.000"
+
+type backupFile struct {
+	path string
+	at   time.Time
+	seq  int
+}
+
+// parseBackup interpreta <path>.<marca>[-N][.gz]; false si el archivo no es
+// un respaldo de este RotatingFile
+func (rf *RotatingFile) parseBackup(path string) (backupFile, bool) {
+	rest := strings.TrimSuffix(strings.TrimPrefix(path, rf.path+"."), ".gz")
+	if len(rest) < len(backupStampLayout) {
+		return backupFile{}, false
+	}
+	at, err := time.ParseInLocation(backupStampLayout, rest[:len(backupStampLayout)], time.Local)
+	if err != nil {
+		return backupFile{}, false
+	}
+	backup := backupFile{path: path, at: at}
+	if suffix := rest[len(backupStampLayout):]; suffix != "" {
+		seq, err := strconv.Atoi(strings.TrimPrefix(suffix, "-"))
+		if !strings.HasPrefix(suffix, "-") || err != nil || seq < 1 {
+			return backupFile{}, false
+		}
+		backup.seq = seq
+	}
+	return backup, true
+}
+
+// Backups devuelve los archivos rotados, del más antiguo al más reciente.
+// Se ordenan por marca y secuencia: como texto ".120-1.gz" quedaría antes
+// que ".120.gz" y prune borraría el más nuevo.
+func (rf *RotatingFile) Backups() ([]string, error) {
+	matches, err := filepath.Glob(rf.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var backups []backupFile
+	for _, match := range matches {
+		if backup, ok := rf.parseBackup(match); ok {
+			backups = append(backups, backup)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].at.Equal(backups[j].at) {
+			return backups[i].at.Before(backups[j].at)
+		}
+		return backups[i].seq < backups[j].seq
+	})
+	paths := make([]string, len(backups))
+	for i, backup := range backups {
+		paths[i] = backup.path
+	}
+	return paths, nil
+}
+
+func (rf *RotatingFile) prune() error {
+	if rf.config.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := rf.Backups()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for len(backups) > rf.config.MaxBackups {
+		errs = append(errs, os.Remove(backups[0]))
+		backups = backups[1:]
+	}
+	return errors.Join(errs...)
+}
+
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	rf.closed = true
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+// ==============================================
+// ESCRITURA ASÍNCRONA CON POLÍTICA DE DESCARTE
+// ==============================================
+
+type DropPolicy string
+
+const (
+	DropNewest    DropPolicy = "drop_newest"
+	DropOldest    DropPolicy = "drop_oldest"
+	BlockWhenFull DropPolicy = "block"
+)
+
+type asyncEntry struct {
+	data    []byte
+	flushed chan struct{}
+}
+
+// AsyncWriter encola las escrituras y las vuelca desde una goroutine; con el
+// buffer lleno aplica la política configurada en lugar de frenar al llamador
+type AsyncWriter struct {
+	out     io.Writer
+	queue   chan asyncEntry
+	policy  DropPolicy
+	dropped atomic.Int64
+	lastErr atomic.Value
+	mu      sync.RWMutex
+	closed  bool
+	done    chan struct{}
+}
+
+func NewAsyncWriter(out io.Writer, size int, policy DropPolicy) *AsyncWriter {
+	aw := &AsyncWriter{
+		out:    out,
+		queue:  make(chan asyncEntry, size),
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	go aw.run()
+	return aw
+}
+
+func (aw *AsyncWriter) run() {
+	defer close(aw.done)
+	for entry := range aw.queue {
+		if entry.flushed != nil {
+			close(entry.flushed)
+			continue
+		}
+		if _, err := aw.out.Write(entry.data); err != nil {
+			aw.lastErr.Store(err)
+		}
+	}
+}
+
+func (aw *AsyncWriter) Write(p []byte) (int, error) {
+	aw.mu.RLock()
+	defer aw.mu.RUnlock()
+	if aw.closed {
+		return 0, os.ErrClosed
+	}
+
+	// El llamador puede reutilizar p en cuanto Write retorna
+	entry := asyncEntry{data: append([]byte(nil), p...)}
+	switch aw.policy {
+	case BlockWhenFull:
+		aw.queue <- entry
+	case DropOldest:
+		for {
+			select {
+			case aw.queue <- entry:
+				return len(p), nil
+			default:
+			}
+			select {
+			case old := <-aw.queue:
+				if old.flushed != nil {
+					close(old.flushed)
+				} else {
+					aw.dropped.Add(1)
+				}
+			default:
+			}
+		}
+	default:
+		select {
+		case aw.queue <- entry:
+		default:
+			aw.dropped.Add(1)
+		}
+	}
+	return len(p), nil
+}
+
+// Flush espera a que se escriba todo lo encolado hasta ahora
+func (aw *AsyncWriter) Flush() {
+	aw.mu.RLock()
+	if aw.closed {
+		aw.mu.RUnlock()
+		return
+	}
+	flushed := make(chan struct{})
+	aw.queue <- asyncEntry{flushed: flushed}
+	aw.mu.RUnlock()
+	<-flushed
+}
+
+func (aw *AsyncWriter) Dropped() int64 {
+	return aw.dropped.Load()
+}
+
+// Close vacía la cola y devuelve el último error de escritura, si hubo
+func (aw *AsyncWriter) Close() error {
+	aw.mu.Lock()
+	if aw.closed {
+		aw.mu.Unlock()
+		return nil
+	}
+	aw.closed = true
+	close(aw.queue)
+	aw.mu.Unlock()
+
+	<-aw.done
+	if err, ok := aw.lastErr.Load().(error); ok {
+		return err
+	}
+	return nil
+}
+
+// ==============================================
+// INTEGRACIÓN CON LOS LOGGERS
+// ==============================================
+
+func (bl *BaseLogger) SetEncoder(encoder LogEncoder) {
+	bl.configMu.Lock()
+	defer bl.configMu.Unlock()
+	bl.encoder = encoder
+}
+
+func (bl *BaseLogger) SetSampler(sampler *Sampler) {
+	bl.configMu.Lock()
+	defer bl.configMu.Unlock()
+	bl.sampler = sampler
+}
+
+// prepare aplica nivel y muestreo y devuelve el encoder vigente, para que el
+// registro se codifique con la misma configuración con la que se filtró;
+// false si el registro se descarta
+func (bl *BaseLogger) prepare(level LogLevel, message string, fields map[string]interface{}) (LogRecord, LogEncoder, bool) {
+	bl.configMu.RLock()
+	minLevel, encoder, sampler := bl.level, bl.encoder, bl.sampler
+	bl.configMu.RUnlock()
+
+	if level < minLevel {
+		return LogRecord{}, nil, false
+	}
+	now := time.Now()
+	if sampler != nil && !sampler.Allow(level, message, now) {
+		return LogRecord{}, nil, false
+	}
+	return LogRecord{Time: now, Level: level, Logger: bl.name, Message: message, Fields: fields}, encoder, true
+}
+
+func (fl *FileLogger) ConfigSchema() ConfigSchema {
+	schema := fl.BaseLogger.ConfigSchema()
+	schema["filename"] = ConfigField{Type: "string", Default: "app.log"}
+	schema["max_size_kb"] = ConfigField{Type: "int", Default: 10240, Min: floatPtr(1)}
+	schema["rotate_every"] = ConfigField{Type: "duration", Default: "24h"}
+	schema["max_backups"] = ConfigField{Type: "int", Default: 7, Min: floatPtr(0)}
+	schema["compress"] = ConfigField{Type: "bool", Default: true}
+	schema["buffer_size"] = ConfigField{Type: "int", Default: 1024, Min: floatPtr(1)}
+	schema["drop_policy"] = ConfigField{Type: "string", Default: string(DropNewest),
+		Enum: []string{string(DropNewest), string(DropOldest), string(BlockWhenFull)}}
+	return schema
+}
+
+func (fl *FileLogger) Initialize(config map[string]interface{}) error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	if filename, ok := config["filename"].(string); ok {
+		fl.filename = filename
+	}
+	if kb, ok := config["max_size_kb"].(int); ok {
+		fl.rotation.MaxSize = int64(kb) * 1024
+	}
+	if every, ok := config["rotate_every"].(time.Duration); ok {
+		fl.rotation.Interval = every
+	}
+	if backups, ok := config["max_backups"].(int); ok {
+		fl.rotation.MaxBackups = backups
+	}
+	if compress, ok := config["compress"].(bool); ok {
+		fl.rotation.Compress = compress
+	}
+	if size, ok := config["buffer_size"].(int); ok {
+		fl.bufferSize = size
+	}
+	if policy, ok := config["drop_policy"].(string); ok {
+		fl.dropPolicy = DropPolicy(policy)
+	}
+	fl.closed = false
+	return fl.BaseLogger.Initialize(config)
+}
+
+// writerLocked abre el archivo en el primer registro, para que un
+// FileLogger sin uso no cree archivos
+func (fl *FileLogger) writerLocked() (*AsyncWriter, error) {
+	if fl.writer != nil {
+		return fl.writer, nil
+	}
+	file, err := OpenRotatingFile(fl.filename, fl.rotation)
+	if err != nil {
+		return nil, err
+	}
+	fl.file = file
+	fl.writer = NewAsyncWriter(file, fl.bufferSize, fl.dropPolicy)
+	return fl.writer, nil
+}
+
+// Flush espera a que los registros encolados lleguen al archivo
+func (fl *FileLogger) Flush() {
+	fl.mu.Lock()
+	writer := fl.writer
+	fl.mu.Unlock()
+	if writer != nil {
+		writer.Flush()
+	}
+}
+
+func (fl *FileLogger) Dropped() int64 {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	if fl.writer == nil {
+		return 0
+	}
+	return fl.writer.Dropped()
+}
+
+func (fl *FileLogger) Shutdown() error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	var errs []error
+	fl.closed = true
+	if fl.writer != nil {
+		errs = append(errs, fl.writer.Close(), fl.file.Close())
+		fl.writer, fl.file = nil, nil
+	}
+	errs = append(errs, fl.BaseLogger.Shutdown())
+	return errors.Join(errs...)
+}
+
+// ==============================================
+// ADAPTADOR PARA HANDLERS DE log/slog
+// ==============================================
+
+// SlogLogger permite registrar cualquier slog.Handler como plugin Logger
+type SlogLogger struct {
+	BaseLogger
+	handler slog.Handler
+}
+
+func NewSlogLogger(name string, handler slog.Handler) *SlogLogger {
+	return &SlogLogger{
+		BaseLogger: BaseLogger{
+			BaseProcessor: BaseProcessor{
+				name:        name,
+				version:     "1.0.0",
+				description: "Adaptador de un slog.Handler",
+				author:      "Go Deep Team",
+			},
+			level: DEBUG,
+		},
+		handler: handler,
+	}
+}
+
+// slog no tiene FATAL; se usa un nivel por encima de ERROR
+func toSlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case DEBUG:
+		return slog.LevelDebug
+	case INFO:
+		return slog.LevelInfo
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	}
+	return slog.LevelError + 4
+}
+
+func (sl *SlogLogger) Log(level LogLevel, message string, fields map[string]interface{}) {
+	record, _, ok := sl.prepare(level, message, fields)
+	if !ok {
+		return
+	}
+	ctx := context.Background()
+	slogLevel := toSlogLevel(level)
+	if !sl.handler.Enabled(ctx, slogLevel) {
+		return
+	}
+
+	slogRecord := slog.NewRecord(record.Time, slogLevel, message, 0)
+	for _, key := range sortedFieldKeys(fields) {
+		slogRecord.AddAttrs(slog.Any(key, fields[key]))
+	}
+	if err := sl.handler.Handle(ctx, slogRecord); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", sl.name, err)
+	}
+}
+
+// ==============================================
+// DEMOSTRACIÓN DE LOGGING ESTRUCTURADO
+// ==============================================
+
+// slowWriter simula un disco lento para forzar el descarte
+type slowWriter struct {
+	delay   time.Duration
+	written atomic.Int64
+}
+
+func (sw *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(sw.delay)
+	sw.written.Add(1)
+	return len(p), nil
+}
+
+func demoStructuredLogging() {
+	fmt.Println("🪵 DEMO: Logging Estructurado")
+	fmt.Println("=============================")
+
+	fields := map[string]interface{}{"user": "ana", "latency": 42 * time.Millisecond, "path": "/api/v1 items"}
+	record := LogRecord{Time: time.Now(), Level: INFO, Logger: "api", Message: "petición atendida", Fields: fields}
+	fmt.Printf("JSON:   %s", JSONEncoder{}.Encode(record))
+	fmt.Printf("logfmt: %s", LogfmtEncoder{}.Encode(record))
+
+	// Muestreo: 2 primeros DEBUG iguales por ventana y luego 1 de cada 4
+	console := NewConsoleLogger()
+	console.SetLevel(DEBUG)
+	console.SetEncoder(LogfmtEncoder{})
+	console.SetSampler(NewSampler(time.Minute, map[LogLevel]LevelSampling{DEBUG: {First: 2, Thereafter: 4}}))
+	for i := 1; i <= 10; i++ {
+		console.Log(DEBUG, "reintentando conexión", map[string]interface{}{"intento": i})
+	}
+	console.Log(ERROR, "conexión perdida", nil)
+	fmt.Printf("🎲 DEBUG descartados por muestreo: %d\n", console.sampler.Dropped()[DEBUG])
+
+	// Rotación por tamaño con compresión y máximo de respaldos
+	dir, err := os.MkdirTemp("", "plugins-logs-*")
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	fileLogger := NewFileLogger()
+	fileLogger.Initialize(map[string]interface{}{
+		"filename":    filepath.Join(dir, "app.log"),
+		"max_size_kb": 1,
+		"max_backups": 3,
+		"compress":    true,
+		"drop_policy": string(BlockWhenFull),
+	})
+	for i := 0; i < 60; i++ {
+		fileLogger.Log(INFO, "pedido procesado", map[string]interface{}{"pedido": i, "total": 19.99})
+	}
+	fileLogger.Flush()
+	backups, _ := fileLogger.file.Backups()
+	fileLogger.Shutdown()
+	fmt.Printf("🔁 Respaldos tras rotar (máx. 3):\n")
+	for _, backup := range backups {
+		fmt.Printf("   %s\n", filepath.Base(backup))
+	}
+
+	// Buffer pequeño sobre un disco lento: se descartan los más nuevos
+	slow := &slowWriter{delay: 5 * time.Millisecond}
+	async := NewAsyncWriter(slow, 4, DropNewest)
+	for i := 0; i < 50; i++ {
+		async.Write([]byte("evento\n"))
+	}
+	async.Close()
+	fmt.Printf("📉 Escritura asíncrona: %d escritos, %d descartados\n", slow.written.Load(), async.Dropped())
+
+	// Cualquier slog.Handler se registra como un plugin Logger más
+	manager := NewPluginManager()
+	manager.RegisterPlugin(NewSlogLogger("SlogText", slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+	manager.InitializeAll()
+	if logger, exists := Get[Logger](manager, "SlogText"); exists {
+		logger.Log(DEBUG, "filtrado por el handler", nil)
+		logger.Log(WARN, "cuota casi agotada", map[string]interface{}{"usado": 0.93, "plan": "pro"})
+	}
+	fmt.Println()
+}
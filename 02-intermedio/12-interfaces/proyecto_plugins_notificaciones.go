@@ -0,0 +1,853 @@
+// Archivo: proyecto_plugins_notificaciones.go
+// Proyecto: Sistema de Plugins - Despacho confiable de notificaciones
+// Demuestra: cola de prioridad con container/heap, workers con sync.Cond,
+// reintentos con backoff exponencial, historial persistente y dead-letter queue
+
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// ESTADOS DE ENTREGA
+// ==============================================
+
+type DeliveryState string
+
+const (
+	DeliveryQueued    DeliveryState = "queued"
+	DeliverySending   DeliveryState = "sending"
+	DeliveryRetrying  DeliveryState = "retrying"
+	DeliveryDelivered DeliveryState = "delivered"
+	DeliveryFailed    DeliveryState = "failed"
+)
+
+// Un notificador marca con ErrPermanentFailure los errores que no se
+// resuelven reintentando; cualquier otro error se considera transitorio
+var (
+	ErrPermanentFailure = errors.New("fallo permanente")
+	ErrDispatcherClosed = errors.New("dispatcher cerrado")
+	ErrAlreadyDelivered = errors.New("mensaje ya entregado")
+)
+
+// deliveryTracker guarda el último estado conocido de cada mensaje enviado
+// directamente por un notificador
+type deliveryTracker struct {
+	mu       sync.RWMutex
+	statuses map[string]DeliveryStatus
+}
+
+func (dt *deliveryTracker) record(messageID string, err error) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	if dt.statuses == nil {
+		dt.statuses = make(map[string]DeliveryStatus)
+	}
+	status := DeliveryStatus{Delivered: err == nil, Timestamp: time.Now(), State: DeliveryDelivered}
+	if err != nil {
+		status.State, status.Error = DeliveryFailed, err.Error()
+	}
+	dt.statuses[messageID] = status
+}
+
+func (dt *deliveryTracker) GetDeliveryStatus(messageID string) DeliveryStatus {
+	dt.mu.RLock()
+	defer dt.mu.RUnlock()
+
+	status, exists := dt.statuses[messageID]
+	if !exists {
+		return DeliveryStatus{Error: "mensaje desconocido"}
+	}
+	return status
+}
+
+// ==============================================
+// HISTORIAL DE ENTREGAS
+// ==============================================
+
+// PendingDelivery es lo necesario para retomar una entrega tras un reinicio
+type PendingDelivery struct {
+	Message   Message
+	Recipient string
+	Notifier  string
+}
+
+// DeliveryStore guarda cada transición de estado de un mensaje y el
+// contenido de las entregas que aún no han terminado
+type DeliveryStore interface {
+	Append(messageID string, status DeliveryStatus) error
+	History(messageID string) []DeliveryStatus
+	// Track guarda una entrega aceptada; deja de estar pendiente cuando se
+	// registra un estado delivered o failed para su mensaje
+	Track(pending PendingDelivery) error
+	Pending() []PendingDelivery
+}
+
+type InMemoryDeliveryStore struct {
+	mu      sync.RWMutex
+	history map[string][]DeliveryStatus
+	pending map[string]PendingDelivery
+}
+
+func NewInMemoryDeliveryStore() *InMemoryDeliveryStore {
+	return &InMemoryDeliveryStore{
+		history: make(map[string][]DeliveryStatus),
+		pending: make(map[string]PendingDelivery),
+	}
+}
+
+func (s *InMemoryDeliveryStore) Append(messageID string, status DeliveryStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history[messageID] = append(s.history[messageID], status)
+	if status.State == DeliveryDelivered || status.State == DeliveryFailed {
+		delete(s.pending, messageID)
+	}
+	return nil
+}
+
+func (s *InMemoryDeliveryStore) Track(pending PendingDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[pending.Message.ID] = pending
+	return nil
+}
+
+func (s *InMemoryDeliveryStore) Pending() []PendingDelivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pending := make([]PendingDelivery, 0, len(s.pending))
+	for _, p := range s.pending {
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Message.ID < pending[j].Message.ID })
+	return pending
+}
+
+func (s *InMemoryDeliveryStore) History(messageID string) []DeliveryStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]DeliveryStatus{}, s.history[messageID]...)
+}
+
+// deliveryRecord es una línea del log: una transición de estado o, si
+// lleva Pending, la entrega aceptada con el contenido del mensaje
+type deliveryRecord struct {
+	MessageID string           `json:"message_id"`
+	Status    DeliveryStatus   `json:"status"`
+	Pending   *PendingDelivery `json:"pending,omitempty"`
+}
+
+// FileDeliveryStore agrega una línea JSON por transición; al abrirse
+// reconstruye el historial, así sobrevive a reinicios del proceso
+type FileDeliveryStore struct {
+	*InMemoryDeliveryStore
+	mu   sync.Mutex
+	file *os.File
+}
+
+func OpenFileDeliveryStore(path string) (*FileDeliveryStore, error) {
+	store := &FileDeliveryStore{InMemoryDeliveryStore: NewInMemoryDeliveryStore()}
+
+	err := replayJSONLines(path, func(line []byte) error {
+		var record deliveryRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return err
+		}
+		if record.Pending != nil {
+			return store.InMemoryDeliveryStore.Track(*record.Pending)
+		}
+		return store.InMemoryDeliveryStore.Append(record.MessageID, record.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	store.file = file
+	return store, nil
+}
+
+// replayJSONLines llama a apply con cada línea completa de path. Una última
+// línea sin terminar es la que deja un proceso que cae en mitad de un
+// Append: se recorta para que el archivo se pueda seguir usando. Cualquier
+// otra línea ilegible es un error.
+func replayJSONLines(path string, apply func(line []byte) error) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var complete int64
+	for number := 1; ; number++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				return file.Truncate(complete)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := apply(line[:len(line)-1]); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, number, err)
+		}
+		complete += int64(len(line))
+	}
+}
+
+func (s *FileDeliveryStore) Append(messageID string, status DeliveryStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := appendJSONLine(s.file, deliveryRecord{MessageID: messageID, Status: status}); err != nil {
+		return err
+	}
+	return s.InMemoryDeliveryStore.Append(messageID, status)
+}
+
+func (s *FileDeliveryStore) Track(pending PendingDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := appendJSONLine(s.file, deliveryRecord{MessageID: pending.Message.ID, Pending: &pending}); err != nil {
+		return err
+	}
+	return s.InMemoryDeliveryStore.Track(pending)
+}
+
+// appendJSONLine escribe v y el salto de línea con una sola llamada, así
+// un corte deja como mucho la última línea a medias
+func appendJSONLine(file *os.File, v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileDeliveryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// ==============================================
+// DEAD-LETTER QUEUE
+// ==============================================
+
+type DeadLetter struct {
+	Message   Message
+	Recipient string
+	Notifier  string
+	Attempts  int
+	LastError string
+	FailedAt  time.Time
+}
+
+type DeadLetterStore interface {
+	Put(letter DeadLetter) error
+	List() []DeadLetter
+	Take(messageID string) (DeadLetter, bool)
+}
+
+type InMemoryDeadLetterStore struct {
+	mu      sync.Mutex
+	letters map[string]DeadLetter
+}
+
+func NewInMemoryDeadLetterStore() *InMemoryDeadLetterStore {
+	return &InMemoryDeadLetterStore{letters: make(map[string]DeadLetter)}
+}
+
+func (s *InMemoryDeadLetterStore) Put(letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters[letter.Message.ID] = letter
+	return nil
+}
+
+func (s *InMemoryDeadLetterStore) List() []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters := make([]DeadLetter, 0, len(s.letters))
+	for _, letter := range s.letters {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i].FailedAt.Before(letters[j].FailedAt) })
+	return letters
+}
+
+// Take saca la carta de la cola, para reenviarla o descartarla
+func (s *InMemoryDeadLetterStore) Take(messageID string) (DeadLetter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	letter, exists := s.letters[messageID]
+	delete(s.letters, messageID)
+	return letter, exists
+}
+
+type deadLetterRecord struct {
+	Op     string     `json:"op"` // put o take
+	Letter DeadLetter `json:"letter"`
+}
+
+// FileDeadLetterStore registra cada Put y Take como una línea JSON; al
+// abrirse los reproduce para reconstruir la cola
+type FileDeadLetterStore struct {
+	*InMemoryDeadLetterStore
+	mu   sync.Mutex
+	file *os.File
+}
+
+func OpenFileDeadLetterStore(path string) (*FileDeadLetterStore, error) {
+	store := &FileDeadLetterStore{InMemoryDeadLetterStore: NewInMemoryDeadLetterStore()}
+
+	err := replayJSONLines(path, func(line []byte) error {
+		var record deadLetterRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return err
+		}
+		switch record.Op {
+		case "put":
+			return store.InMemoryDeadLetterStore.Put(record.Letter)
+		case "take":
+			store.InMemoryDeadLetterStore.Take(record.Letter.Message.ID)
+			return nil
+		default:
+			return fmt.Errorf("operación desconocida %q", record.Op)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	store.file = file
+	return store, nil
+}
+
+func (s *FileDeadLetterStore) Put(letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := appendJSONLine(s.file, deadLetterRecord{Op: "put", Letter: letter}); err != nil {
+		return err
+	}
+	return s.InMemoryDeadLetterStore.Put(letter)
+}
+
+// Take no puede devolver error: si no se persiste, la carta reaparece al
+// reabrir y reenviarla de nuevo la rechaza Enqueue si ya se entregó
+func (s *FileDeadLetterStore) Take(messageID string) (DeadLetter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	letter, exists := s.InMemoryDeadLetterStore.Take(messageID)
+	if !exists {
+		return letter, false
+	}
+	if err := appendJSONLine(s.file, deadLetterRecord{Op: "take", Letter: DeadLetter{Message: Message{ID: messageID}}}); err != nil {
+		fmt.Fprintf(os.Stderr, "dead-letter: no se pudo registrar la salida de %s: %v\n", messageID, err)
+	}
+	return letter, true
+}
+
+func (s *FileDeadLetterStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// ==============================================
+// COLA DE PRIORIDAD
+// ==============================================
+
+type delivery struct {
+	message   Message
+	recipient string
+	notifier  string
+	attempt   int
+	seq       uint64
+}
+
+// deliveryQueue ordena por Priority ascendente (1 es la más urgente) y, a
+// igual prioridad, por orden de llegada
+type deliveryQueue []*delivery
+
+func (q deliveryQueue) Len() int { return len(q) }
+func (q deliveryQueue) Less(i, j int) bool {
+	if q[i].message.Priority != q[j].message.Priority {
+		return q[i].message.Priority < q[j].message.Priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q deliveryQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *deliveryQueue) Push(x interface{}) { *q = append(*q, x.(*delivery)) }
+func (q *deliveryQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// ==============================================
+// DISPATCHER
+// ==============================================
+
+type DispatcherConfig struct {
+	Workers     int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{Workers: 4, MaxAttempts: 5, BaseBackoff: time.Second, MaxBackoff: time.Minute}
+}
+
+type NotificationDispatcher struct {
+	manager     *PluginManager
+	config      DispatcherConfig
+	store       DeliveryStore
+	deadLetters DeadLetterStore
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    deliveryQueue
+	seq      uint64
+	inFlight map[string]bool
+	closing  bool
+	started  bool
+	wg       sync.WaitGroup
+}
+
+func NewNotificationDispatcher(manager *PluginManager, store DeliveryStore, deadLetters DeadLetterStore, config DispatcherConfig) *NotificationDispatcher {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+	d := &NotificationDispatcher{
+		manager:     manager,
+		config:      config,
+		store:       store,
+		deadLetters: deadLetters,
+		inFlight:    make(map[string]bool),
+	}
+	d.cond = sync.NewCond(&d.mu)
+	return d
+}
+
+// Enqueue valida el destino y encola el mensaje. Un ID ya entregado o aún
+// en curso se rechaza, lo que hace idempotentes los reintentos del llamador.
+func (d *NotificationDispatcher) Enqueue(notifierName, recipient string, message Message) error {
+	// Se acepta aunque el notificador esté deshabilitado: se reintenta al entregar
+	plugin, exists := d.manager.GetPlugin(notifierName)
+	notifier, isNotifier := plugin.(Notifier)
+	if !exists || !isNotifier {
+		return fmt.Errorf("notificador %s no registrado", notifierName)
+	}
+	if !containsMessageType(notifier.SupportedTypes(), message.Type) {
+		return fmt.Errorf("%s no soporta mensajes de tipo %s", notifierName, message.Type)
+	}
+	if message.ID == "" {
+		return fmt.Errorf("el mensaje necesita un ID")
+	}
+
+	// La comprobación de entregado va bajo el mismo lock que inFlight: un
+	// worker registra la entrega antes de soltar el ID en finish, así que
+	// el mensaje aparece en uno de los dos y no se encola dos veces
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closing {
+		return ErrDispatcherClosed
+	}
+	if d.inFlight[message.ID] {
+		return fmt.Errorf("mensaje %s ya está en cola", message.ID)
+	}
+	if last, ok := d.Status(message.ID); ok && last.Delivered {
+		return fmt.Errorf("%w: %s", ErrAlreadyDelivered, message.ID)
+	}
+	pending := PendingDelivery{Message: message, Recipient: recipient, Notifier: notifierName}
+	if err := d.store.Track(pending); err != nil {
+		return fmt.Errorf("no se pudo guardar el mensaje %s: %w", message.ID, err)
+	}
+	d.inFlight[message.ID] = true
+	d.record(message.ID, DeliveryStatus{State: DeliveryQueued, Notifier: notifierName})
+	d.pushLocked(&delivery{message: message, recipient: recipient, notifier: notifierName, attempt: 1})
+	return nil
+}
+
+// Recover vuelve a encolar las entregas que el store tiene a medias: las
+// que seguían en cola, enviándose o esperando un reintento cuando terminó
+// el proceso anterior. Una entrega que se estaba enviando se repite, así
+// que la garantía es de al menos una vez.
+func (d *NotificationDispatcher) Recover() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closing {
+		return 0
+	}
+
+	recovered := 0
+	for _, pending := range d.store.Pending() {
+		id := pending.Message.ID
+		if d.inFlight[id] {
+			continue
+		}
+		attempt := 1
+		if last, ok := d.Status(id); ok {
+			attempt = max(last.Attempt, 1)
+			if last.State == DeliveryRetrying {
+				attempt++
+			}
+		}
+		d.inFlight[id] = true
+		d.record(id, DeliveryStatus{State: DeliveryQueued, Attempt: attempt, Notifier: pending.Notifier})
+		d.pushLocked(&delivery{message: pending.Message, recipient: pending.Recipient, notifier: pending.Notifier, attempt: attempt})
+		recovered++
+	}
+	return recovered
+}
+
+func containsMessageType(types []MessageType, target MessageType) bool {
+	for _, t := range types {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *NotificationDispatcher) pushLocked(item *delivery) {
+	d.seq++
+	item.seq = d.seq
+	heap.Push(&d.queue, item)
+	d.cond.Signal()
+}
+
+// Start lanza los workers; encolar antes de Start permite ver el orden por prioridad
+func (d *NotificationDispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.started {
+		return
+	}
+	d.started = true
+	for i := 0; i < d.config.Workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+}
+
+func (d *NotificationDispatcher) worker() {
+	defer d.wg.Done()
+	for {
+		d.mu.Lock()
+		for d.queue.Len() == 0 && !(d.closing && len(d.inFlight) == 0) {
+			d.cond.Wait()
+		}
+		if d.queue.Len() == 0 {
+			d.mu.Unlock()
+			return
+		}
+		item := heap.Pop(&d.queue).(*delivery)
+		d.mu.Unlock()
+
+		d.deliver(item)
+	}
+}
+
+func (d *NotificationDispatcher) deliver(item *delivery) {
+	id := item.message.ID
+	d.record(id, DeliveryStatus{State: DeliverySending, Attempt: item.attempt, Notifier: item.notifier})
+
+	notifier, enabled := Get[Notifier](d.manager, item.notifier)
+	_, registered := d.manager.GetPlugin(item.notifier)
+	var err error
+	switch {
+	case !registered:
+		err = fmt.Errorf("%w: notificador %s no disponible", ErrPermanentFailure, item.notifier)
+	case !enabled:
+		err = fmt.Errorf("notificador %s deshabilitado", item.notifier)
+	default:
+		err = notifier.Send(item.recipient, item.message)
+	}
+
+	if err == nil {
+		d.record(id, DeliveryStatus{Delivered: true, State: DeliveryDelivered, Attempt: item.attempt, Notifier: item.notifier})
+		d.finish(id)
+		return
+	}
+
+	if errors.Is(err, ErrPermanentFailure) || item.attempt >= d.config.MaxAttempts {
+		// La carta se guarda antes del estado failed, que es el que saca la
+		// entrega de las pendientes del store
+		letter := DeadLetter{
+			Message:   item.message,
+			Recipient: item.recipient,
+			Notifier:  item.notifier,
+			Attempts:  item.attempt,
+			LastError: err.Error(),
+			FailedAt:  time.Now(),
+		}
+		if err := d.deadLetters.Put(letter); err != nil {
+			fmt.Fprintf(os.Stderr, "dispatcher: no se pudo guardar la dead letter de %s: %v\n", id, err)
+		}
+		d.record(id, DeliveryStatus{State: DeliveryFailed, Attempt: item.attempt, Notifier: item.notifier, Error: err.Error()})
+		d.finish(id)
+		return
+	}
+
+	wait := d.backoff(item.attempt)
+	d.record(id, DeliveryStatus{
+		State:       DeliveryRetrying,
+		Attempt:     item.attempt,
+		Notifier:    item.notifier,
+		Error:       err.Error(),
+		NextAttempt: time.Now().Add(wait),
+	})
+	item.attempt++
+	time.AfterFunc(wait, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.pushLocked(item)
+	})
+}
+
+// backoff duplica la espera en cada intento, con jitter para que los
+// reintentos de muchos mensajes no coincidan
+func (d *NotificationDispatcher) backoff(attempt int) time.Duration {
+	wait := d.config.BaseBackoff << (attempt - 1)
+	if wait <= 0 || wait > d.config.MaxBackoff {
+		wait = d.config.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
+
+func (d *NotificationDispatcher) finish(messageID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.inFlight, messageID)
+	if len(d.inFlight) == 0 {
+		d.cond.Broadcast()
+	}
+}
+
+func (d *NotificationDispatcher) record(messageID string, status DeliveryStatus) {
+	status.Timestamp = time.Now()
+	if err := d.store.Append(messageID, status); err != nil {
+		fmt.Fprintf(os.Stderr, "dispatcher: no se pudo guardar el estado de %s: %v\n", messageID, err)
+	}
+}
+
+// Close deja de aceptar mensajes y espera a que todos los encolados, incluidos
+// sus reintentos, terminen entregados o en la dead-letter queue
+func (d *NotificationDispatcher) Close() {
+	d.mu.Lock()
+	d.closing = true
+	d.cond.Broadcast()
+	started := d.started
+	d.mu.Unlock()
+
+	if !started {
+		d.Start()
+	}
+	d.wg.Wait()
+}
+
+// Status devuelve el último estado registrado del mensaje
+func (d *NotificationDispatcher) Status(messageID string) (DeliveryStatus, bool) {
+	history := d.store.History(messageID)
+	if len(history) == 0 {
+		return DeliveryStatus{}, false
+	}
+	return history[len(history)-1], true
+}
+
+func (d *NotificationDispatcher) History(messageID string) []DeliveryStatus {
+	return d.store.History(messageID)
+}
+
+// Redrive devuelve a la cola un mensaje de la dead-letter queue
+func (d *NotificationDispatcher) Redrive(messageID string) error {
+	letter, exists := d.deadLetters.Take(messageID)
+	if !exists {
+		return fmt.Errorf("mensaje %s no está en la dead-letter queue", messageID)
+	}
+	if err := d.Enqueue(letter.Notifier, letter.Recipient, letter.Message); err != nil {
+		d.deadLetters.Put(letter)
+		return err
+	}
+	return nil
+}
+
+// ==============================================
+// DEMOSTRACIÓN DEL DISPATCHER
+// ==============================================
+
+// flakyNotifier falla de forma transitoria las primeras veces para cada
+// destinatario y de forma permanente para direcciones inválidas
+type flakyNotifier struct {
+	BaseProcessor
+	deliveryTracker
+	mu        sync.Mutex
+	failures  map[string]int
+	failFirst int
+}
+
+func (fn *flakyNotifier) SupportedTypes() []MessageType { return []MessageType{EMAIL, PUSH} }
+
+func (fn *flakyNotifier) Send(recipient string, message Message) error {
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+
+	var err error
+	switch {
+	case !strings.Contains(recipient, "@"):
+		err = fmt.Errorf("%w: destinatario inválido %q", ErrPermanentFailure, recipient)
+	case fn.failures[recipient] < fn.failFirst:
+		fn.failures[recipient]++
+		err = fmt.Errorf("timeout conectando con el servidor")
+	default:
+		fmt.Printf("   📨 P%d %s → %s\n", message.Priority, message.ID, recipient)
+	}
+	fn.record(message.ID, err)
+	return err
+}
+
+func demoNotificationDispatcher() {
+	fmt.Println("📬 DEMO: Despacho de Notificaciones con Reintentos")
+	fmt.Println("=================================================")
+
+	dir, err := os.MkdirTemp("", "plugins-deliveries-*")
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	store, err := OpenFileDeliveryStore(filepath.Join(dir, "deliveries.jsonl"))
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+
+	manager := NewPluginManager()
+	flaky := &flakyNotifier{
+		BaseProcessor: BaseProcessor{name: "FlakyMailer", version: "1.0.0"},
+		failures:      map[string]int{"ops@example.com": 0},
+		failFirst:     2,
+	}
+	manager.RegisterPlugin(flaky)
+	manager.InitializeAll()
+
+	deadLetters, err := OpenFileDeadLetterStore(filepath.Join(dir, "dead-letters.jsonl"))
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	dispatcher := NewNotificationDispatcher(manager, store, deadLetters, DispatcherConfig{
+		Workers: 1, MaxAttempts: 3, BaseBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond,
+	})
+
+	// Se encolan antes de arrancar para que el orden refleje la prioridad
+	messages := []struct {
+		recipient string
+		message   Message
+	}{
+		{"ana@example.com", Message{ID: "boletin-1", Subject: "Boletín", Type: EMAIL, Priority: 5}},
+		{"ops@example.com", Message{ID: "alerta-1", Subject: "Disco lleno", Type: EMAIL, Priority: 1}},
+		{"carlos@example.com", Message{ID: "factura-1", Subject: "Factura", Type: EMAIL, Priority: 3}},
+		{"sin-arroba", Message{ID: "push-1", Subject: "Promo", Type: PUSH, Priority: 4}},
+	}
+	for _, m := range messages {
+		if err := dispatcher.Enqueue("FlakyMailer", m.recipient, m.message); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		}
+	}
+	if err := dispatcher.Enqueue("FlakyMailer", "x@example.com", Message{ID: "sms-1", Type: SMS}); err != nil {
+		fmt.Printf("❌ Rechazado al encolar: %v\n", err)
+	}
+
+	dispatcher.Start()
+	dispatcher.Close()
+
+	fmt.Println("📜 Historial de alerta-1:")
+	for _, status := range dispatcher.History("alerta-1") {
+		line := fmt.Sprintf("   %-9s intento %d", status.State, status.Attempt)
+		if status.Error != "" {
+			line += " (" + status.Error + ")"
+		}
+		fmt.Println(line)
+	}
+	for _, letter := range deadLetters.List() {
+		fmt.Printf("☠️ Dead letter %s tras %d intento(s): %s\n", letter.Message.ID, letter.Attempts, letter.LastError)
+	}
+	fmt.Printf("🔎 Estado según el notificador: factura-1 entregado=%t\n", flaky.GetDeliveryStatus("factura-1").Delivered)
+
+	// Un dispatcher nuevo sobre el mismo store sigue rechazando lo ya entregado
+	dispatcher = NewNotificationDispatcher(manager, store, deadLetters, DispatcherConfig{Workers: 1, MaxAttempts: 3})
+	if err := dispatcher.Enqueue("FlakyMailer", "ana@example.com", messages[0].message); errors.Is(err, ErrAlreadyDelivered) {
+		fmt.Printf("🔁 Reenvío idempotente: %v\n", err)
+	}
+
+	// Un mensaje aceptado que no llega a enviarse: el proceso "cae" sin Close
+	dispatcher.Enqueue("FlakyMailer", "ana@example.com", Message{ID: "recordatorio-1", Subject: "Recordatorio", Type: EMAIL, Priority: 2})
+	store.Close()
+	deadLetters.Close()
+
+	// El historial, las entregas a medias y la dead-letter queue sobreviven al reinicio
+	store, err = OpenFileDeliveryStore(filepath.Join(dir, "deliveries.jsonl"))
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	defer store.Close()
+	deadLetters, err = OpenFileDeadLetterStore(filepath.Join(dir, "dead-letters.jsonl"))
+	if err != nil {
+		fmt.Printf("❌ %v\n\n", err)
+		return
+	}
+	defer deadLetters.Close()
+	fmt.Printf("💾 Tras reabrir: %d transiciones de alerta-1, %d dead letter(s)\n",
+		len(store.History("alerta-1")), len(deadLetters.List()))
+
+	dispatcher = NewNotificationDispatcher(manager, store, deadLetters, DispatcherConfig{Workers: 1, MaxAttempts: 3})
+	fmt.Printf("♻️ Entregas retomadas: %d\n", dispatcher.Recover())
+	dispatcher.Start()
+	dispatcher.Close()
+	if status, ok := dispatcher.Status("recordatorio-1"); ok {
+		fmt.Printf("   recordatorio-1 → %s\n", status.State)
+	}
+	fmt.Println()
+}
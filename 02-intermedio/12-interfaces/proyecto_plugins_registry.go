@@ -0,0 +1,309 @@
+// Archivo: proyecto_plugins_registry.go
+// Proyecto: Sistema de Plugins - Registro de capacidades con genéricos
+// Demuestra: funciones genéricas con parámetros de tipo interface, descubrimiento
+// de capacidades por type assertion y eventos del registro con suscriptores
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// CAPACIDADES
+// ==============================================
+
+var ErrPluginInUse = errors.New("plugin en uso")
+
+// capabilities asocia el nombre usado en configuración y pipelines con la
+// interface que lo implementa. Normalmente se modifica al arrancar, pero
+// capabilitiesMu permite registrar capacidades con plugins ya en uso.
+var capabilitiesMu sync.RWMutex
+
+var capabilities = map[string]func(PluginInfo) bool{
+	"processor":     implements[DataProcessor],
+	"logger":        implements[Logger],
+	"authenticator": implements[Authenticator],
+	"notifier":      implements[Notifier],
+}
+
+func implements[T any](plugin PluginInfo) bool {
+	_, ok := plugin.(T)
+	return ok
+}
+
+// RegisterCapability da de alta una capacidad nueva sin tocar el manager;
+// debe llamarse antes de registrar plugins, típicamente desde init()
+func RegisterCapability[T any](name string) {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	capabilities[name] = implements[T]
+}
+
+// CapabilitiesOf lista, ordenadas, las capacidades que implementa un plugin
+func CapabilitiesOf(plugin PluginInfo) []string {
+	capabilitiesMu.RLock()
+	defer capabilitiesMu.RUnlock()
+	var names []string
+	for name, has := range capabilities {
+		if has(plugin) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func pluginHasType(plugin PluginInfo, pluginType string) bool {
+	capabilitiesMu.RLock()
+	has, known := capabilities[pluginType]
+	capabilitiesMu.RUnlock()
+	return known && has(plugin)
+}
+
+// ==============================================
+// BÚSQUEDA TIPADA
+// ==============================================
+
+// Get devuelve el plugin con ese nombre si implementa T y está habilitado
+func Get[T any](pm *PluginManager, name string) (T, bool) {
+	var zero T
+	plugin, exists := pm.GetPlugin(name)
+	if !exists || !plugin.IsEnabled() {
+		return zero, false
+	}
+	typed, ok := plugin.(T)
+	if !ok {
+		return zero, false
+	}
+	return typed, true
+}
+
+// All devuelve, ordenados por nombre, los plugins habilitados que implementan T
+func All[T any](pm *PluginManager) []T {
+	pm.mu.RLock()
+	names := make([]string, 0, len(pm.plugins))
+	for name := range pm.plugins {
+		names = append(names, name)
+	}
+	pm.mu.RUnlock()
+	sort.Strings(names)
+
+	var found []T
+	for _, name := range names {
+		if typed, ok := Get[T](pm, name); ok {
+			found = append(found, typed)
+		}
+	}
+	return found
+}
+
+// ==============================================
+// EVENTOS DEL REGISTRO
+// ==============================================
+
+type PluginEventType string
+
+const (
+	EventRegistered   PluginEventType = "registered"
+	EventUnregistered PluginEventType = "unregistered"
+	EventEnabled      PluginEventType = "enabled"  // el plugin pasó a estar disponible
+	EventDisabled     PluginEventType = "disabled" // el plugin dejó de estar disponible
+	EventFailed       PluginEventType = "failed"   // el plugin no terminó su Shutdown a tiempo
+)
+
+type PluginEvent struct {
+	Type         PluginEventType
+	Plugin       string
+	Capabilities []string
+	State        PluginState
+	Time         time.Time
+}
+
+// Subscribe registra un handler para los eventos del registro y devuelve la
+// función que lo da de baja. Los handlers se llaman en la goroutine que
+// provocó el cambio, fuera del lock, así que pueden consultar el manager.
+func (pm *PluginManager) Subscribe(handler func(PluginEvent)) func() {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	id := pm.nextHandler
+	pm.nextHandler++
+	pm.handlers[id] = handler
+	return func() {
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+		delete(pm.handlers, id)
+	}
+}
+
+func (pm *PluginManager) emit(eventType PluginEventType, plugin PluginInfo) {
+	pm.mu.RLock()
+	ids := make([]int, 0, len(pm.handlers))
+	for id := range pm.handlers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	handlers := make([]func(PluginEvent), len(ids))
+	for i, id := range ids {
+		handlers[i] = pm.handlers[id]
+	}
+	event := PluginEvent{
+		Type:         eventType,
+		Plugin:       plugin.Name(),
+		Capabilities: CapabilitiesOf(plugin),
+		State:        pm.states[plugin.Name()],
+		Time:         time.Now(),
+	}
+	pm.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// ==============================================
+// HABILITAR Y DESHABILITAR EN TIEMPO DE EJECUCIÓN
+// ==============================================
+
+// EnablePlugin inicializa un plugin deshabilitado; sus dependencias deben
+// estar activas
+func (pm *PluginManager) EnablePlugin(name string) error {
+	if _, exists := pm.GetPlugin(name); !exists {
+		return fmt.Errorf("plugin %s no registrado", name)
+	}
+	pm.setEnabled(name, true)
+	if pm.State(name) == StateInitialized {
+		return nil
+	}
+	return pm.startPlugin(name)
+}
+
+// DisablePlugin apaga un plugin activo; se rechaza si otro plugin activo
+// depende de él. La comprobación y el apagado van bajo lifecycle, para que
+// ningún dependiente arranque entre una y otro.
+func (pm *PluginManager) DisablePlugin(name string) error {
+	if _, exists := pm.GetPlugin(name); !exists {
+		return fmt.Errorf("plugin %s no registrado", name)
+	}
+	pm.lifecycle.Lock()
+	defer pm.lifecycle.Unlock()
+	if users := pm.activeDependents(name); len(users) > 0 {
+		return fmt.Errorf("%w: %s es requerido por %s", ErrPluginInUse, name, strings.Join(users, ", "))
+	}
+	pm.setEnabled(name, false)
+	return pm.stopPluginLocked(name)
+}
+
+// UnregisterPlugin apaga el plugin si estaba activo y lo quita del registro
+func (pm *PluginManager) UnregisterPlugin(name string) error {
+	plugin, exists := pm.GetPlugin(name)
+	if !exists {
+		return fmt.Errorf("plugin %s no registrado", name)
+	}
+	pm.lifecycle.Lock()
+	defer pm.lifecycle.Unlock()
+	if users := pm.activeDependents(name); len(users) > 0 {
+		return fmt.Errorf("%w: %s es requerido por %s", ErrPluginInUse, name, strings.Join(users, ", "))
+	}
+	err := pm.stopPluginLocked(name)
+
+	pm.mu.Lock()
+	delete(pm.plugins, name)
+	delete(pm.states, name)
+	delete(pm.configs, name)
+	pm.mu.Unlock()
+
+	pm.emit(EventUnregistered, plugin)
+	return err
+}
+
+// setEnabled guarda la decisión en la configuración del plugin, de modo que
+// InitializeAll la respeta; la próxima ApplyConfig la reemplaza
+func (pm *PluginManager) setEnabled(name string, enabled bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	settings := pm.configs[name]
+	settings.enabled = enabled
+	pm.configs[name] = settings
+}
+
+func (pm *PluginManager) activeDependents(name string) []string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	var users []string
+	for other, plugin := range pm.plugins {
+		if pm.states[other] != StateInitialized {
+			continue
+		}
+		for _, raw := range plugin.Dependencies() {
+			if dep, err := ParseDependency(raw); err == nil && dep.Name == name {
+				users = append(users, other)
+			}
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// ==============================================
+// DEMOSTRACIÓN DEL REGISTRO
+// ==============================================
+
+// Auditor es una capacidad que el manager no conocía
+type Auditor interface {
+	PluginInfo
+	Audit(action, actor string) string
+}
+
+type auditPlugin struct {
+	BaseProcessor
+}
+
+func (ap *auditPlugin) Audit(action, actor string) string {
+	return fmt.Sprintf("%s realizó %s", actor, action)
+}
+
+func init() {
+	RegisterCapability[Auditor]("auditor")
+}
+
+func demoCapabilityRegistry() {
+	fmt.Println("🧩 DEMO: Registro de Capacidades Genérico")
+	fmt.Println("=========================================")
+
+	manager := NewPluginManager()
+	unsubscribe := manager.Subscribe(func(e PluginEvent) {
+		fmt.Printf("   📣 %-12s %-14s [%s]\n", e.Type, e.Plugin, strings.Join(e.Capabilities, ", "))
+	})
+	defer unsubscribe()
+
+	manager.RegisterPlugin(NewJSONProcessor())
+	manager.RegisterPlugin(NewConsoleLogger())
+	manager.RegisterPlugin(&auditPlugin{BaseProcessor{name: "Auditor", version: "1.0.0", dependencies: []string{"ConsoleLogger"}}})
+	manager.InitializeAll()
+
+	if auditor, ok := Get[Auditor](manager, "Auditor"); ok {
+		fmt.Printf("🔎 Get[Auditor]: %s\n", auditor.Audit("login", "ana"))
+	}
+	fmt.Printf("🔎 All[Logger]: %d | All[Auditor]: %d\n", len(All[Logger](manager)), len(All[Auditor](manager)))
+
+	// Un plugin deshabilitado desaparece de las búsquedas hasta que se habilita
+	if err := manager.DisablePlugin("ConsoleLogger"); errors.Is(err, ErrPluginInUse) {
+		fmt.Printf("❌ %v\n", err)
+	}
+	manager.DisablePlugin("JSONProcessor")
+	if _, ok := Get[DataProcessor](manager, "JSONProcessor"); !ok {
+		fmt.Printf("⏸️ JSONProcessor deshabilitado (estado: %s)\n", manager.State("JSONProcessor"))
+	}
+	manager.EnablePlugin("JSONProcessor")
+	manager.UnregisterPlugin("Auditor")
+	fmt.Printf("🔎 All[DataProcessor]: %d | All[Auditor]: %d\n", len(All[DataProcessor](manager)), len(All[Auditor](manager)))
+
+	manager.ShutdownAll(time.Second)
+	fmt.Println()
+}
@@ -0,0 +1,248 @@
+// Archivo: proyecto_plugins_smtp.go
+// Proyecto: Sistema de Plugins - Envío real de email por SMTP
+// Demuestra: net/smtp con STARTTLS y AUTH PLAIN, construcción de mensajes
+// MIME y clasificación de errores SMTP en transitorios y permanentes
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ==============================================
+// MODOS DE CONEXIÓN
+// ==============================================
+
+const (
+	SMTPStartTLS = "starttls" // puerto 587: texto plano que se eleva a TLS (obligatorio)
+	SMTPTLS      = "tls"      // puerto 465: TLS desde el primer byte
+	SMTPPlain    = "none"     // solo para relays locales de confianza
+)
+
+// SetTLSConfig permite confiar en una CA propia, como la de un servidor de pruebas
+func (en *EmailNotifier) SetTLSConfig(config *tls.Config) {
+	en.configMu.Lock()
+	defer en.configMu.Unlock()
+	en.tlsConfig = config
+}
+
+func (en *EmailNotifier) settings() smtpSettings {
+	en.configMu.RLock()
+	defer en.configMu.RUnlock()
+	return en.smtpSettings
+}
+
+func (s smtpSettings) clientTLSConfig() *tls.Config {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.tlsConfig != nil {
+		config = s.tlsConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = s.smtpServer
+	}
+	return config
+}
+
+// ==============================================
+// ENVÍO
+// ==============================================
+
+func (en *EmailNotifier) Send(recipient string, message Message) error {
+	settings := en.settings()
+	// Sin servidor configurado se muestra el email en lugar de enviarlo
+	if settings.smtpServer == "" {
+		fmt.Printf("📧 [EmailNotifier] (sin servidor SMTP) Email a: %s\n", recipient)
+		fmt.Printf("   Asunto: %s\n", message.Subject)
+		fmt.Printf("   Mensaje: %s\n", message.Body)
+		en.record(message.ID, nil)
+		return nil
+	}
+
+	err := settings.deliver(recipient, message)
+	en.record(message.ID, err)
+	return err
+}
+
+func (s smtpSettings) deliver(recipient string, message Message) error {
+	to, err := mail.ParseAddress(recipient)
+	if err != nil {
+		return fmt.Errorf("%w: destinatario inválido %q: %v", ErrPermanentFailure, recipient, err)
+	}
+	from, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return fmt.Errorf("%w: remitente inválido %q: %v", ErrPermanentFailure, s.from, err)
+	}
+	body, err := buildEmail(from, to, message, time.Now())
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.smtpServer, strconv.Itoa(s.port))
+	dialer := &net.Dialer{Timeout: s.timeout}
+	var conn net.Conn
+	if s.tlsMode == SMTPTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, s.clientTLSConfig())
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("conectando con %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	client, err := smtp.NewClient(conn, s.smtpServer)
+	if err != nil {
+		conn.Close()
+		return classifySMTPError("saludo", err)
+	}
+	defer client.Close()
+
+	if err := client.Hello("localhost"); err != nil {
+		return classifySMTPError("EHLO", err)
+	}
+	if s.tlsMode == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%w: %s no ofrece STARTTLS", ErrPermanentFailure, addr)
+		}
+		if err := client.StartTLS(s.clientTLSConfig()); err != nil {
+			return classifySMTPError("STARTTLS", err)
+		}
+	}
+	if s.username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("%w: %s no ofrece AUTH", ErrPermanentFailure, addr)
+		}
+		// PlainAuth se niega a enviar la contraseña sin TLS salvo a localhost
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.smtpServer)); err != nil {
+			return classifySMTPError("AUTH", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return classifySMTPError("MAIL FROM", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return classifySMTPError("RCPT TO", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return classifySMTPError("DATA", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return classifySMTPError("DATA", err)
+	}
+	if err := w.Close(); err != nil {
+		return classifySMTPError("DATA", err)
+	}
+	// Con el DATA aceptado el mensaje ya es responsabilidad del servidor: un
+	// fallo en QUIT no debe provocar un reintento que lo duplique
+	if err := client.Quit(); err != nil {
+		fmt.Fprintf(os.Stderr, "smtp: QUIT falló tras entregar a %s: %v\n", to.Address, err)
+	}
+	return nil
+}
+
+// classifySMTPError marca como permanentes las respuestas 5xx; las 4xx y
+// los errores de red se reintentan
+func classifySMTPError(stage string, err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return fmt.Errorf("%w: %s: %v", ErrPermanentFailure, stage, err)
+	}
+	return fmt.Errorf("%s: %w", stage, err)
+}
+
+// buildEmail arma un mensaje RFC 5322 en UTF-8 con cuerpo quoted-printable
+func buildEmail(from, to *mail.Address, message Message, now time.Time) ([]byte, error) {
+	// Un salto de línea en el asunto permitiría inyectar cabeceras
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(message.Subject)
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	if message.ID != "" {
+		fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", message.ID, domain)
+	}
+	if message.Priority == 1 {
+		buf.WriteString("X-Priority: 1\r\n")
+	}
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(message.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes(), nil
+}
+
+// ==============================================
+// CONFIGURACIÓN
+// ==============================================
+
+func (en *EmailNotifier) ConfigSchema() ConfigSchema {
+	return ConfigSchema{
+		"smtp_server": {Type: "string", Required: true},
+		"port":        {Type: "int", Default: 587, Min: floatPtr(1), Max: floatPtr(65535)},
+		"tls_mode":    {Type: "string", Default: SMTPStartTLS, Enum: []string{SMTPStartTLS, SMTPTLS, SMTPPlain}},
+		"username":    {Type: "string"},
+		"password":    {Type: "string"},
+		"from":        {Type: "string", Default: "no-reply@go-deep.dev"},
+		"timeout":     {Type: "duration", Default: "10s"},
+	}
+}
+
+func (en *EmailNotifier) Initialize(config map[string]interface{}) error {
+	en.configMu.Lock()
+	defer en.configMu.Unlock()
+
+	// Se valida sobre una copia: un error deja la configuración vigente
+	settings := en.smtpSettings
+	if server, ok := config["smtp_server"].(string); ok {
+		settings.smtpServer = server
+	}
+	if port, ok := config["port"].(int); ok {
+		settings.port = port
+	}
+	if mode, ok := config["tls_mode"].(string); ok {
+		settings.tlsMode = mode
+	}
+	if username, ok := config["username"].(string); ok {
+		settings.username = username
+	}
+	if password, ok := config["password"].(string); ok {
+		settings.password = password
+	}
+	if from, ok := config["from"].(string); ok {
+		if _, err := mail.ParseAddress(from); err != nil {
+			return fmt.Errorf("remitente inválido %q: %w", from, err)
+		}
+		settings.from = from
+	}
+	if timeout, ok := config["timeout"].(time.Duration); ok {
+		settings.timeout = timeout
+	}
+	en.smtpSettings = settings
+	return en.BaseProcessor.Initialize(config)
+}
@@ -0,0 +1,249 @@
+// Archivo: proyecto_plugins_webhook.go
+// Proyecto: Sistema de Plugins - Notificaciones por webhook firmadas
+// Demuestra: net/http como cliente, payloads JSON estilo Slack y firmas
+// HMAC-SHA256 con marca de tiempo contra ataques de repetición
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// FIRMA HMAC
+// ==============================================
+
+const (
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookIDHeader        = "X-Webhook-Id"
+)
+
+var ErrWebhookSignature = errors.New("firma de webhook inválida")
+
+// SignWebhook firma "v1:<timestamp>:<cuerpo>"; incluir la marca de tiempo
+// permite al receptor rechazar peticiones antiguas reenviadas
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v1:%d:", timestamp)
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature es lo que haría el receptor del webhook
+func VerifyWebhookSignature(secret, timestampHeader, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: marca de tiempo %q", ErrWebhookSignature, timestampHeader)
+	}
+	if age := now.Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: marca de tiempo fuera de tolerancia (%v)", ErrWebhookSignature, age.Round(time.Second))
+	}
+	if !hmac.Equal([]byte(SignWebhook(secret, timestamp, body)), []byte(signature)) {
+		return ErrWebhookSignature
+	}
+	return nil
+}
+
+// ==============================================
+// NOTIFICADOR GENÉRICO DE WEBHOOKS
+// ==============================================
+
+type WebhookPayload struct {
+	MessageID string `json:"message_id"`
+	Channel   string `json:"channel,omitempty"`
+	Recipient string `json:"recipient,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Text      string `json:"text"`
+	Priority  int    `json:"priority,omitempty"`
+}
+
+type WebhookNotifier struct {
+	BaseProcessor
+	deliveryTracker
+	configMu sync.RWMutex // una recarga en caliente no se cruza con un envío
+	webhookSettings
+	client *http.Client
+	now    func() time.Time
+}
+
+// webhookSettings es la configuración del WebhookNotifier; cada envío
+// trabaja sobre una copia
+type webhookSettings struct {
+	webhookURL string
+	secret     string
+	channel    string
+	username   string
+	timeout    time.Duration
+}
+
+func NewWebhookNotifier(name, url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		BaseProcessor: BaseProcessor{
+			name:        name,
+			version:     "1.0.0",
+			description: "Notificador vía webhook HTTP con firma HMAC",
+			author:      "Go Deep Team",
+		},
+		webhookSettings: webhookSettings{
+			webhookURL: url,
+			secret:     secret,
+			username:   "go-deep",
+			timeout:    10 * time.Second,
+		},
+		client: &http.Client{},
+		now:    time.Now,
+	}
+}
+
+func (wn *WebhookNotifier) settings() webhookSettings {
+	wn.configMu.RLock()
+	defer wn.configMu.RUnlock()
+	return wn.webhookSettings
+}
+
+func (wn *WebhookNotifier) SupportedTypes() []MessageType {
+	return []MessageType{SLACK, PUSH}
+}
+
+// Send publica el mensaje; un destinatario con "#" se usa como canal
+func (wn *WebhookNotifier) Send(recipient string, message Message) error {
+	settings := wn.settings()
+	payload := WebhookPayload{
+		MessageID: message.ID,
+		Channel:   settings.channel,
+		Username:  settings.username,
+		Text:      message.Body,
+		Priority:  message.Priority,
+	}
+	if message.Subject != "" {
+		payload.Text = "*" + message.Subject + "*\n" + message.Body
+	}
+	if strings.HasPrefix(recipient, "#") {
+		payload.Channel = recipient
+	} else {
+		payload.Recipient = recipient
+	}
+
+	// Sin URL configurada se muestra el payload en lugar de enviarlo
+	if settings.webhookURL == "" {
+		fmt.Printf("💬 [%s] (sin webhook configurado) Canal: %s\n", wn.name, payload.Channel)
+		fmt.Printf("   Mensaje: %s\n", message.Body)
+		wn.record(message.ID, nil)
+		return nil
+	}
+
+	err := wn.post(settings, payload)
+	wn.record(message.ID, err)
+	return err
+}
+
+func (wn *WebhookNotifier) post(settings webhookSettings, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanentFailure, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), settings.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, settings.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanentFailure, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-deep-plugins/"+wn.version)
+	req.Header.Set(WebhookIDHeader, payload.MessageID)
+	if settings.secret != "" {
+		timestamp := wn.now().Unix()
+		req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(settings.secret, timestamp, body))
+	}
+
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("enviando webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook respondió %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	default:
+		return fmt.Errorf("%w: webhook respondió %s: %s", ErrPermanentFailure, resp.Status, strings.TrimSpace(string(detail)))
+	}
+}
+
+func (wn *WebhookNotifier) ConfigSchema() ConfigSchema {
+	return ConfigSchema{
+		"webhook_url": {Type: "string", Required: true},
+		"secret":      {Type: "string"},
+		"channel":     {Type: "string"},
+		"username":    {Type: "string"},
+		"timeout":     {Type: "duration", Default: "10s"},
+	}
+}
+
+func (wn *WebhookNotifier) Initialize(config map[string]interface{}) error {
+	wn.configMu.Lock()
+	defer wn.configMu.Unlock()
+
+	// Se valida sobre una copia: un error deja la configuración vigente
+	settings := wn.webhookSettings
+	if url, ok := config["webhook_url"].(string); ok {
+		if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			return fmt.Errorf("URL de webhook inválida: %q", url)
+		}
+		settings.webhookURL = url
+	}
+	if secret, ok := config["secret"].(string); ok {
+		settings.secret = secret
+	}
+	if channel, ok := config["channel"].(string); ok {
+		settings.channel = channel
+	}
+	if username, ok := config["username"].(string); ok {
+		settings.username = username
+	}
+	if timeout, ok := config["timeout"].(time.Duration); ok {
+		settings.timeout = timeout
+	}
+	wn.webhookSettings = settings
+	return wn.BaseProcessor.Initialize(config)
+}
+
+// ==============================================
+// SLACK
+// ==============================================
+
+func (sn *SlackNotifier) SupportedTypes() []MessageType {
+	return []MessageType{SLACK}
+}
+
+func (sn *SlackNotifier) ConfigSchema() ConfigSchema {
+	schema := sn.WebhookNotifier.ConfigSchema()
+	schema["channel"] = ConfigField{Type: "string", Default: "#general"}
+	return schema
+}
+
+func (sn *SlackNotifier) Initialize(config map[string]interface{}) error {
+	if channel, ok := config["channel"].(string); ok && !strings.HasPrefix(channel, "#") {
+		return fmt.Errorf("canal de Slack inválido: %q", channel)
+	}
+	return sn.WebhookNotifier.Initialize(config)
+}
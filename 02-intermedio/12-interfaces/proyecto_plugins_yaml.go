@@ -0,0 +1,298 @@
+// Archivo: proyecto_plugins_yaml.go
+// Proyecto: Sistema de Plugins - Lector mínimo de YAML para la configuración
+// Soporta el subconjunto que usan los archivos de configuración: mapas y listas
+// en bloque, escalares, cadenas entre comillas, listas [a, b] y comentarios
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML devuelve map[string]interface{}, []interface{} o un escalar,
+// con la misma forma que produciría encoding/json
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(stripYAMLComment(raw), " \t\r")
+		if strings.TrimSpace(raw) == "" || raw == "---" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(raw, " "), "\t") {
+			return nil, fmt.Errorf("línea %d: YAML no admite tabs para indentar", i+1)
+		}
+		text := strings.TrimLeft(raw, " ")
+		p.lines = append(p.lines, yamlLine{indent: len(raw) - len(text), text: text, number: i + 1})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, err := p.parseNode(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("línea %d: indentación inesperada", p.lines[p.pos].number)
+	}
+	return value, nil
+}
+
+func stripYAMLComment(line string) string {
+	inSingle, inDouble := false, false
+	for i, r := range line {
+		switch {
+		case r == '\'' && !inDouble:
+			inSingle = !inSingle
+		case r == '"' && !inSingle:
+			inDouble = !inDouble
+		case r == '#' && !inSingle && !inDouble && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) parseNode(indent int) (interface{}, error) {
+	if isYAMLSeqItem(p.lines[p.pos].text) {
+		return p.parseSeq(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *yamlParser) parseSeq(indent int) ([]interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || !isYAMLSeqItem(line.text) {
+			return nil, fmt.Errorf("línea %d: se esperaba un elemento de lista", line.number)
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		switch {
+		case rest == "":
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				item, err := p.parseNode(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			} else {
+				items = append(items, nil)
+			}
+		case isYAMLSeqItem(rest) || yamlKeyEnd(rest) >= 0:
+			// "- clave: valor" abre un bloque alineado con el texto tras el guion
+			p.lines[p.pos] = yamlLine{indent: line.indent + len(line.text) - len(rest), text: rest, number: line.number}
+			item, err := p.parseNode(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		default:
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("línea %d: %w", line.number, err)
+			}
+			items = append(items, value)
+			p.pos++
+		}
+	}
+	return items, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || isYAMLSeqItem(line.text) {
+			return nil, fmt.Errorf("línea %d: indentación inesperada", line.number)
+		}
+
+		end := yamlKeyEnd(line.text)
+		if end < 0 {
+			return nil, fmt.Errorf("línea %d: se esperaba 'clave: valor'", line.number)
+		}
+		key, err := parseYAMLKey(line.text[:end])
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: %w", line.number, err)
+		}
+		if _, dup := result[key]; dup {
+			return nil, fmt.Errorf("línea %d: clave duplicada %q", line.number, key)
+		}
+		rest := strings.TrimSpace(line.text[end+1:])
+		p.pos++
+
+		if rest != "" {
+			if result[key], err = parseYAMLScalar(rest); err != nil {
+				return nil, fmt.Errorf("línea %d: %w", line.number, err)
+			}
+			continue
+		}
+
+		// Valor en bloque: más indentado, o una lista al mismo nivel
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && isYAMLSeqItem(next.text)) {
+				if result[key], err = p.parseNode(next.indent); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		result[key] = nil
+	}
+	return result, nil
+}
+
+// yamlKeyEnd devuelve la posición del ':' que separa la clave, o -1
+func yamlKeyEnd(text string) int {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, `'`) {
+		closing := strings.IndexByte(text[1:], text[0])
+		if closing < 0 {
+			return -1
+		}
+		after := closing + 2
+		if after < len(text) && text[after] == ':' && (after+1 == len(text) || text[after+1] == ' ') {
+			return after
+		}
+		return -1
+	}
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return -1
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return i
+		}
+	}
+	return -1
+}
+
+func parseYAMLKey(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if value, err := parseYAMLScalar(raw); err == nil {
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	}
+	if raw == "" {
+		return "", fmt.Errorf("clave vacía")
+	}
+	return raw, nil
+}
+
+func parseYAMLScalar(raw string) (interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		return strconv.Unquote(raw)
+	case strings.HasPrefix(raw, `'`):
+		if len(raw) < 2 || !strings.HasSuffix(raw, `'`) {
+			return nil, fmt.Errorf("cadena sin cerrar: %s", raw)
+		}
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+	case strings.HasPrefix(raw, "["):
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("lista sin cerrar: %s", raw)
+		}
+		items := []interface{}{}
+		inner := strings.TrimSpace(raw[1 : len(raw)-1])
+		if inner == "" {
+			return items, nil
+		}
+		parts, err := splitYAMLFlow(inner)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			item, err := parseYAMLScalar(part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case raw == "{}":
+		return map[string]interface{}{}, nil
+	case strings.HasPrefix(raw, "{"):
+		return nil, fmt.Errorf("mapas en línea no soportados: %s", raw)
+	}
+
+	// Como en YAML 1.2, yes/no/on/off son texto: solo true y false son booleanos
+	switch strings.ToLower(raw) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null", "~":
+		return nil, nil
+	}
+	// Como en inferScalar, solo la gramática de número de JSON: nan, .inf,
+	// 0x1F o 1_000 siguen siendo texto, y json.Number conserva los enteros
+	// que no caben en un float64
+	if jsonNumberPattern.MatchString(raw) {
+		return json.Number(raw), nil
+	}
+	return raw, nil
+}
+
+// splitYAMLFlow separa los elementos de una lista en línea por las comas que
+// no están dentro de comillas ni de una lista anidada
+func splitYAMLFlow(inner string) ([]string, error) {
+	var parts []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++ // el carácter escapado no cierra la cadena
+		case quote != 0 && c == quote:
+			// En comillas simples, '' es una comilla literal
+			if quote == '\'' && i+1 < len(inner) && inner[i+1] == '\'' {
+				i++
+				continue
+			}
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, inner[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return nil, fmt.Errorf("lista en línea sin cerrar: [%s]", inner)
+	}
+	return append(parts, inner[start:]), nil
+}
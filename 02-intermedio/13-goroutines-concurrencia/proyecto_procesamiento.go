@@ -8,8 +8,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
@@ -33,11 +36,14 @@ type Evento struct {
 
 // EventoProcesado representa un evento después de procesamiento
 type EventoProcesado struct {
-	EventoOriginal Evento        `json:"evento_original"`
-	Procesado      time.Time     `json:"procesado"`
-	Resultado      string        `json:"resultado"`
-	ProcessorID    int           `json:"processor_id"`
-	TiempoProceso  time.Duration `json:"tiempo_proceso"`
+	EventoOriginal Evento           `json:"evento_original"`
+	Procesado      time.Time        `json:"procesado"`
+	Resultado      string           `json:"resultado"`
+	Salida         ResultadoHandler `json:"salida"`
+	Error          string           `json:"error,omitempty"`
+	ProcessorID    int              `json:"processor_id"`
+	TiempoProceso  time.Duration    `json:"tiempo_proceso"`
+	TiempoEspera   time.Duration    `json:"tiempo_espera"` // tiempo en la cola de su prioridad
 }
 
 // Estadisticas del sistema
@@ -55,6 +61,9 @@ type Estadisticas struct {
 
 type GeneradorEventos struct {
 	eventosGenerados int64
+	idInicial        int64   // último ID de la ejecución anterior, si se reanudó
+	probReenvio      float64 // fracción de eventos que se envían dos veces
+	reenvios         int64
 	activo           bool
 	mu               sync.RWMutex
 }
@@ -65,7 +74,22 @@ func NewGeneradorEventos() *GeneradorEventos {
 	}
 }
 
-func (g *GeneradorEventos) GenerarEventos(ctx context.Context, output chan<- Evento, eventosPerSec int) {
+// Reanudar continúa la numeración de IDs tras el último de un checkpoint,
+// para que los eventos nuevos no choquen con los ya agregados
+func (g *GeneradorEventos) Reanudar(ultimoID int64) {
+	atomic.StoreInt64(&g.eventosGenerados, ultimoID)
+	atomic.StoreInt64(&g.idInicial, ultimoID)
+}
+
+// SimularReenvios hace que una fracción de los eventos se envíe dos veces,
+// como un productor que reintenta sin saber si el primer envío llegó
+func (g *GeneradorEventos) SimularReenvios(probabilidad float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.probReenvio = probabilidad
+}
+
+func (g *GeneradorEventos) GenerarEventos(ctx context.Context, output *ColaEventos, eventosPerSec int) {
 	ticker := time.NewTicker(time.Second / time.Duration(eventosPerSec))
 	defer ticker.Stop()
 
@@ -87,19 +111,32 @@ func (g *GeneradorEventos) GenerarEventos(ctx context.Context, output chan<- Eve
 				Priority:  rand.Intn(3) + 1,
 			}
 
-			select {
-			case output <- evento:
-				// Evento enviado exitosamente
-			default:
-				// Canal lleno, descartar evento
-				fmt.Printf("⚠️ Evento %d descartado - canal lleno\n", evento.ID)
+			// La política de contrapresión de la cola decide si se bloquea, se
+			// rechaza este evento o se expulsa otro para hacerle sitio
+			if _, descarte := output.Encolar(ctx, evento); descarte != nil {
+				fmt.Printf("⚠️ Evento %d descartado (%s, prioridad %d)\n",
+					descarte.Evento.ID, descarte.Motivo, descarte.Evento.Priority)
+			}
+
+			// Reintento del productor: mismo evento, mismo ID
+			g.mu.RLock()
+			reenviar := rand.Float64() < g.probReenvio
+			g.mu.RUnlock()
+			if reenviar {
+				atomic.AddInt64(&g.reenvios, 1)
+				output.Encolar(ctx, evento)
 			}
 		}
 	}
 }
 
+// GetEventosGenerados cuenta los eventos de esta ejecución, sin reenvíos
 func (g *GeneradorEventos) GetEventosGenerados() int64 {
-	return atomic.LoadInt64(&g.eventosGenerados)
+	return atomic.LoadInt64(&g.eventosGenerados) - atomic.LoadInt64(&g.idInicial)
+}
+
+func (g *GeneradorEventos) GetReenvios() int64 {
+	return atomic.LoadInt64(&g.reenvios)
 }
 
 // ==============================================
@@ -110,84 +147,107 @@ type ProcesadorEventos struct {
 	id                int
 	eventosProcesados int64
 	errores           int64
+	panics            int64
 	tiempoTotal       int64 // en nanosegundos
-	activo            bool
+	activo            atomic.Bool
+	latencias         *LatenciasPorPrioridad
+	handlers          *RegistroHandlers
+
+	// retiro corta solo la espera en la cola, para que el autoescalado
+	// pueda quitar el procesador sin abandonar el evento en curso
+	retiro  context.Context
+	retirar context.CancelFunc
 }
 
 func NewProcesadorEventos(id int) *ProcesadorEventos {
-	return &ProcesadorEventos{
-		id:     id,
-		activo: true,
-	}
+	p := &ProcesadorEventos{id: id}
+	p.activo.Store(true)
+	return p
+}
+
+func (p *ProcesadorEventos) Activo() bool {
+	return p.activo.Load()
 }
 
-func (p *ProcesadorEventos) ProcesarEventos(ctx context.Context, input <-chan Evento, output chan<- EventoProcesado, wg *sync.WaitGroup) {
+func (p *ProcesadorEventos) marcarRetirado() {
+	p.activo.Store(false)
+}
+
+func (p *ProcesadorEventos) ProcesarEventos(ctx context.Context, input *ColaEventos, output chan<- EventoProcesado, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	fmt.Printf("🔧 Procesador %d iniciado\n", p.id)
 
+	ctxCola := ctx
+	if p.retiro != nil {
+		ctxCola = p.retiro
+	}
+
 	for {
-		select {
-		case <-ctx.Done():
-			fmt.Printf("🛑 Procesador %d deteniéndose...\n", p.id)
-			return
-		case evento, ok := <-input:
-			if !ok {
-				fmt.Printf("✅ Procesador %d terminó - canal cerrado\n", p.id)
-				return
+		// La cola decide qué prioridad se atiende según la política configurada
+		evento, espera, ok := input.Desencolar(ctxCola)
+		if !ok {
+			if ctx.Err() != nil {
+				fmt.Printf("🛑 Procesador %d deteniéndose...\n", p.id)
+			} else if ctxCola.Err() != nil {
+				fmt.Printf("👋 Procesador %d retirado\n", p.id)
+			} else {
+				fmt.Printf("✅ Procesador %d terminó - cola cerrada\n", p.id)
 			}
+			return
+		}
 
-			inicio := time.Now()
+		inicio := time.Now()
 
-			// Simular procesamiento complejo
-			resultado := p.procesarEvento(evento)
+		// Ejecutar el handler registrado para la acción del evento
+		salida, err := p.procesarEvento(ctx, evento)
+		if ctx.Err() != nil {
+			fmt.Printf("🛑 Procesador %d deteniéndose...\n", p.id)
+			return
+		}
 
-			tiempoProceso := time.Since(inicio)
+		tiempoProceso := time.Since(inicio)
+		if p.latencias != nil {
+			p.latencias.Registrar(evento.Priority, espera+tiempoProceso)
+		}
 
-			// Crear evento procesado
-			eventoProcesado := EventoProcesado{
-				EventoOriginal: evento,
-				Procesado:      time.Now(),
-				Resultado:      resultado,
-				ProcessorID:    p.id,
-				TiempoProceso:  tiempoProceso,
-			}
+		// Crear evento procesado
+		eventoProcesado := EventoProcesado{
+			EventoOriginal: evento,
+			Procesado:      time.Now(),
+			Resultado:      salida.Mensaje,
+			Salida:         salida,
+			ProcessorID:    p.id,
+			TiempoProceso:  tiempoProceso,
+			TiempoEspera:   espera,
+		}
+		if err != nil {
+			eventoProcesado.Resultado = fmt.Sprintf("ERROR: %v", err)
+			eventoProcesado.Error = err.Error()
+		}
 
-			// Enviar resultado
-			select {
-			case output <- eventoProcesado:
-				atomic.AddInt64(&p.eventosProcesados, 1)
-				atomic.AddInt64(&p.tiempoTotal, int64(tiempoProceso))
-			case <-ctx.Done():
-				return
-			}
+		// Enviar resultado
+		select {
+		case output <- eventoProcesado:
+			atomic.AddInt64(&p.eventosProcesados, 1)
+			atomic.AddInt64(&p.tiempoTotal, int64(tiempoProceso))
+		case <-ctx.Done():
+			return
 		}
 	}
 }
 
-func (p *ProcesadorEventos) procesarEvento(evento Evento) string {
-	// Simular tiempo de procesamiento basado en prioridad
-	var tiempoProceso time.Duration
-	switch evento.Priority {
-	case 1: // Alta prioridad - procesamiento rápido
-		tiempoProceso = time.Duration(rand.Intn(50)) * time.Millisecond
-	case 2: // Media prioridad
-		tiempoProceso = time.Duration(rand.Intn(100)+50) * time.Millisecond
-	case 3: // Baja prioridad
-		tiempoProceso = time.Duration(rand.Intn(200)+100) * time.Millisecond
-	}
-
-	time.Sleep(tiempoProceso)
-
-	// Simular ocasional error (5% probabilidad)
-	if rand.Float32() < 0.05 {
+// procesarEvento delega en el registro de handlers; los panics y timeouts
+// llegan como errores y se cuentan igual que los fallos del handler
+func (p *ProcesadorEventos) procesarEvento(ctx context.Context, evento Evento) (ResultadoHandler, error) {
+	salida, err := p.handlers.Ejecutar(ctx, evento)
+	if err != nil && ctx.Err() == nil {
 		atomic.AddInt64(&p.errores, 1)
-		return fmt.Sprintf("ERROR: Falló procesamiento de %s", evento.Action)
+		if errors.Is(err, ErrPanicHandler) {
+			atomic.AddInt64(&p.panics, 1)
+		}
 	}
-
-	// Procesamiento exitoso
-	return fmt.Sprintf("Procesado: %s para usuario %d con prioridad %d",
-		evento.Action, evento.UserID, evento.Priority)
+	return salida, err
 }
 
 func (p *ProcesadorEventos) GetEstadisticas() (int64, int64, time.Duration) {
@@ -210,13 +270,17 @@ func (p *ProcesadorEventos) GetEstadisticas() (int64, int64, time.Duration) {
 type AgregadorResultados struct {
 	resultadosProcesados  int64
 	resultadosDescartados int64
+	duplicados            int64
 	estadisticas          map[string]int64
+	confirmados           *RegistroConfirmados // los eventos llegan en desorden
+	ventanas              []*AgregadorVentanas
 	mu                    sync.RWMutex
 }
 
-func NewAgregadorResultados() *AgregadorResultados {
+func NewAgregadorResultados(ventanaDedup int) *AgregadorResultados {
 	return &AgregadorResultados{
 		estadisticas: make(map[string]int64),
+		confirmados:  NewRegistroConfirmados(ventanaDedup),
 	}
 }
 
@@ -228,7 +292,12 @@ func (a *AgregadorResultados) AgregarResultados(ctx context.Context, input <-cha
 	for {
 		select {
 		case <-ctx.Done():
-			fmt.Println("🛑 Agregador deteniéndose...")
+			// Lo que ya salió de los procesadores se agrega igual: si se
+			// abandonara no estaría en el checkpoint y no se volvería a enviar
+			fmt.Println("🛑 Agregador deteniéndose, vaciando resultados pendientes...")
+			for resultado := range input {
+				a.procesarResultado(resultado)
+			}
 			return
 		case resultado, ok := <-input:
 			if !ok {
@@ -243,9 +312,31 @@ func (a *AgregadorResultados) AgregarResultados(ctx context.Context, input <-cha
 }
 
 func (a *AgregadorResultados) procesarResultado(resultado EventoProcesado) {
+	if !a.registrar(resultado) {
+		return
+	}
+
+	// Las ventanas tienen su propio lock y llaman a sus sinks: se alimentan
+	// fuera del lock del agregador
+	a.mu.RLock()
+	ventanas := a.ventanas
+	a.mu.RUnlock()
+	for _, v := range ventanas {
+		v.Agregar(resultado)
+	}
+}
+
+// registrar actualiza los totales; devuelve false si el resultado es un duplicado
+func (a *AgregadorResultados) registrar(resultado EventoProcesado) bool {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	// Un reenvío del mismo evento no vuelve a contar
+	if !a.confirmados.Confirmar(resultado.EventoOriginal.ID) {
+		a.duplicados++
+		return false
+	}
+
 	atomic.AddInt64(&a.resultadosProcesados, 1)
 
 	// Actualizar estadísticas por acción
@@ -256,6 +347,35 @@ func (a *AgregadorResultados) procesarResultado(resultado EventoProcesado) {
 	if a.resultadosProcesados%100 == 0 {
 		fmt.Printf("📈 Resultados procesados: %d\n", a.resultadosProcesados)
 	}
+	return true
+}
+
+// AgregarVentanas suma agregaciones por ventanas de tiempo de evento a las
+// que reciben los resultados ya deduplicados
+func (a *AgregadorResultados) AgregarVentanas(ventanas ...*AgregadorVentanas) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ventanas = append(a.ventanas, ventanas...)
+}
+
+// CerrarVentanas emite las ventanas abiertas; va tras vaciar el canal
+func (a *AgregadorResultados) CerrarVentanas() {
+	a.mu.RLock()
+	ventanas := a.ventanas
+	a.mu.RUnlock()
+	for _, v := range ventanas {
+		v.Cerrar()
+	}
+}
+
+func (a *AgregadorResultados) ResumenVentanas() []ResumenVentanas {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	resumenes := make([]ResumenVentanas, len(a.ventanas))
+	for i, v := range a.ventanas {
+		resumenes[i] = v.Resumen()
+	}
+	return resumenes
 }
 
 func (a *AgregadorResultados) GetEstadisticas() (int64, map[string]int64) {
@@ -271,21 +391,34 @@ func (a *AgregadorResultados) GetEstadisticas() (int64, map[string]int64) {
 	return atomic.LoadInt64(&a.resultadosProcesados), estadisticasCopia
 }
 
+func (a *AgregadorResultados) GetDuplicados() int64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.duplicados
+}
+
 // ==============================================
 // MONITOR DEL SISTEMA
 // ==============================================
 
 type MonitorSistema struct {
-	generador    *GeneradorEventos
-	procesadores []*ProcesadorEventos
-	agregador    *AgregadorResultados
+	generador *GeneradorEventos
+	pool      *PoolProcesadores
+	escalado  *ControladorEscalado
+	agregador *AgregadorResultados
+	cola      *ColaEventos
+	latencias *LatenciasPorPrioridad
 }
 
-func NewMonitorSistema(generador *GeneradorEventos, procesadores []*ProcesadorEventos, agregador *AgregadorResultados) *MonitorSistema {
+func NewMonitorSistema(generador *GeneradorEventos, pool *PoolProcesadores, escalado *ControladorEscalado,
+	agregador *AgregadorResultados, cola *ColaEventos, latencias *LatenciasPorPrioridad) *MonitorSistema {
 	return &MonitorSistema{
-		generador:    generador,
-		procesadores: procesadores,
-		agregador:    agregador,
+		generador: generador,
+		pool:      pool,
+		escalado:  escalado,
+		agregador: agregador,
+		cola:      cola,
+		latencias: latencias,
 	}
 }
 
@@ -320,20 +453,35 @@ func (m *MonitorSistema) mostrarEstadisticas() {
 	var totalProcesados, totalErrores int64
 	var tiempoPromedioTotal time.Duration
 
-	fmt.Println("\n👷 Procesadores:")
-	for _, procesador := range m.procesadores {
+	minimo, maximo := m.escalado.Limites()
+	fmt.Printf("\n👷 Procesadores (%d activos, min %d, max %d):\n", m.pool.Activos(), minimo, maximo)
+	for _, procesador := range m.pool.Todos() {
 		procesados, errores, tiempoPromedio := procesador.GetEstadisticas()
 		totalProcesados += procesados
 		totalErrores += errores
 		tiempoPromedioTotal += tiempoPromedio
 
-		fmt.Printf("  Procesador %d: %d procesados, %d errores, %v tiempo promedio\n",
-			procesador.id, procesados, errores, tiempoPromedio)
+		estado := ""
+		if !procesador.Activo() {
+			estado = " [retirado]"
+		}
+		fmt.Printf("  Procesador %d: %d procesados, %d errores (%d panics), %v tiempo promedio%s\n",
+			procesador.id, procesados, errores, atomic.LoadInt64(&procesador.panics), tiempoPromedio, estado)
+	}
+
+	// Últimas decisiones del autoescalado
+	if decisiones := m.escalado.Decisiones(); len(decisiones) > 0 {
+		fmt.Println("\n📐 Decisiones de escalado recientes:")
+		for _, d := range decisiones[max(len(decisiones)-5, 0):] {
+			fmt.Printf("  %s %d → %d: %s\n", d.Momento.Format("15:04:05.000"), d.Desde, d.Hasta, d.Motivo)
+		}
 	}
 
 	// Estadísticas del agregador
 	resultados, estadisticasAcciones := m.agregador.GetEstadisticas()
-	fmt.Printf("\n📈 Resultados agregados: %d\n", resultados)
+	// Los agregados y duplicados incluyen lo restaurado del checkpoint
+	fmt.Printf("\n📈 Resultados agregados: %d, duplicados ignorados: %d (reenvíos en esta ejecución: %d)\n",
+		resultados, m.agregador.GetDuplicados(), m.generador.GetReenvios())
 
 	// Estadísticas por acción
 	fmt.Println("\n📋 Estadísticas por acción:")
@@ -341,14 +489,45 @@ func (m *MonitorSistema) mostrarEstadisticas() {
 		fmt.Printf("  %s: %d\n", accion, cantidad)
 	}
 
+	// Ventanas de tiempo de evento
+	if resumenes := m.agregador.ResumenVentanas(); len(resumenes) > 0 {
+		fmt.Println("\n🪟 Ventanas:")
+		for _, r := range resumenes {
+			watermark := "-"
+			if !r.Watermark.IsZero() {
+				watermark = r.Watermark.Format("15:04:05.000")
+			}
+			fmt.Printf("  %-10s por %-7s: %4d emitidas, %4d abiertas, %d fuera de plazo, watermark %s\n",
+				r.Config.Tipo, r.Config.Clave, r.Emitidas, r.Abiertas, r.Tardios, watermark)
+		}
+	}
+
+	// Contrapresión: eventos perdidos y estado de la marca alta
+	presion := "normal"
+	if m.cola.EnPresion() {
+		presion = "sobre la marca alta"
+	}
+	descartes := m.cola.Descartes()
+	fmt.Printf("\n🚦 Contrapresión (%s): %s descartados, ocupación %s\n", m.cola.PoliticaContrapresion(), descartes, presion)
+
+	// Colas y latencia de extremo a extremo por prioridad
+	nombresPrioridad := [NumPrioridades]string{"alta", "media", "baja"}
+	pendientes := m.cola.Longitudes()
+	fmt.Printf("\n⏱️ Latencia por prioridad (planificación %s):\n", m.cola.Politica())
+	for i, nombre := range nombresPrioridad {
+		resumen := m.latencias.Resumen(i + 1)
+		fmt.Printf("  %-5s: %4d en cola, %5d atendidos, %4d descartados, p50 %v, p95 %v, p99 %v\n", nombre, pendientes[i],
+			resumen.Eventos, descartes.PorPrioridad[i], resumen.P50.Round(time.Millisecond), resumen.P95.Round(time.Millisecond), resumen.P99.Round(time.Millisecond))
+	}
+
 	// Métricas del sistema
 	fmt.Printf("\n🖥️ Sistema: %d goroutines activas, %d CPUs\n",
 		runtime.NumGoroutine(), runtime.NumCPU())
 
-	// Throughput
-	if eventosGenerados > 0 {
-		eficiencia := float64(totalProcesados) / float64(eventosGenerados) * 100
-		fmt.Printf("⚡ Eficiencia: %.1f%% (%d/%d)\n", eficiencia, totalProcesados, eventosGenerados)
+	// Throughput; los reenvíos también pasan por los procesadores
+	if enviados := eventosGenerados + m.generador.GetReenvios(); enviados > 0 {
+		eficiencia := float64(totalProcesados) / float64(enviados) * 100
+		fmt.Printf("⚡ Eficiencia: %.1f%% (%d/%d)\n", eficiencia, totalProcesados, enviados)
 	}
 
 	fmt.Println(separador)
@@ -359,95 +538,225 @@ func (m *MonitorSistema) mostrarEstadisticas() {
 // ==============================================
 
 type SistemaProcesamiento struct {
-	generador    *GeneradorEventos
-	procesadores []*ProcesadorEventos
-	agregador    *AgregadorResultados
-	monitor      *MonitorSistema
-
-	canalEventos    chan Evento
+	generador *GeneradorEventos
+	pool      *PoolProcesadores
+	escalado  *ControladorEscalado
+	agregador *AgregadorResultados
+	monitor   *MonitorSistema
+
+	procesadoresIniciales int
+	checkpoint            ConfigCheckpoint
+	almacen               *AlmacenCheckpoints // nil sin checkpoints
+
+	colaEventos     *ColaEventos
+	latencias       *LatenciasPorPrioridad
+	handlers        *RegistroHandlers
 	canalResultados chan EventoProcesado
 }
 
-func NewSistemaProcesamiento(numProcesadores int, bufferSize int) *SistemaProcesamiento {
+// NewSistemaProcesamiento arranca con numProcesadores y deja que el
+// controlador de escalado los ajuste entre los límites de escalado
+func NewSistemaProcesamiento(numProcesadores int, bufferSize int, planificacion ConfigPlanificador,
+	escalado ConfigEscalado, checkpoint ConfigCheckpoint) *SistemaProcesamiento {
 	// Crear componentes
 	generador := NewGeneradorEventos()
-	agregador := NewAgregadorResultados()
+	agregador := NewAgregadorResultados(checkpoint.VentanaDedup)
+	latencias := NewLatenciasPorPrioridad()
+	handlers := NewRegistroHandlers(time.Second)
 
-	// Crear procesadores
-	procesadores := make([]*ProcesadorEventos, numProcesadores)
-	for i := 0; i < numProcesadores; i++ {
-		procesadores[i] = NewProcesadorEventos(i + 1)
-	}
+	// Crear cola por prioridad y canal de resultados
+	colaEventos := NewColaEventos(planificacion)
+	canalResultados := make(chan EventoProcesado, bufferSize)
+
+	// Los procesadores se crean en el pool; todos comparten el registro de handlers
+	pool := NewPoolProcesadores(colaEventos, canalResultados, latencias, handlers)
+	controlador := NewControladorEscalado(escalado, pool, colaEventos)
+	minimo, maximo := controlador.Limites()
 
 	// Crear monitor
-	monitor := NewMonitorSistema(generador, procesadores, agregador)
+	monitor := NewMonitorSistema(generador, pool, controlador, agregador, colaEventos, latencias)
 
-	// Crear canales
-	canalEventos := make(chan Evento, bufferSize)
-	canalResultados := make(chan EventoProcesado, bufferSize)
+	var almacen *AlmacenCheckpoints
+	if checkpoint.Ruta != "" {
+		almacen = NewAlmacenCheckpoints(checkpoint.Ruta)
+	}
 
 	return &SistemaProcesamiento{
-		generador:       generador,
-		procesadores:    procesadores,
-		agregador:       agregador,
-		monitor:         monitor,
-		canalEventos:    canalEventos,
-		canalResultados: canalResultados,
+		generador:             generador,
+		pool:                  pool,
+		escalado:              controlador,
+		agregador:             agregador,
+		monitor:               monitor,
+		procesadoresIniciales: min(max(numProcesadores, minimo), maximo),
+		checkpoint:            checkpoint,
+		almacen:               almacen,
+		colaEventos:           colaEventos,
+		latencias:             latencias,
+		handlers:              handlers,
+		canalResultados:       canalResultados,
 	}
 }
 
+// Handlers devuelve el registro donde se dan de alta los handlers por acción
+func (s *SistemaProcesamiento) Handlers() *RegistroHandlers {
+	return s.handlers
+}
+
+// Reanudar restaura el agregador desde el último checkpoint y hace que el
+// generador siga numerando tras el último ID agregado. Devuelve false si no
+// había checkpoint que restaurar.
+func (s *SistemaProcesamiento) Reanudar() (bool, error) {
+	if s.almacen == nil {
+		return false, nil
+	}
+	estado, ok, err := s.almacen.Cargar()
+	if err != nil || !ok {
+		return false, err
+	}
+	s.agregador.Restaurar(estado)
+	s.generador.Reanudar(estado.UltimoID)
+	fmt.Printf("♻️ Reanudando desde checkpoint del %s: marca %d (+%d adelantados), último ID %d, %d resultados agregados\n",
+		estado.Guardado.Format(time.DateTime), estado.Marca, len(estado.Adelantados), estado.UltimoID, estado.ResultadosProcesados)
+	return true, nil
+}
+
+// Estadisticas resume el estado actual del sistema
+func (s *SistemaProcesamiento) Estadisticas() Estadisticas {
+	var estadisticas Estadisticas
+	var tiempoTotal time.Duration
+	for _, procesador := range s.pool.Todos() {
+		procesados, errores, tiempoPromedio := procesador.GetEstadisticas()
+		estadisticas.EventosProcesados += procesados
+		estadisticas.ErroresTotales += errores
+		tiempoTotal += tiempoPromedio * time.Duration(procesados)
+	}
+	if estadisticas.EventosProcesados > 0 {
+		estadisticas.TiempoPromedio = tiempoTotal / time.Duration(estadisticas.EventosProcesados)
+	}
+	estadisticas.EventosDescartados = s.colaEventos.Descartes().Total
+	estadisticas.ProcessorsActivos = s.pool.Activos()
+	return estadisticas
+}
+
+// plazoVaciado limita cuánto se espera a que los procesadores terminen la
+// cola al detenerse; lo que quede se pierde y no entra en el checkpoint
+const plazoVaciado = 10 * time.Second
+
+// Ejecutar genera eventos durante duracion. Al terminar se cierra la cola y
+// se espera a que los procesadores la vacíen antes de cancelar su contexto:
+// cancelar primero perdería lo encolado y lo que ya estaba en proceso.
 func (s *SistemaProcesamiento) Ejecutar(duracion time.Duration, eventosPerSec int) {
-	ctx, cancel := context.WithTimeout(context.Background(), duracion)
+	ctxGeneracion, cancelGeneracion := context.WithTimeout(context.Background(), duracion)
+	defer cancelGeneracion()
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	var wg sync.WaitGroup
+	var wgAgregador sync.WaitGroup
 
+	minimo, maximo := s.escalado.Limites()
 	fmt.Println("🚀 INICIANDO SISTEMA DE PROCESAMIENTO CONCURRENTE")
-	fmt.Printf("📊 Configuración: %d procesadores, %d eventos/seg, duración %v\n\n",
-		len(s.procesadores), eventosPerSec, duracion)
+	fmt.Printf("📊 Configuración: %d procesadores (autoescalado %d-%d), %d eventos/seg, duración %v\n\n",
+		s.procesadoresIniciales, minimo, maximo, eventosPerSec, duracion)
 
 	// Iniciar generador de eventos
-	go s.generador.GenerarEventos(ctx, s.canalEventos, eventosPerSec)
+	go s.generador.GenerarEventos(ctxGeneracion, s.colaEventos, eventosPerSec)
 
-	// Iniciar procesadores
-	for _, procesador := range s.procesadores {
-		wg.Add(1)
-		go procesador.ProcesarEventos(ctx, s.canalEventos, s.canalResultados, &wg)
-	}
+	// Iniciar procesadores y el controlador que ajusta su número
+	s.pool.Iniciar(ctx, s.procesadoresIniciales)
+	escaladoTerminado := make(chan struct{})
+	go func() {
+		defer close(escaladoTerminado)
+		s.escalado.Ejecutar(ctxGeneracion)
+	}()
 
 	// Iniciar agregador
-	wg.Add(1)
-	go s.agregador.AgregarResultados(ctx, s.canalResultados, &wg)
+	wgAgregador.Add(1)
+	go s.agregador.AgregarResultados(ctx, s.canalResultados, &wgAgregador)
 
 	// Iniciar monitor
 	go s.monitor.Monitorear(ctx, 2*time.Second)
 
-	// Esperar a que termine el contexto
-	<-ctx.Done()
-
-	// Cerrar canales para permitir que las goroutines terminen limpiamente
-	close(s.canalEventos)
-
-	// Esperar a que todos los procesadores terminen
-	wg.Wait()
+	// Guardar checkpoints periódicos del agregador
+	checkpointsTerminados := make(chan struct{})
+	go func() {
+		defer close(checkpointsTerminados)
+		if s.almacen != nil {
+			s.agregador.GuardarCheckpoints(ctx, s.almacen, s.checkpoint.Intervalo)
+		}
+	}()
+
+	// Esperar a que termine la generación
+	<-ctxGeneracion.Done()
+
+	// Con la cola cerrada Desencolar sigue entregando lo encolado y los
+	// procesadores terminan cuando queda vacía
+	s.colaEventos.Cerrar()
+
+	// Sin el controlador en marcha ya no se agregan procesadores al pool
+	<-escaladoTerminado
+	vaciado := make(chan struct{})
+	go func() {
+		s.pool.Esperar()
+		close(vaciado)
+	}()
+	select {
+	case <-vaciado:
+	case <-time.After(plazoVaciado):
+		fmt.Printf("⏱️ La cola no se vació en %v, se cancelan los procesadores\n", plazoVaciado)
+		cancel()
+		<-vaciado
+	}
 	close(s.canalResultados)
+	wgAgregador.Wait()
+	s.agregador.CerrarVentanas()
+
+	// El guardado periódico termina antes del final para que una foto
+	// anterior no pise a la definitiva
+	cancel()
+	<-checkpointsTerminados
+
+	// Checkpoint final, con todos los resultados ya agregados
+	if s.almacen != nil {
+		if err := s.almacen.Guardar(s.agregador.Checkpoint()); err != nil {
+			fmt.Printf("❌ Error guardando checkpoint final: %v\n", err)
+		} else {
+			fmt.Printf("💾 Checkpoint guardado en %s\n", s.almacen.Ruta())
+		}
+	}
 
 	// Mostrar estadísticas finales
 	fmt.Println("\n🏁 SISTEMA DETENIDO - ESTADÍSTICAS FINALES:")
 	s.monitor.mostrarEstadisticas()
+	final := s.Estadisticas()
+	fmt.Printf("📦 Procesados: %d | Descartados: %d | Errores: %d | Tiempo promedio: %v\n",
+		final.EventosProcesados, final.EventosDescartados, final.ErroresTotales, final.TiempoPromedio)
 }
 
 // ==============================================
 // FUNCIÓN PRINCIPAL
 // ==============================================
 
+// rutaCheckpoint usa PROCESAMIENTO_CHECKPOINT si está definida y si no el
+// directorio de caché del usuario, no un archivo compartido en /tmp. Sin
+// ninguno de los dos se desactivan los checkpoints.
+func rutaCheckpoint() string {
+	if ruta := os.Getenv("PROCESAMIENTO_CHECKPOINT"); ruta != "" {
+		return ruta
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cache, "go-deep", "procesamiento-checkpoint.json")
+}
+
 func main() {
 	fmt.Println("🏭 PROYECTO: Sistema de Procesamiento de Datos Concurrente")
 	fmt.Println("==========================================================")
 
 	// Configuración del sistema
-	numProcesadores := runtime.NumCPU() // Usar todos los CPUs disponibles
-	bufferSize := 1000                  // Buffer de canales
+	numProcesadores := runtime.NumCPU() // Procesadores iniciales; el autoescalado los ajusta
+	bufferSize := 1000                  // Buffer de canales y capacidad de cada cola de prioridad
 	eventosPerSec := 50                 // Eventos por segundo
 	duracion := 10 * time.Second        // Duración de la simulación
 
@@ -460,8 +769,66 @@ func main() {
 	// Configurar random seed
 	rand.Seed(time.Now().UnixNano())
 
+	// Comparar políticas de planificación antes de la simulación completa
+	demoPlanificacion()
+	demoContrapresion()
+	demoVentanas()
+
 	// Crear y ejecutar sistema
-	sistema := NewSistemaProcesamiento(numProcesadores, bufferSize)
+	planificacion := ConfigPlanificadorPorDefecto(bufferSize) // pesos 6:3:1 para alta, media y baja
+	planificacion.Contrapresion = ConfigContrapresion{
+		Politica:       ContrapresionDescartarMenorPrioridad,
+		CapacidadTotal: bufferSize,
+		MarcaAlta:      0.8,
+		MarcaBaja:      0.5,
+		AlCambiarPresion: func(senal SenalPresion) {
+			if senal.Alta {
+				fmt.Printf("🚨 Cola sobre la marca alta: %d/%d eventos\n", senal.Ocupacion, senal.Capacidad)
+			} else {
+				fmt.Printf("🟢 Cola bajo la marca baja: %d/%d eventos\n", senal.Ocupacion, senal.Capacidad)
+			}
+		},
+	}
+	escalado := ConfigEscaladoPorDefecto(1, 8) // entre 1 y 8 procesadores según backlog y espera
+	checkpoint := ConfigCheckpoint{
+		// Al volver a ejecutar el programa se reanuda desde este archivo
+		Ruta:         rutaCheckpoint(),
+		Intervalo:    time.Second,
+		VentanaDedup: 4096,
+	}
+	sistema := NewSistemaProcesamiento(numProcesadores, bufferSize, planificacion, escalado, checkpoint)
+	if _, err := sistema.Reanudar(); err != nil {
+		fmt.Printf("⚠️ No se pudo reanudar (%v), se empieza de cero\n", err)
+	}
+	sistema.generador.SimularReenvios(0.05) // el 5% de los eventos llega dos veces
+
+	// Ventanas en tiempo de evento: los de baja prioridad esperan más en
+	// cola, así que llegan desordenados y algunos tarde
+	porAccion, err := NewAgregadorVentanas(ConfigVentana{
+		Tipo: VentanaFija, Clave: PorAccion, Tamano: 5 * time.Second,
+		Desorden: 300 * time.Millisecond, RetrasoExtra: 200 * time.Millisecond,
+	}, func(a AgregadoVentana) {
+		fmt.Printf("🪟 %s\n", a)
+	})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	sesiones, err := NewAgregadorVentanas(ConfigVentana{
+		Tipo: VentanaSesion, Clave: PorUsuario, Inactividad: 2 * time.Second,
+		Desorden: 300 * time.Millisecond, RetrasoExtra: 200 * time.Millisecond,
+	}, func(a AgregadoVentana) {
+		if a.Eventos > 1 {
+			fmt.Printf("🧑 Sesión de usuario %s\n", a)
+		}
+	})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	sistema.agregador.AgregarVentanas(porAccion, sesiones)
+	RegistrarHandlersDemo(sistema.Handlers())
+	fmt.Printf("🧩 Handlers registrados: %s (+ respaldo)\n\n", strings.Join(sistema.Handlers().Acciones(), ", "))
 	sistema.Ejecutar(duracion, eventosPerSec)
 
 	fmt.Println("\n✅ Simulación completada exitosamente!")
@@ -472,6 +839,12 @@ func main() {
 	fmt.Println("   🎯 Context para cancelación")
 	fmt.Println("   🔒 Operaciones atómicas")
 	fmt.Println("   📡 Comunicación vía channels")
+	fmt.Println("   🎚️ Planificación por prioridad con sync.Cond")
+	fmt.Println("   🧩 Handlers por acción con timeouts y recover")
+	fmt.Println("   📐 Autoescalado del worker pool con cooldowns")
+	fmt.Println("   🚦 Contrapresión y descarte de carga")
+	fmt.Println("   💾 Agregación exactly-once con checkpoints")
+	fmt.Println("   🪟 Ventanas en tiempo de evento con watermarks")
 	fmt.Println("   ⚡ Procesamiento en tiempo real")
 
 	// Estadísticas finales de goroutines
@@ -0,0 +1,255 @@
+// ==============================================
+// PROYECTO: Sistema de Procesamiento - Agregación exactly-once
+// ==============================================
+// Deduplicación por ID de evento con una marca de agua contigua y checkpoints
+// periódicos del agregador en disco para reanudar tras un reinicio
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// ==============================================
+// MARCA DE AGUA DE IDS CONFIRMADOS
+// ==============================================
+
+// RegistroConfirmados sabe qué IDs ya se agregaron aunque los workers
+// terminen en desorden: todos los IDs hasta la marca están confirmados, y los
+// que llegaron por delante de un hueco se guardan aparte hasta que el hueco
+// se llena. El mayor ID visto no sirve como checkpoint: un evento más
+// antiguo todavía en vuelo quedaría marcado como agregado.
+type RegistroConfirmados struct {
+	marca       int64
+	adelantados map[int64]struct{}
+	limite      int
+}
+
+func NewRegistroConfirmados(limite int) *RegistroConfirmados {
+	return &RegistroConfirmados{
+		adelantados: make(map[int64]struct{}),
+		limite:      max(limite, 1),
+	}
+}
+
+// Confirmar devuelve false si el ID ya estaba confirmado. Si hay más de
+// limite IDs por delante de la marca, los huecos más antiguos se dan por
+// perdidos (eventos descartados por contrapresión, por ejemplo): si uno de
+// ellos llega después, se trata como duplicado.
+func (r *RegistroConfirmados) Confirmar(id int64) bool {
+	if id <= r.marca {
+		return false
+	}
+	if _, visto := r.adelantados[id]; visto {
+		return false
+	}
+	r.adelantados[id] = struct{}{}
+	r.avanzar()
+
+	for len(r.adelantados) > r.limite {
+		menor := int64(math.MaxInt64)
+		for pendiente := range r.adelantados {
+			menor = min(menor, pendiente)
+		}
+		delete(r.adelantados, menor)
+		r.marca = menor
+		r.avanzar()
+	}
+	return true
+}
+
+func (r *RegistroConfirmados) avanzar() {
+	for {
+		if _, ok := r.adelantados[r.marca+1]; !ok {
+			return
+		}
+		delete(r.adelantados, r.marca+1)
+		r.marca++
+	}
+}
+
+// Adelantados devuelve, ordenados, los IDs confirmados por encima de la marca
+func (r *RegistroConfirmados) Adelantados() []int64 {
+	ids := make([]int64, 0, len(r.adelantados))
+	for id := range r.adelantados {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Maximo es el mayor ID confirmado
+func (r *RegistroConfirmados) Maximo() int64 {
+	maximo := r.marca
+	for id := range r.adelantados {
+		maximo = max(maximo, id)
+	}
+	return maximo
+}
+
+// ==============================================
+// CHECKPOINTS EN DISCO
+// ==============================================
+
+type ConfigCheckpoint struct {
+	Ruta         string        // archivo JSON; vacío desactiva los checkpoints
+	Intervalo    time.Duration // cada cuánto se guarda; <= 0 usa intervaloCheckpointPorDefecto
+	VentanaDedup int           // IDs confirmados por delante de la marca que se recuerdan
+}
+
+const intervaloCheckpointPorDefecto = time.Second
+
+// versionCheckpoint cambia si el formato deja de ser compatible
+const versionCheckpoint = 2
+
+// EstadoAgregador es la foto consistente del agregador: contadores, marca de
+// agua y IDs adelantados se capturan bajo el mismo lock
+type EstadoAgregador struct {
+	Version              int              `json:"version"`
+	Guardado             time.Time        `json:"guardado"`
+	Marca                int64            `json:"marca"`       // todos los IDs <= Marca están agregados
+	Adelantados          []int64          `json:"adelantados"` // agregados por encima de la marca
+	UltimoID             int64            `json:"ultimo_id"`   // mayor ID agregado, para seguir numerando
+	ResultadosProcesados int64            `json:"resultados_procesados"`
+	Duplicados           int64            `json:"duplicados"`
+	Estadisticas         map[string]int64 `json:"estadisticas"`
+}
+
+var ErrCheckpointInvalido = errors.New("checkpoint inválido")
+
+type AlmacenCheckpoints struct {
+	ruta string
+}
+
+func NewAlmacenCheckpoints(ruta string) *AlmacenCheckpoints {
+	return &AlmacenCheckpoints{ruta: ruta}
+}
+
+// Guardar escribe a un temporal y lo renombra: un corte a mitad de escritura
+// deja el checkpoint anterior intacto
+func (a *AlmacenCheckpoints) Guardar(estado EstadoAgregador) error {
+	datos, err := json.MarshalIndent(estado, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(a.ruta), 0o755); err != nil {
+		return fmt.Errorf("creando directorio del checkpoint: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(a.ruta), filepath.Base(a.ruta)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creando checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no hace nada si el rename tuvo éxito
+
+	if _, err := tmp.Write(datos); err != nil {
+		tmp.Close()
+		return fmt.Errorf("escribiendo checkpoint: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sincronizando checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cerrando checkpoint: %w", err)
+	}
+	return os.Rename(tmp.Name(), a.ruta)
+}
+
+// Cargar devuelve el último checkpoint; ok es false si todavía no existe
+func (a *AlmacenCheckpoints) Cargar() (estado EstadoAgregador, ok bool, err error) {
+	datos, err := os.ReadFile(a.ruta)
+	if errors.Is(err, fs.ErrNotExist) {
+		return EstadoAgregador{}, false, nil
+	}
+	if err != nil {
+		return EstadoAgregador{}, false, err
+	}
+	if err := json.Unmarshal(datos, &estado); err != nil {
+		return EstadoAgregador{}, false, fmt.Errorf("%w: %v", ErrCheckpointInvalido, err)
+	}
+	if estado.Version != versionCheckpoint {
+		return EstadoAgregador{}, false, fmt.Errorf("%w: versión %d, se esperaba %d", ErrCheckpointInvalido, estado.Version, versionCheckpoint)
+	}
+	return estado, true, nil
+}
+
+func (a *AlmacenCheckpoints) Ruta() string {
+	return a.ruta
+}
+
+// ==============================================
+// ESTADO DEL AGREGADOR
+// ==============================================
+
+// Checkpoint captura el estado del agregador para guardarlo
+func (a *AgregadorResultados) Checkpoint() EstadoAgregador {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	estadisticas := make(map[string]int64, len(a.estadisticas))
+	for k, v := range a.estadisticas {
+		estadisticas[k] = v
+	}
+	return EstadoAgregador{
+		Version:              versionCheckpoint,
+		Guardado:             time.Now(),
+		Marca:                a.confirmados.marca,
+		Adelantados:          a.confirmados.Adelantados(),
+		UltimoID:             a.confirmados.Maximo(),
+		ResultadosProcesados: a.resultadosProcesados,
+		Duplicados:           a.duplicados,
+		Estadisticas:         estadisticas,
+	}
+}
+
+// Restaurar reemplaza el estado del agregador por el de un checkpoint;
+// debe llamarse antes de empezar a agregar
+func (a *AgregadorResultados) Restaurar(estado EstadoAgregador) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.resultadosProcesados = estado.ResultadosProcesados
+	a.duplicados = estado.Duplicados
+	a.estadisticas = make(map[string]int64, len(estado.Estadisticas))
+	for k, v := range estado.Estadisticas {
+		a.estadisticas[k] = v
+	}
+	a.confirmados = NewRegistroConfirmados(a.confirmados.limite)
+	a.confirmados.marca = estado.Marca
+	for _, id := range estado.Adelantados {
+		a.confirmados.Confirmar(id)
+	}
+}
+
+// GuardarCheckpoints guarda el estado cada intervalo hasta que se cancela
+// ctx; el checkpoint final lo hace quien detiene el sistema, una vez que el
+// agregador vació su canal
+func (a *AgregadorResultados) GuardarCheckpoints(ctx context.Context, almacen *AlmacenCheckpoints, intervalo time.Duration) {
+	// time.NewTicker entra en pánico con un intervalo <= 0
+	if intervalo <= 0 {
+		intervalo = intervaloCheckpointPorDefecto
+	}
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := almacen.Guardar(a.Checkpoint()); err != nil {
+				fmt.Printf("❌ Error guardando checkpoint: %v\n", err)
+			}
+		}
+	}
+}
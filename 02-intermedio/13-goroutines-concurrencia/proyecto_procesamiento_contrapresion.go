@@ -0,0 +1,404 @@
+// ==============================================
+// PROYECTO: Sistema de Procesamiento - Contrapresión y descarte de carga
+// ==============================================
+// Políticas explícitas para cuando la cola de eventos se llena: bloquear,
+// descartar el nuevo, el más antiguo o el de menor prioridad, o limitar la
+// admisión con un token bucket; con contadores de descarte y marca alta
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ==============================================
+// CONFIGURACIÓN DE LA CONTRAPRESIÓN
+// ==============================================
+
+type PoliticaContrapresion string
+
+const (
+	// El productor espera a que haya espacio (o a que se cancele su contexto)
+	ContrapresionBloquear PoliticaContrapresion = "bloquear"
+	// Se rechaza el evento que llega; es el comportamiento por defecto
+	ContrapresionDescartarNuevo PoliticaContrapresion = "descartar_nuevo"
+	// Se expulsa el evento que más lleva esperando para hacer sitio
+	ContrapresionDescartarAntiguo PoliticaContrapresion = "descartar_antiguo"
+	// Se expulsa el último evento de la prioridad más baja que la del entrante
+	ContrapresionDescartarMenorPrioridad PoliticaContrapresion = "descartar_menor_prioridad"
+	// Solo se admiten eventos con token disponible; el resto se descarta
+	ContrapresionTokenBucket PoliticaContrapresion = "token_bucket"
+)
+
+type ConfigContrapresion struct {
+	Politica PoliticaContrapresion
+
+	// CapacidadTotal limita la suma de todas las prioridades; 0 usa
+	// CapacidadCola por cada prioridad
+	CapacidadTotal int
+
+	// Token bucket: eventos por segundo sostenidos y ráfaga máxima
+	Tasa   float64
+	Rafaga int
+
+	// Marcas de ocupación (fracción de CapacidadTotal). Al superar la alta
+	// se avisa una vez, y no se vuelve a avisar hasta bajar de la baja.
+	MarcaAlta        float64
+	MarcaBaja        float64
+	AlCambiarPresion func(SenalPresion)
+}
+
+// SenalPresion describe un cruce de las marcas de ocupación
+type SenalPresion struct {
+	Alta      bool // true al superar la marca alta, false al bajar de la baja
+	Ocupacion int
+	Capacidad int
+	Momento   time.Time
+}
+
+// ==============================================
+// CONTADORES DE DESCARTE
+// ==============================================
+
+type MotivoDescarte int
+
+const (
+	DescartadoColaLlena MotivoDescarte = iota // rechazado al llegar
+	DescartadoAntiguo                         // expulsado por uno más nuevo
+	DescartadoPrioridad                       // expulsado por uno de mayor prioridad
+	DescartadoLimite                          // sin token en el token bucket
+	numMotivosDescarte
+)
+
+var nombresMotivoDescarte = [numMotivosDescarte]string{"cola_llena", "antiguo", "prioridad", "limite"}
+
+func (m MotivoDescarte) String() string {
+	if m < 0 || m >= numMotivosDescarte {
+		return "desconocido"
+	}
+	return nombresMotivoDescarte[m]
+}
+
+// Descarte identifica un evento perdido y por qué
+type Descarte struct {
+	Evento Evento
+	Motivo MotivoDescarte
+}
+
+type ResumenDescartes struct {
+	Total        int64
+	PorMotivo    [numMotivosDescarte]int64
+	PorPrioridad [NumPrioridades]int64
+}
+
+func (r ResumenDescartes) String() string {
+	partes := make([]string, 0, numMotivosDescarte)
+	for motivo, n := range r.PorMotivo {
+		if n > 0 {
+			partes = append(partes, fmt.Sprintf("%s: %d", MotivoDescarte(motivo), n))
+		}
+	}
+	if len(partes) == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d (%s)", r.Total, strings.Join(partes, ", "))
+}
+
+// ==============================================
+// ADMISIÓN EN LA COLA
+// ==============================================
+
+// Encolar agrega el evento aplicando la política de contrapresión.
+// admitido indica si el evento entró; descarte, si no es nil, es el evento
+// perdido: el propio entrante o el expulsado para hacerle sitio. Un evento
+// rechazado por cierre de la cola o cancelación de ctx no cuenta como descarte.
+func (c *ColaEventos) Encolar(ctx context.Context, evento Evento) (admitido bool, descarte *Descarte) {
+	var senal *SenalPresion
+	defer func() {
+		// El aviso se hace fuera del lock para que pueda consultar la cola
+		if senal != nil {
+			c.config.Contrapresion.AlCambiarPresion(*senal)
+		}
+	}()
+
+	if c.config.Contrapresion.Politica == ContrapresionBloquear {
+		stop := context.AfterFunc(ctx, func() {
+			c.mu.Lock()
+			c.espacio.Broadcast()
+			c.mu.Unlock()
+		})
+		defer stop()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := indicePrioridad(evento.Priority)
+	if c.cerrada || ctx.Err() != nil {
+		return false, nil
+	}
+
+	ahora := time.Now()
+	switch c.config.Contrapresion.Politica {
+	case ContrapresionTokenBucket:
+		if !c.tomarToken(ahora) {
+			return false, c.descartar(evento, DescartadoLimite)
+		}
+		if c.llena(i) {
+			return false, c.descartar(evento, DescartadoColaLlena)
+		}
+
+	case ContrapresionBloquear:
+		for c.llena(i) {
+			c.espacio.Wait()
+			if c.cerrada || ctx.Err() != nil {
+				return false, nil
+			}
+		}
+
+	case ContrapresionDescartarAntiguo:
+		if c.llena(i) {
+			victima := c.colaMasAntigua(i)
+			descarte = c.expulsar(victima, 0, DescartadoAntiguo)
+		}
+
+	case ContrapresionDescartarMenorPrioridad:
+		if c.llena(i) {
+			// Solo se puede hacer sitio si la cola propia no está llena y hay
+			// eventos de prioridad más baja; si no, se pierde el entrante
+			victima := c.colaMenorPrioridad(i)
+			if victima < 0 {
+				return false, c.descartar(evento, DescartadoColaLlena)
+			}
+			descarte = c.expulsar(victima, len(c.colas[victima])-1, DescartadoPrioridad)
+		}
+
+	default:
+		if c.llena(i) {
+			return false, c.descartar(evento, DescartadoColaLlena)
+		}
+	}
+
+	c.colas[i] = append(c.colas[i], eventoEnCola{evento: evento, encolado: ahora})
+	c.cond.Signal()
+	senal = c.revisarPresion(ahora)
+	return true, descarte
+}
+
+// llena indica si un evento de la prioridad i ya no cabe
+func (c *ColaEventos) llena(i int) bool {
+	if c.config.CapacidadCola > 0 && len(c.colas[i]) >= c.config.CapacidadCola {
+		return true
+	}
+	capacidad := c.capacidadTotal()
+	return capacidad > 0 && c.ocupacion() >= capacidad
+}
+
+func (c *ColaEventos) capacidadTotal() int {
+	if c.config.Contrapresion.CapacidadTotal > 0 {
+		return c.config.Contrapresion.CapacidadTotal
+	}
+	return c.config.CapacidadCola * NumPrioridades
+}
+
+func (c *ColaEventos) ocupacion() int {
+	total := 0
+	for i := range c.colas {
+		total += len(c.colas[i])
+	}
+	return total
+}
+
+// colaMasAntigua elige de dónde expulsar el evento más antiguo: la cola
+// propia si es ella la llena, o la que tenga el primer evento más viejo
+func (c *ColaEventos) colaMasAntigua(i int) int {
+	if c.config.CapacidadCola > 0 && len(c.colas[i]) >= c.config.CapacidadCola {
+		return i
+	}
+	victima := i
+	for j := range c.colas {
+		if len(c.colas[j]) == 0 {
+			continue
+		}
+		if len(c.colas[victima]) == 0 || c.colas[j][0].encolado.Before(c.colas[victima][0].encolado) {
+			victima = j
+		}
+	}
+	return victima
+}
+
+// colaMenorPrioridad devuelve la cola no vacía de prioridad más baja que i,
+// o -1 si no hay a quién expulsar
+func (c *ColaEventos) colaMenorPrioridad(i int) int {
+	if c.config.CapacidadCola > 0 && len(c.colas[i]) >= c.config.CapacidadCola {
+		return -1
+	}
+	for j := NumPrioridades - 1; j > i; j-- {
+		if len(c.colas[j]) > 0 {
+			return j
+		}
+	}
+	return -1
+}
+
+func (c *ColaEventos) expulsar(cola, posicion int, motivo MotivoDescarte) *Descarte {
+	expulsado := c.colas[cola][posicion].evento
+	c.colas[cola] = append(c.colas[cola][:posicion], c.colas[cola][posicion+1:]...)
+	return c.descartar(expulsado, motivo)
+}
+
+func (c *ColaEventos) descartar(evento Evento, motivo MotivoDescarte) *Descarte {
+	c.descartes[motivo][indicePrioridad(evento.Priority)]++
+	return &Descarte{Evento: evento, Motivo: motivo}
+}
+
+// tomarToken recarga el bucket según el tiempo transcurrido y consume un token
+func (c *ColaEventos) tomarToken(ahora time.Time) bool {
+	cfg := c.config.Contrapresion
+	if cfg.Tasa <= 0 {
+		return true
+	}
+	rafaga := float64(max(cfg.Rafaga, 1))
+	if c.ultimaRecarga.IsZero() {
+		c.tokens = rafaga
+	} else {
+		c.tokens = math.Min(rafaga, c.tokens+ahora.Sub(c.ultimaRecarga).Seconds()*cfg.Tasa)
+	}
+	c.ultimaRecarga = ahora
+	if c.tokens < 1 {
+		return false
+	}
+	c.tokens--
+	return true
+}
+
+// revisarPresion aplica la histéresis de las marcas y devuelve la señal a
+// emitir, si hubo cruce; se llama con el lock tomado
+func (c *ColaEventos) revisarPresion(ahora time.Time) *SenalPresion {
+	cfg := c.config.Contrapresion
+	capacidad := c.capacidadTotal()
+	if cfg.MarcaAlta <= 0 || capacidad <= 0 {
+		return nil
+	}
+	ocupacion := c.ocupacion()
+	fraccion := float64(ocupacion) / float64(capacidad)
+
+	switch {
+	case !c.enPresion && fraccion >= cfg.MarcaAlta:
+		c.enPresion = true
+	case c.enPresion && fraccion <= cfg.MarcaBaja:
+		c.enPresion = false
+	default:
+		return nil
+	}
+	if cfg.AlCambiarPresion == nil {
+		return nil
+	}
+	return &SenalPresion{Alta: c.enPresion, Ocupacion: ocupacion, Capacidad: capacidad, Momento: ahora}
+}
+
+// EnPresion indica si la ocupación está por encima de la marca alta
+func (c *ColaEventos) EnPresion() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enPresion
+}
+
+func (c *ColaEventos) PoliticaContrapresion() PoliticaContrapresion {
+	if c.config.Contrapresion.Politica == "" {
+		return ContrapresionDescartarNuevo
+	}
+	return c.config.Contrapresion.Politica
+}
+
+func (c *ColaEventos) Descartes() ResumenDescartes {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var resumen ResumenDescartes
+	for motivo := range c.descartes {
+		for i, n := range c.descartes[motivo] {
+			resumen.Total += n
+			resumen.PorMotivo[motivo] += n
+			resumen.PorPrioridad[i] += n
+		}
+	}
+	return resumen
+}
+
+// ==============================================
+// DEMOSTRACIÓN: POLÍTICAS BAJO SOBRECARGA
+// ==============================================
+
+// demoContrapresion produce un evento por milisegundo, de prioridad
+// rotativa, contra un consumidor que tarda 4ms por evento y una cola de 10
+func demoContrapresion() {
+	fmt.Println("🚦 DEMO: Contrapresión y Descarte de Carga")
+	fmt.Println("==========================================")
+
+	politicas := []PoliticaContrapresion{
+		ContrapresionBloquear,
+		ContrapresionDescartarNuevo,
+		ContrapresionDescartarAntiguo,
+		ContrapresionDescartarMenorPrioridad,
+		ContrapresionTokenBucket,
+	}
+
+	for _, politica := range politicas {
+		config := ConfigPlanificadorPorDefecto(0)
+		config.Contrapresion = ConfigContrapresion{
+			Politica:       politica,
+			CapacidadTotal: 10,
+			Tasa:           300,
+			Rafaga:         5,
+			MarcaAlta:      0.8,
+			MarcaBaja:      0.3,
+		}
+		// El aviso llega desde el productor o el consumidor
+		var avisos atomic.Int32
+		config.Contrapresion.AlCambiarPresion = func(s SenalPresion) {
+			if s.Alta {
+				avisos.Add(1)
+			}
+		}
+		cola := NewColaEventos(config)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		consumidorTerminado := make(chan [NumPrioridades]int)
+		go func() {
+			var atendidos [NumPrioridades]int
+			for {
+				evento, _, ok := cola.Desencolar(ctx)
+				if !ok {
+					consumidorTerminado <- atendidos
+					return
+				}
+				atendidos[indicePrioridad(evento.Priority)]++
+				time.Sleep(4 * time.Millisecond)
+			}
+		}()
+
+		enviados := 0
+		ticker := time.NewTicker(time.Millisecond)
+		for ctx.Err() == nil {
+			<-ticker.C
+			// Con bloquear, el productor se frena y envía menos
+			cola.Encolar(ctx, Evento{ID: int64(enviados), Priority: enviados%NumPrioridades + 1})
+			if ctx.Err() == nil {
+				enviados++
+			}
+		}
+		ticker.Stop()
+		cancel()
+		atendidos := <-consumidorTerminado
+
+		descartes := cola.Descartes()
+		fmt.Printf("   %-26s enviados %3d | atendidos alta/media/baja %2d/%2d/%2d | descartados %s | avisos de marca alta %d\n",
+			politica, enviados, atendidos[0], atendidos[1], atendidos[2], descartes, avisos.Load())
+	}
+	fmt.Println()
+}
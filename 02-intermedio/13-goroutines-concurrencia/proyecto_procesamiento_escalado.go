@@ -0,0 +1,274 @@
+// ==============================================
+// PROYECTO: Sistema de Procesamiento - Autoescalado de procesadores
+// ==============================================
+// Pool dinámico de ProcesadorEventos y un controlador que lo ajusta según
+// el backlog de la cola y la espera de los eventos, con cooldowns
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// POOL DE PROCESADORES
+// ==============================================
+
+// PoolProcesadores lanza y retira procesadores mientras el sistema corre.
+// Conserva también los retirados para que sus estadísticas sigan contando.
+type PoolProcesadores struct {
+	cola      *ColaEventos
+	salida    chan<- EventoProcesado
+	latencias *LatenciasPorPrioridad
+	handlers  *RegistroHandlers
+
+	ctx          context.Context
+	wg           sync.WaitGroup
+	procesadores []*ProcesadorEventos
+	siguienteID  int
+	mu           sync.Mutex
+}
+
+func NewPoolProcesadores(cola *ColaEventos, salida chan<- EventoProcesado, latencias *LatenciasPorPrioridad, handlers *RegistroHandlers) *PoolProcesadores {
+	return &PoolProcesadores{
+		cola:      cola,
+		salida:    salida,
+		latencias: latencias,
+		handlers:  handlers,
+	}
+}
+
+// Iniciar fija el contexto de los procesadores y lanza los iniciales
+func (pp *PoolProcesadores) Iniciar(ctx context.Context, cantidad int) {
+	pp.mu.Lock()
+	pp.ctx = ctx
+	pp.mu.Unlock()
+	for i := 0; i < cantidad; i++ {
+		pp.Agregar()
+	}
+}
+
+func (pp *PoolProcesadores) Agregar() {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	pp.siguienteID++
+	procesador := NewProcesadorEventos(pp.siguienteID)
+	procesador.latencias = pp.latencias
+	procesador.handlers = pp.handlers
+	// Retirar cancela solo la espera en la cola: el evento en curso termina
+	procesador.retiro, procesador.retirar = context.WithCancel(pp.ctx)
+	pp.procesadores = append(pp.procesadores, procesador)
+
+	pp.wg.Add(1)
+	go procesador.ProcesarEventos(pp.ctx, pp.cola, pp.salida, &pp.wg)
+}
+
+// Retirar detiene el procesador activo más reciente
+func (pp *PoolProcesadores) Retirar() bool {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	for i := len(pp.procesadores) - 1; i >= 0; i-- {
+		if procesador := pp.procesadores[i]; procesador.Activo() {
+			procesador.marcarRetirado()
+			procesador.retirar()
+			return true
+		}
+	}
+	return false
+}
+
+func (pp *PoolProcesadores) Activos() int {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	activos := 0
+	for _, procesador := range pp.procesadores {
+		if procesador.Activo() {
+			activos++
+		}
+	}
+	return activos
+}
+
+// Todos devuelve los procesadores lanzados, activos o retirados
+func (pp *PoolProcesadores) Todos() []*ProcesadorEventos {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	return append([]*ProcesadorEventos(nil), pp.procesadores...)
+}
+
+func (pp *PoolProcesadores) Esperar() {
+	pp.wg.Wait()
+}
+
+// ==============================================
+// CONTROLADOR DE ESCALADO
+// ==============================================
+
+type ConfigEscalado struct {
+	Min, Max             int
+	Intervalo            time.Duration // cada cuánto se evalúa; <= 0 usa intervaloEscaladoPorDefecto
+	CooldownSubida       time.Duration // mínimo entre dos subidas
+	CooldownBajada       time.Duration // mínimo desde el último cambio para bajar
+	BacklogPorProcesador int           // eventos en cola que puede absorber un procesador
+	EsperaObjetivo       time.Duration // espera media en cola aceptable
+}
+
+const intervaloEscaladoPorDefecto = 250 * time.Millisecond
+
+func ConfigEscaladoPorDefecto(min, max int) ConfigEscalado {
+	return ConfigEscalado{
+		Min:                  min,
+		Max:                  max,
+		Intervalo:            intervaloEscaladoPorDefecto,
+		CooldownSubida:       time.Second,
+		CooldownBajada:       3 * time.Second,
+		BacklogPorProcesador: 20,
+		EsperaObjetivo:       200 * time.Millisecond,
+	}
+}
+
+type DecisionEscalado struct {
+	Momento     time.Time
+	Desde       int
+	Hasta       int
+	Backlog     int
+	EsperaMedia time.Duration
+	Motivo      string
+}
+
+// maxDecisiones limita el historial que se muestra en el monitor
+const maxDecisiones = 20
+
+type ControladorEscalado struct {
+	config ConfigEscalado
+	pool   *PoolProcesadores
+	cola   *ColaEventos
+
+	ultimaSubida       time.Time
+	ultimoCambio       time.Time
+	desencoladosPrevio int64
+	esperaPrevia       time.Duration
+	decisiones         []DecisionEscalado
+	mu                 sync.Mutex
+}
+
+func NewControladorEscalado(config ConfigEscalado, pool *PoolProcesadores, cola *ColaEventos) *ControladorEscalado {
+	config.Min = max(config.Min, 1)
+	config.Max = max(config.Max, config.Min)
+	if config.BacklogPorProcesador <= 0 {
+		config.BacklogPorProcesador = 1
+	}
+	// Ejecutar usa Intervalo en time.NewTicker, que entra en pánico con <= 0
+	if config.Intervalo <= 0 {
+		config.Intervalo = intervaloEscaladoPorDefecto
+	}
+	return &ControladorEscalado{config: config, pool: pool, cola: cola}
+}
+
+func (c *ControladorEscalado) Ejecutar(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Intervalo)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ahora := <-ticker.C:
+			c.evaluar(ahora)
+		}
+	}
+}
+
+// evaluar mide el backlog y la espera media de los eventos atendidos desde
+// la evaluación anterior y aplica la decisión
+func (c *ControladorEscalado) evaluar(ahora time.Time) {
+	longitudes := c.cola.Longitudes()
+	backlog := 0
+	for _, n := range longitudes {
+		backlog += n
+	}
+	desencolados, esperaTotal := c.cola.EsperaAcumulada()
+
+	c.mu.Lock()
+	var esperaMedia time.Duration
+	if n := desencolados - c.desencoladosPrevio; n > 0 {
+		esperaMedia = (esperaTotal - c.esperaPrevia) / time.Duration(n)
+	}
+	c.desencoladosPrevio, c.esperaPrevia = desencolados, esperaTotal
+	c.mu.Unlock()
+
+	activos := c.pool.Activos()
+	objetivo, motivo := c.decidir(backlog, esperaMedia, activos, ahora)
+	if objetivo == activos {
+		return
+	}
+
+	for i := activos; i < objetivo; i++ {
+		c.pool.Agregar()
+	}
+	for i := objetivo; i < activos; i++ {
+		c.pool.Retirar()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if objetivo > activos {
+		c.ultimaSubida = ahora
+	}
+	c.ultimoCambio = ahora
+	c.decisiones = append(c.decisiones, DecisionEscalado{
+		Momento: ahora, Desde: activos, Hasta: objetivo, Backlog: backlog, EsperaMedia: esperaMedia, Motivo: motivo,
+	})
+	if len(c.decisiones) > maxDecisiones {
+		c.decisiones = c.decisiones[len(c.decisiones)-maxDecisiones:]
+	}
+}
+
+// decidir calcula cuántos procesadores hacen falta. Sube de golpe hasta lo
+// que pide el backlog y baja de a uno, con una banda muerta entre ambos
+// umbrales y cooldowns para no oscilar.
+func (c *ControladorEscalado) decidir(backlog int, esperaMedia time.Duration, activos int, ahora time.Time) (int, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cfg := c.config
+
+	if activos < cfg.Min {
+		return cfg.Min, "por debajo del mínimo"
+	}
+	if activos > cfg.Max {
+		return cfg.Max, "por encima del máximo"
+	}
+
+	capacidad := activos * cfg.BacklogPorProcesador
+	saturado := backlog > capacidad || (cfg.EsperaObjetivo > 0 && esperaMedia > cfg.EsperaObjetivo)
+	if saturado && activos < cfg.Max && ahora.Sub(c.ultimaSubida) >= cfg.CooldownSubida {
+		necesarios := int(math.Ceil(float64(backlog) / float64(cfg.BacklogPorProcesador)))
+		objetivo := min(max(necesarios, activos+1), cfg.Max)
+		if backlog > capacidad {
+			return objetivo, fmt.Sprintf("backlog %d > %d", backlog, capacidad)
+		}
+		return objetivo, fmt.Sprintf("espera media %v > %v", esperaMedia.Round(time.Millisecond), cfg.EsperaObjetivo)
+	}
+
+	ocioso := backlog <= capacidad/4 && esperaMedia <= cfg.EsperaObjetivo/2
+	if ocioso && activos > cfg.Min && ahora.Sub(c.ultimoCambio) >= cfg.CooldownBajada {
+		return activos - 1, fmt.Sprintf("backlog %d y espera %v bajos", backlog, esperaMedia.Round(time.Millisecond))
+	}
+	return activos, ""
+}
+
+// Decisiones devuelve las últimas decisiones de escalado
+func (c *ControladorEscalado) Decisiones() []DecisionEscalado {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]DecisionEscalado(nil), c.decisiones...)
+}
+
+func (c *ControladorEscalado) Limites() (int, int) {
+	return c.config.Min, c.config.Max
+}
@@ -0,0 +1,221 @@
+// ==============================================
+// PROYECTO: Sistema de Procesamiento - Handlers por acción
+// ==============================================
+// Registro de handlers indexado por Evento.Action, con timeout por acción
+// vía context, recuperación de panics y handler de respaldo
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// CONTRATO DE LOS HANDLERS
+// ==============================================
+
+var (
+	ErrAccionDesconocida = errors.New("acción sin handler")
+	ErrTimeoutHandler    = errors.New("handler excedió su timeout")
+	ErrPanicHandler      = errors.New("panic en handler")
+)
+
+// ResultadoHandler es la salida tipada de un handler
+type ResultadoHandler struct {
+	Tipo    string                 `json:"tipo"` // categoría del resultado: "compra", "sesion"...
+	Mensaje string                 `json:"mensaje"`
+	Datos   map[string]interface{} `json:"datos,omitempty"`
+}
+
+// HandlerEvento procesa un tipo de evento; debe respetar la cancelación de ctx
+type HandlerEvento interface {
+	Manejar(ctx context.Context, evento Evento) (ResultadoHandler, error)
+}
+
+// HandlerFunc permite usar una función como HandlerEvento
+type HandlerFunc func(ctx context.Context, evento Evento) (ResultadoHandler, error)
+
+func (f HandlerFunc) Manejar(ctx context.Context, evento Evento) (ResultadoHandler, error) {
+	return f(ctx, evento)
+}
+
+// ==============================================
+// REGISTRO DE HANDLERS
+// ==============================================
+
+type entradaHandler struct {
+	handler HandlerEvento
+	timeout time.Duration
+}
+
+type RegistroHandlers struct {
+	handlers          map[string]entradaHandler
+	fallback          *entradaHandler
+	timeoutPorDefecto time.Duration
+	mu                sync.RWMutex
+}
+
+func NewRegistroHandlers(timeoutPorDefecto time.Duration) *RegistroHandlers {
+	return &RegistroHandlers{
+		handlers:          make(map[string]entradaHandler),
+		timeoutPorDefecto: timeoutPorDefecto,
+	}
+}
+
+// Registrar asocia un handler a una acción; timeout 0 usa el del registro
+func (r *RegistroHandlers) Registrar(action string, handler HandlerEvento, timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[action] = entradaHandler{handler: handler, timeout: timeout}
+}
+
+// RegistrarFallback define el handler de las acciones sin handler propio
+func (r *RegistroHandlers) RegistrarFallback(handler HandlerEvento, timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = &entradaHandler{handler: handler, timeout: timeout}
+}
+
+func (r *RegistroHandlers) Acciones() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	acciones := make([]string, 0, len(r.handlers))
+	for action := range r.handlers {
+		acciones = append(acciones, action)
+	}
+	sort.Strings(acciones)
+	return acciones
+}
+
+// Ejecutar busca el handler de la acción y lo corre con su timeout. Un panic
+// se convierte en error; si el handler ignora ctx, al vencer el timeout se
+// abandona su resultado.
+func (r *RegistroHandlers) Ejecutar(ctx context.Context, evento Evento) (ResultadoHandler, error) {
+	r.mu.RLock()
+	entrada, existe := r.handlers[evento.Action]
+	if !existe && r.fallback != nil {
+		entrada, existe = *r.fallback, true
+	}
+	timeout := r.timeoutPorDefecto
+	r.mu.RUnlock()
+
+	if !existe {
+		return ResultadoHandler{}, fmt.Errorf("%w: %q", ErrAccionDesconocida, evento.Action)
+	}
+	if entrada.timeout > 0 {
+		timeout = entrada.timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type salida struct {
+		resultado ResultadoHandler
+		err       error
+	}
+	terminado := make(chan salida, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				terminado <- salida{err: fmt.Errorf("%w: %s: %v", ErrPanicHandler, evento.Action, p)}
+			}
+		}()
+		resultado, err := entrada.handler.Manejar(ctx, evento)
+		terminado <- salida{resultado, err}
+	}()
+
+	select {
+	case s := <-terminado:
+		if s.err != nil && errors.Is(s.err, context.DeadlineExceeded) {
+			s.err = fmt.Errorf("%w: %s tras %v", ErrTimeoutHandler, evento.Action, timeout)
+		}
+		return s.resultado, s.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ResultadoHandler{}, fmt.Errorf("%w: %s tras %v", ErrTimeoutHandler, evento.Action, timeout)
+		}
+		return ResultadoHandler{}, ctx.Err()
+	}
+}
+
+// ==============================================
+// HANDLERS DE DEMOSTRACIÓN
+// ==============================================
+
+// trabajar simula trabajo que se interrumpe si se cancela ctx
+func trabajar(ctx context.Context, duracion time.Duration) error {
+	timer := time.NewTimer(duracion)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func numeroDeDatos(evento Evento) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(evento.Data, "data_"))
+	return n
+}
+
+// RegistrarHandlersDemo da de alta handlers que imitan cargas reales: un
+// pago que puede rechazarse, una búsqueda lenta con timeout, un handler de
+// analítica con un bug que provoca panics, y un respaldo genérico
+func RegistrarHandlersDemo(r *RegistroHandlers) {
+	r.Registrar("purchase", HandlerFunc(func(ctx context.Context, evento Evento) (ResultadoHandler, error) {
+		if err := trabajar(ctx, time.Duration(rand.Intn(50)+20)*time.Millisecond); err != nil {
+			return ResultadoHandler{}, err
+		}
+		importe := numeroDeDatos(evento)
+		if importe%10 == 0 {
+			return ResultadoHandler{}, fmt.Errorf("pago rechazado para usuario %d", evento.UserID)
+		}
+		return ResultadoHandler{
+			Tipo:    "compra",
+			Mensaje: fmt.Sprintf("Compra de %d€ para usuario %d", importe, evento.UserID),
+			Datos:   map[string]interface{}{"importe": importe},
+		}, nil
+	}), 200*time.Millisecond)
+
+	r.Registrar("search", HandlerFunc(func(ctx context.Context, evento Evento) (ResultadoHandler, error) {
+		if err := trabajar(ctx, time.Duration(rand.Intn(100)+20)*time.Millisecond); err != nil {
+			return ResultadoHandler{}, err
+		}
+		return ResultadoHandler{Tipo: "busqueda", Mensaje: fmt.Sprintf("Búsqueda %q", evento.Data)}, nil
+	}), 100*time.Millisecond)
+
+	sesion := HandlerFunc(func(ctx context.Context, evento Evento) (ResultadoHandler, error) {
+		if err := trabajar(ctx, time.Duration(rand.Intn(20)+10)*time.Millisecond); err != nil {
+			return ResultadoHandler{}, err
+		}
+		return ResultadoHandler{Tipo: "sesion", Mensaje: fmt.Sprintf("%s de usuario %d", evento.Action, evento.UserID)}, nil
+	})
+	r.Registrar("login", sesion, 0)
+	r.Registrar("logout", sesion, 0)
+
+	analitica := HandlerFunc(func(ctx context.Context, evento Evento) (ResultadoHandler, error) {
+		var contadores map[string]int
+		if numeroDeDatos(evento)%25 != 0 {
+			contadores = map[string]int{}
+		}
+		contadores[evento.Action]++ // con un mapa nil provoca panic
+		return ResultadoHandler{Tipo: "analitica", Mensaje: fmt.Sprintf("%s registrado", evento.Action)}, trabajar(ctx, 5*time.Millisecond)
+	})
+	r.Registrar("click", analitica, 0)
+	r.Registrar("view", analitica, 0)
+
+	r.RegistrarFallback(HandlerFunc(func(ctx context.Context, evento Evento) (ResultadoHandler, error) {
+		return ResultadoHandler{Tipo: "generico", Mensaje: fmt.Sprintf("Acción %s sin handler específico", evento.Action)}, nil
+	}), 0)
+}
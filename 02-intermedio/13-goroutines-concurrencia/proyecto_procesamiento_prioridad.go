@@ -0,0 +1,340 @@
+// ==============================================
+// PROYECTO: Sistema de Procesamiento - Planificación por prioridad
+// ==============================================
+// Una cola por prioridad con round-robin ponderado o prioridad estricta
+// con envejecimiento, y percentiles de latencia por prioridad
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// CONFIGURACIÓN DE LA PLANIFICACIÓN
+// ==============================================
+
+const NumPrioridades = 3 // 1=alta, 2=media, 3=baja
+
+type PoliticaPlanificacion string
+
+const (
+	// Cada prioridad recibe una fracción de los turnos proporcional a su peso
+	PlanificacionPonderada PoliticaPlanificacion = "ponderada"
+	// Siempre gana la prioridad más alta; el envejecimiento evita la inanición
+	PlanificacionEstricta PoliticaPlanificacion = "estricta"
+)
+
+type ConfigPlanificador struct {
+	Politica       PoliticaPlanificacion
+	Pesos          [NumPrioridades]int // peso de alta, media y baja (ponderada)
+	Envejecimiento time.Duration       // estricta: cada tramo de espera sube un nivel
+	CapacidadCola  int                 // eventos máximos por cola de prioridad
+	Contrapresion  ConfigContrapresion // qué hacer cuando la cola se llena
+}
+
+func ConfigPlanificadorPorDefecto(capacidadCola int) ConfigPlanificador {
+	return ConfigPlanificador{
+		Politica:       PlanificacionPonderada,
+		Pesos:          [NumPrioridades]int{6, 3, 1},
+		Envejecimiento: 500 * time.Millisecond,
+		CapacidadCola:  capacidadCola,
+		Contrapresion:  ConfigContrapresion{Politica: ContrapresionDescartarNuevo},
+	}
+}
+
+// indicePrioridad convierte la prioridad del evento en índice de cola; los
+// valores fuera de rango se tratan como baja prioridad
+func indicePrioridad(prioridad int) int {
+	if prioridad < 1 || prioridad > NumPrioridades {
+		return NumPrioridades - 1
+	}
+	return prioridad - 1
+}
+
+// ==============================================
+// COLA DE EVENTOS POR PRIORIDAD
+// ==============================================
+
+type eventoEnCola struct {
+	evento   Evento
+	encolado time.Time
+}
+
+type ColaEventos struct {
+	config  ConfigPlanificador
+	colas   [NumPrioridades][]eventoEnCola
+	credito [NumPrioridades]int // estado del round-robin ponderado suave
+	cerrada bool
+
+	// Acumulados para que el autoescalado calcule la espera media
+	desencolados int64
+	esperaTotal  time.Duration
+
+	// Contrapresión: token bucket, descartes por motivo y prioridad, y
+	// estado de la marca alta
+	tokens        float64
+	ultimaRecarga time.Time
+	descartes     [numMotivosDescarte][NumPrioridades]int64
+	enPresion     bool
+
+	mu      sync.Mutex
+	cond    *sync.Cond // hay eventos para desencolar
+	espacio *sync.Cond // hay sitio para los productores bloqueados
+}
+
+func NewColaEventos(config ConfigPlanificador) *ColaEventos {
+	for i, peso := range config.Pesos {
+		if peso <= 0 {
+			config.Pesos[i] = 1
+		}
+	}
+	c := &ColaEventos{config: config}
+	c.cond = sync.NewCond(&c.mu)
+	c.espacio = sync.NewCond(&c.mu)
+	return c
+}
+
+// Desencolar bloquea hasta que haya un evento según la política. Devuelve
+// también cuánto esperó en cola; ok es false si se canceló ctx o la cola
+// se cerró y quedó vacía.
+func (c *ColaEventos) Desencolar(ctx context.Context) (evento Evento, espera time.Duration, ok bool) {
+	// sync.Cond no conoce context: la cancelación despierta a los que esperan
+	stop := context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	})
+	defer stop()
+
+	var senal *SenalPresion
+	defer func() {
+		if senal != nil {
+			c.config.Contrapresion.AlCambiarPresion(*senal)
+		}
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		if ctx.Err() != nil {
+			return Evento{}, 0, false
+		}
+		if i := c.elegir(time.Now()); i >= 0 {
+			item := c.colas[i][0]
+			c.colas[i][0] = eventoEnCola{}
+			c.colas[i] = c.colas[i][1:]
+			espera := time.Since(item.encolado)
+			c.desencolados++
+			c.esperaTotal += espera
+			// Los productores esperan espacio en colas distintas: Signal podría
+			// despertar a uno cuya cola sigue llena y perder el aviso
+			c.espacio.Broadcast()
+			senal = c.revisarPresion(time.Now())
+			return item.evento, espera, true
+		}
+		if c.cerrada {
+			return Evento{}, 0, false
+		}
+		c.cond.Wait()
+	}
+}
+
+// elegir devuelve el índice de la cola a atender, o -1 si todas están vacías
+func (c *ColaEventos) elegir(ahora time.Time) int {
+	mejor := -1
+
+	if c.config.Politica == PlanificacionEstricta {
+		// Nivel efectivo = prioridad - tramos de envejecimiento que lleva
+		// esperando el primero de la cola; a igual nivel gana la más alta
+		mejorNivel := 0
+		for i := range c.colas {
+			if len(c.colas[i]) == 0 {
+				continue
+			}
+			nivel := i
+			if c.config.Envejecimiento > 0 {
+				nivel -= int(ahora.Sub(c.colas[i][0].encolado) / c.config.Envejecimiento)
+			}
+			if mejor < 0 || nivel < mejorNivel {
+				mejor, mejorNivel = i, nivel
+			}
+		}
+		return mejor
+	}
+
+	// Round-robin ponderado suave: cada cola no vacía suma su peso y la de
+	// mayor crédito paga el total; reparte los turnos sin ráfagas
+	total := 0
+	for i := range c.colas {
+		if len(c.colas[i]) == 0 {
+			c.credito[i] = 0
+			continue
+		}
+		c.credito[i] += c.config.Pesos[i]
+		total += c.config.Pesos[i]
+		if mejor < 0 || c.credito[i] > c.credito[mejor] {
+			mejor = i
+		}
+	}
+	if mejor >= 0 {
+		c.credito[mejor] -= total
+	}
+	return mejor
+}
+
+// Cerrar rechaza nuevos eventos; los pendientes se pueden seguir desencolando
+func (c *ColaEventos) Cerrar() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cerrada = true
+	c.cond.Broadcast()
+	c.espacio.Broadcast()
+}
+
+// Longitudes devuelve los eventos pendientes de cada prioridad
+func (c *ColaEventos) Longitudes() [NumPrioridades]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var longitudes [NumPrioridades]int
+	for i := range c.colas {
+		longitudes[i] = len(c.colas[i])
+	}
+	return longitudes
+}
+
+// EsperaAcumulada devuelve los eventos desencolados desde el inicio y la
+// suma de sus esperas en cola
+func (c *ColaEventos) EsperaAcumulada() (int64, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.desencolados, c.esperaTotal
+}
+
+func (c *ColaEventos) Politica() PoliticaPlanificacion {
+	return c.config.Politica
+}
+
+// ==============================================
+// LATENCIA POR PRIORIDAD
+// ==============================================
+
+// muestrasLatencia limita la memoria: los percentiles se calculan sobre las
+// últimas muestras de cada prioridad
+const muestrasLatencia = 2048
+
+type ResumenLatencia struct {
+	Eventos       int64
+	P50, P95, P99 time.Duration
+	Max           time.Duration
+}
+
+type LatenciasPorPrioridad struct {
+	muestras  [NumPrioridades][]time.Duration
+	siguiente [NumPrioridades]int
+	eventos   [NumPrioridades]int64
+	mu        sync.Mutex
+}
+
+func NewLatenciasPorPrioridad() *LatenciasPorPrioridad {
+	return &LatenciasPorPrioridad{}
+}
+
+// Registrar guarda la latencia de extremo a extremo (espera + proceso)
+func (l *LatenciasPorPrioridad) Registrar(prioridad int, latencia time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i := indicePrioridad(prioridad)
+	l.eventos[i]++
+	if len(l.muestras[i]) < muestrasLatencia {
+		l.muestras[i] = append(l.muestras[i], latencia)
+		return
+	}
+	l.muestras[i][l.siguiente[i]] = latencia
+	l.siguiente[i] = (l.siguiente[i] + 1) % muestrasLatencia
+}
+
+func (l *LatenciasPorPrioridad) Resumen(prioridad int) ResumenLatencia {
+	l.mu.Lock()
+	i := indicePrioridad(prioridad)
+	ordenadas := append([]time.Duration(nil), l.muestras[i]...)
+	resumen := ResumenLatencia{Eventos: l.eventos[i]}
+	l.mu.Unlock()
+
+	if len(ordenadas) == 0 {
+		return resumen
+	}
+	sort.Slice(ordenadas, func(a, b int) bool { return ordenadas[a] < ordenadas[b] })
+	percentil := func(p float64) time.Duration {
+		// Método nearest-rank
+		rango := int(math.Ceil(p*float64(len(ordenadas)))) - 1
+		return ordenadas[max(rango, 0)]
+	}
+	resumen.P50, resumen.P95, resumen.P99 = percentil(0.50), percentil(0.95), percentil(0.99)
+	resumen.Max = ordenadas[len(ordenadas)-1]
+	return resumen
+}
+
+// ==============================================
+// DEMOSTRACIÓN: INANICIÓN VS PLANIFICACIÓN
+// ==============================================
+
+// demoPlanificacion satura un consumidor con eventos de alta prioridad
+// mientras hay eventos de baja esperando, y cuenta cuántos de baja se
+// atienden con cada política
+func demoPlanificacion() {
+	fmt.Println("🎚️ DEMO: Planificación por Prioridad")
+	fmt.Println("====================================")
+
+	escenarios := []struct {
+		nombre string
+		config ConfigPlanificador
+	}{
+		{"Estricta sin envejecimiento", ConfigPlanificador{Politica: PlanificacionEstricta}},
+		{"Estricta con envejecimiento", ConfigPlanificador{Politica: PlanificacionEstricta, Envejecimiento: 20 * time.Millisecond}},
+		{"Ponderada 6:3:1", ConfigPlanificadorPorDefecto(0)},
+	}
+
+	for _, escenario := range escenarios {
+		cola := NewColaEventos(escenario.config)
+		for i := 0; i < 50; i++ {
+			cola.Encolar(context.Background(), Evento{ID: int64(i), Priority: 3})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+		// Llega un evento de alta prioridad por milisegundo, más de lo que
+		// el consumidor puede atender
+		go func() {
+			ticker := time.NewTicker(time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					cola.Encolar(ctx, Evento{Priority: 1})
+				}
+			}
+		}()
+
+		var atendidos [NumPrioridades]int
+		for {
+			evento, _, ok := cola.Desencolar(ctx)
+			if !ok {
+				break
+			}
+			atendidos[indicePrioridad(evento.Priority)]++
+			time.Sleep(2 * time.Millisecond)
+		}
+		cancel()
+
+		fmt.Printf("   %-28s alta: %3d | baja: %2d de 50\n", escenario.nombre, atendidos[0], atendidos[2])
+	}
+	fmt.Println()
+}
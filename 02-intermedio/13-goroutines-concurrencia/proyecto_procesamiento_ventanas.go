@@ -0,0 +1,442 @@
+// ==============================================
+// PROYECTO: Sistema de Procesamiento - Agregaciones por ventanas
+// ==============================================
+// Ventanas fijas, deslizantes y de sesión por UserID o Action, en tiempo de
+// evento (Evento.Timestamp), con watermarks y retraso permitido
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// CONFIGURACIÓN DE VENTANAS
+// ==============================================
+
+type TipoVentana string
+
+const (
+	VentanaFija       TipoVentana = "fija"       // tumbling: [0,T), [T,2T)...
+	VentanaDeslizante TipoVentana = "deslizante" // sliding: de tamaño T cada paso P
+	VentanaSesion     TipoVentana = "sesion"     // se cierra tras un hueco sin eventos
+)
+
+type ClaveAgrupacion string
+
+const (
+	PorUsuario ClaveAgrupacion = "user_id"
+	PorAccion  ClaveAgrupacion = "action"
+)
+
+func (c ClaveAgrupacion) de(evento Evento) string {
+	if c == PorUsuario {
+		return strconv.Itoa(evento.UserID)
+	}
+	return evento.Action
+}
+
+type ConfigVentana struct {
+	Tipo  TipoVentana
+	Clave ClaveAgrupacion
+
+	Tamano       time.Duration // fija y deslizante
+	Paso         time.Duration // deslizante: cada cuánto empieza una ventana
+	Inactividad  time.Duration // sesión: hueco que la cierra
+	Desorden     time.Duration // watermark = mayor Timestamp visto - Desorden
+	RetrasoExtra time.Duration // retraso permitido tras el watermark
+}
+
+var ErrConfigVentana = errors.New("configuración de ventana inválida")
+
+// validar exige la duración que usa cada tipo de ventana: con Tamano o
+// Inactividad <= 0 las ventanas no avanzan y se generarían sin fin
+func (c ConfigVentana) validar() error {
+	switch c.Tipo {
+	case VentanaFija, VentanaDeslizante:
+		if c.Tamano <= 0 {
+			return fmt.Errorf("%w: la ventana %s necesita Tamano > 0", ErrConfigVentana, c.Tipo)
+		}
+		if c.Paso < 0 {
+			return fmt.Errorf("%w: Paso no puede ser negativo", ErrConfigVentana)
+		}
+	case VentanaSesion:
+		if c.Inactividad <= 0 {
+			return fmt.Errorf("%w: la ventana de sesión necesita Inactividad > 0", ErrConfigVentana)
+		}
+	default:
+		return fmt.Errorf("%w: tipo desconocido %q", ErrConfigVentana, c.Tipo)
+	}
+	if c.Clave != PorUsuario && c.Clave != PorAccion {
+		return fmt.Errorf("%w: clave desconocida %q", ErrConfigVentana, c.Clave)
+	}
+	if c.Desorden < 0 || c.RetrasoExtra < 0 {
+		return fmt.Errorf("%w: Desorden y RetrasoExtra no pueden ser negativos", ErrConfigVentana)
+	}
+	return nil
+}
+
+// AgregadoVentana es lo que recibe el sink por cada ventana y clave
+type AgregadoVentana struct {
+	Tipo           TipoVentana
+	Clave          string
+	Inicio, Fin    time.Time
+	Eventos        int64
+	Errores        int64
+	TiempoPromedio time.Duration
+	PorTipo        map[string]int64 // por tipo de resultado del handler
+	Actualizacion  bool             // re-emisión por un evento tardío
+}
+
+func (a AgregadoVentana) String() string {
+	tipos := make([]string, 0, len(a.PorTipo))
+	for tipo, n := range a.PorTipo {
+		tipos = append(tipos, fmt.Sprintf("%s=%d", tipo, n))
+	}
+	sort.Strings(tipos)
+	actualizacion := ""
+	if a.Actualizacion {
+		actualizacion = " (actualización)"
+	}
+	return fmt.Sprintf("[%s-%s] %s: %d eventos, %d errores, %v promedio, %s%s",
+		a.Inicio.Format("15:04:05.000"), a.Fin.Format("15:04:05.000"), a.Clave, a.Eventos, a.Errores,
+		a.TiempoPromedio.Round(time.Millisecond), strings.Join(tipos, " "), actualizacion)
+}
+
+// ==============================================
+// ESTADO DE LAS VENTANAS
+// ==============================================
+
+type estadoVentana struct {
+	inicio, fin time.Time
+	eventos     int64
+	errores     int64
+	tiempoTotal time.Duration
+	porTipo     map[string]int64
+	emitida     bool
+}
+
+func (e *estadoVentana) agregar(resultado EventoProcesado) {
+	e.eventos++
+	if resultado.Error != "" {
+		e.errores++
+	}
+	e.tiempoTotal += resultado.TiempoProceso
+	if tipo := resultado.Salida.Tipo; tipo != "" {
+		e.porTipo[tipo]++
+	}
+}
+
+func (e *estadoVentana) fusionar(otra *estadoVentana) {
+	if otra.inicio.Before(e.inicio) {
+		e.inicio = otra.inicio
+	}
+	if otra.fin.After(e.fin) {
+		e.fin = otra.fin
+	}
+	e.eventos += otra.eventos
+	e.errores += otra.errores
+	e.tiempoTotal += otra.tiempoTotal
+	for tipo, n := range otra.porTipo {
+		e.porTipo[tipo] += n
+	}
+	e.emitida = e.emitida || otra.emitida
+}
+
+// AgregadorVentanas agrupa los resultados en ventanas de tiempo de evento.
+// Una ventana se emite cuando el watermark pasa su fin; un evento que llega
+// después, dentro del retraso permitido, la re-emite como actualización, y
+// uno que llega más tarde se descarta y se cuenta.
+type AgregadorVentanas struct {
+	config   ConfigVentana
+	sink     func(AgregadoVentana)
+	ventanas map[string][]*estadoVentana // por clave
+
+	maxTimestamp time.Time
+	emitidas     int64
+	tardios      int64
+	mu           sync.Mutex
+}
+
+func NewAgregadorVentanas(config ConfigVentana, sink func(AgregadoVentana)) (*AgregadorVentanas, error) {
+	if err := config.validar(); err != nil {
+		return nil, err
+	}
+	if config.Paso == 0 {
+		config.Paso = config.Tamano
+	}
+	return &AgregadorVentanas{
+		config:   config,
+		sink:     sink,
+		ventanas: make(map[string][]*estadoVentana),
+	}, nil
+}
+
+// Watermark es el instante hasta el cual se asume que ya llegó todo
+func (v *AgregadorVentanas) Watermark() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.watermark()
+}
+
+func (v *AgregadorVentanas) watermark() time.Time {
+	if v.maxTimestamp.IsZero() {
+		return time.Time{}
+	}
+	return v.maxTimestamp.Add(-v.config.Desorden)
+}
+
+// Agregar incorpora un resultado; el sink se llama fuera del lock
+func (v *AgregadorVentanas) Agregar(resultado EventoProcesado) {
+	v.mu.Lock()
+	emitir := v.agregar(resultado)
+	v.mu.Unlock()
+
+	for _, agregado := range emitir {
+		v.sink(agregado)
+	}
+}
+
+func (v *AgregadorVentanas) agregar(resultado EventoProcesado) []AgregadoVentana {
+	t := resultado.EventoOriginal.Timestamp
+	clave := v.config.Clave.de(resultado.EventoOriginal)
+	watermark := v.watermark()
+
+	// Las ventanas cuyo plazo de retraso ya venció no admiten el evento
+	var destino []*estadoVentana
+	for _, rango := range v.rangos(t) {
+		if !watermark.IsZero() && !rango.fin.Add(v.config.RetrasoExtra).After(watermark) {
+			continue
+		}
+		destino = append(destino, v.ventanaPara(clave, rango))
+	}
+	if len(destino) == 0 {
+		v.tardios++
+		return nil
+	}
+
+	var emitir []AgregadoVentana
+	for _, ventana := range destino {
+		ventana.agregar(resultado)
+		if ventana.emitida {
+			emitir = append(emitir, v.agregado(clave, ventana, true))
+		}
+	}
+
+	if t.After(v.maxTimestamp) {
+		v.maxTimestamp = t
+	}
+	return append(emitir, v.disparar(v.watermark())...)
+}
+
+type rangoVentana struct {
+	inicio, fin time.Time
+}
+
+// rangos devuelve las ventanas a las que pertenece un instante
+func (v *AgregadorVentanas) rangos(t time.Time) []rangoVentana {
+	switch v.config.Tipo {
+	case VentanaSesion:
+		return []rangoVentana{{t, t.Add(v.config.Inactividad)}}
+	case VentanaDeslizante:
+		var rangos []rangoVentana
+		for inicio := t.Truncate(v.config.Paso); inicio.Add(v.config.Tamano).After(t); inicio = inicio.Add(-v.config.Paso) {
+			rangos = append(rangos, rangoVentana{inicio, inicio.Add(v.config.Tamano)})
+		}
+		return rangos
+	default:
+		inicio := t.Truncate(v.config.Tamano)
+		return []rangoVentana{{inicio, inicio.Add(v.config.Tamano)}}
+	}
+}
+
+// ventanaPara busca o crea la ventana de una clave. En sesiones, el rango
+// nuevo se fusiona con todas las sesiones que solapa.
+func (v *AgregadorVentanas) ventanaPara(clave string, rango rangoVentana) *estadoVentana {
+	existentes := v.ventanas[clave]
+	nueva := &estadoVentana{inicio: rango.inicio, fin: rango.fin, porTipo: make(map[string]int64)}
+
+	if v.config.Tipo != VentanaSesion {
+		for _, ventana := range existentes {
+			if ventana.inicio.Equal(rango.inicio) {
+				return ventana
+			}
+		}
+		v.ventanas[clave] = append(existentes, nueva)
+		return nueva
+	}
+
+	restantes := existentes[:0]
+	for _, ventana := range existentes {
+		if !ventana.inicio.After(nueva.fin) && !nueva.inicio.After(ventana.fin) {
+			nueva.fusionar(ventana)
+		} else {
+			restantes = append(restantes, ventana)
+		}
+	}
+	v.ventanas[clave] = append(restantes, nueva)
+	return nueva
+}
+
+// disparar emite las ventanas que el watermark cerró y olvida las que ya
+// no pueden recibir eventos tardíos
+func (v *AgregadorVentanas) disparar(watermark time.Time) []AgregadoVentana {
+	var emitir []AgregadoVentana
+	for clave, ventanas := range v.ventanas {
+		vivas := ventanas[:0]
+		for _, ventana := range ventanas {
+			if !ventana.emitida && !ventana.fin.After(watermark) {
+				ventana.emitida = true
+				emitir = append(emitir, v.agregado(clave, ventana, false))
+			}
+			if ventana.fin.Add(v.config.RetrasoExtra).After(watermark) {
+				vivas = append(vivas, ventana)
+			}
+		}
+		if len(vivas) == 0 {
+			delete(v.ventanas, clave)
+		} else {
+			v.ventanas[clave] = vivas
+		}
+	}
+	ordenarAgregados(emitir)
+	return emitir
+}
+
+func (v *AgregadorVentanas) agregado(clave string, ventana *estadoVentana, actualizacion bool) AgregadoVentana {
+	v.emitidas++
+	porTipo := make(map[string]int64, len(ventana.porTipo))
+	for tipo, n := range ventana.porTipo {
+		porTipo[tipo] = n
+	}
+	var promedio time.Duration
+	if ventana.eventos > 0 {
+		promedio = ventana.tiempoTotal / time.Duration(ventana.eventos)
+	}
+	return AgregadoVentana{
+		Tipo:           v.config.Tipo,
+		Clave:          clave,
+		Inicio:         ventana.inicio,
+		Fin:            ventana.fin,
+		Eventos:        ventana.eventos,
+		Errores:        ventana.errores,
+		TiempoPromedio: promedio,
+		PorTipo:        porTipo,
+		Actualizacion:  actualizacion,
+	}
+}
+
+func ordenarAgregados(agregados []AgregadoVentana) {
+	sort.Slice(agregados, func(i, j int) bool {
+		if !agregados[i].Inicio.Equal(agregados[j].Inicio) {
+			return agregados[i].Inicio.Before(agregados[j].Inicio)
+		}
+		return agregados[i].Clave < agregados[j].Clave
+	})
+}
+
+// Cerrar emite las ventanas que siguen abiertas, como si el watermark
+// llegara al infinito; se llama al detener el sistema
+func (v *AgregadorVentanas) Cerrar() {
+	v.mu.Lock()
+	var emitir []AgregadoVentana
+	for clave, ventanas := range v.ventanas {
+		for _, ventana := range ventanas {
+			if !ventana.emitida {
+				emitir = append(emitir, v.agregado(clave, ventana, false))
+			}
+		}
+	}
+	v.ventanas = make(map[string][]*estadoVentana)
+	v.mu.Unlock()
+
+	ordenarAgregados(emitir)
+	for _, agregado := range emitir {
+		v.sink(agregado)
+	}
+}
+
+type ResumenVentanas struct {
+	Config    ConfigVentana
+	Abiertas  int
+	Emitidas  int64
+	Tardios   int64 // descartados por llegar después del retraso permitido
+	Watermark time.Time
+}
+
+func (v *AgregadorVentanas) Resumen() ResumenVentanas {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	abiertas := 0
+	for _, ventanas := range v.ventanas {
+		abiertas += len(ventanas)
+	}
+	return ResumenVentanas{
+		Config:    v.config,
+		Abiertas:  abiertas,
+		Emitidas:  v.emitidas,
+		Tardios:   v.tardios,
+		Watermark: v.watermark(),
+	}
+}
+
+// ==============================================
+// DEMOSTRACIÓN: TIEMPO DE EVENTO Y RETRASOS
+// ==============================================
+
+// demoVentanas alimenta los tres tipos de ventana con eventos de marca de
+// tiempo controlada, uno de ellos tardío y otro fuera de plazo
+func demoVentanas() {
+	fmt.Println("🪟 DEMO: Ventanas en Tiempo de Evento")
+	fmt.Println("=====================================")
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	llegadas := []struct {
+		segundo float64
+		usuario int
+	}{
+		{0.5, 1}, {1.2, 2}, {2.5, 1}, {3.1, 1},
+		{1.8, 2}, // tardío: llega tras el 3.1 pero dentro del retraso permitido
+		{6.0, 2}, {8.4, 1},
+		{2.2, 1}, // fuera de plazo: el watermark ya pasó 5s
+		{9.0, 2},
+	}
+
+	configs := []ConfigVentana{
+		{Tipo: VentanaFija, Clave: PorAccion, Tamano: 2 * time.Second, Desorden: time.Second, RetrasoExtra: time.Second},
+		{Tipo: VentanaDeslizante, Clave: PorAccion, Tamano: 4 * time.Second, Paso: 2 * time.Second, Desorden: time.Second, RetrasoExtra: time.Second},
+		{Tipo: VentanaSesion, Clave: PorUsuario, Inactividad: 2 * time.Second, Desorden: time.Second, RetrasoExtra: time.Second},
+	}
+
+	for _, config := range configs {
+		fmt.Printf("   %s por %s:\n", config.Tipo, config.Clave)
+		ventanas, err := NewAgregadorVentanas(config, func(a AgregadoVentana) {
+			fmt.Printf("      %s\n", a)
+		})
+		if err != nil {
+			fmt.Printf("      ❌ %v\n", err)
+			continue
+		}
+		for i, llegada := range llegadas {
+			ventanas.Agregar(EventoProcesado{
+				EventoOriginal: Evento{
+					ID:        int64(i + 1),
+					Timestamp: base.Add(time.Duration(llegada.segundo * float64(time.Second))),
+					UserID:    llegada.usuario,
+					Action:    "click",
+				},
+				Salida:        ResultadoHandler{Tipo: "analitica"},
+				TiempoProceso: 10 * time.Millisecond,
+			})
+		}
+		ventanas.Cerrar()
+		fmt.Printf("      eventos fuera de plazo: %d\n", ventanas.Resumen().Tardios)
+	}
+	fmt.Println()
+}
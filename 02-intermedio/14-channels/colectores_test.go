@@ -0,0 +1,258 @@
+// 🧪 Tests: Colectores de métricas del host
+// Archivo: colectores_test.go
+// Ejecutar con: go test -v proyecto_monitoreo*.go colectores_test.go
+//
+// Los archivos de testdata/proc/t0 y t1 son dos muestras de /proc tomadas
+// con 2 segundos de diferencia
+
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	procT0 = "testdata/proc/t0"
+	procT1 = "testdata/proc/t1"
+)
+
+var (
+	instanteT0 = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	instanteT1 = instanteT0.Add(2 * time.Second)
+)
+
+func casiIgual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// recolectarDosMuestras toma la primera muestra de t0 y devuelve los eventos
+// de la segunda, leída de t1
+func recolectarDosMuestras(t *testing.T, colector Colector, cambiarRaiz func(string)) []Evento {
+	t.Helper()
+	primera, err := colector.Recolectar(instanteT0)
+	if err != nil {
+		t.Fatalf("Expected no error on first sample, got: %v", err)
+	}
+	if len(primera) != 0 {
+		t.Fatalf("Expected no events on first sample, got %d", len(primera))
+	}
+	cambiarRaiz(procT1)
+	eventos, err := colector.Recolectar(instanteT1)
+	if err != nil {
+		t.Fatalf("Expected no error on second sample, got: %v", err)
+	}
+	return eventos
+}
+
+func TestColectorCPU_UsoEntreMuestras(t *testing.T) {
+	colector := NewColectorCPU(procT0)
+
+	eventos := recolectarDosMuestras(t, colector, func(raiz string) { colector.raiz = raiz })
+
+	if len(eventos) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(eventos))
+	}
+	evento := eventos[0]
+	// total 10000 -> 12000 (guest no se suma), ocupado 1500 -> 3000
+	if evento.Tipo != EventoCPU || evento.Servicio != "sistema" {
+		t.Errorf("Expected CPU/sistema, got %s/%s", evento.Tipo, evento.Servicio)
+	}
+	if !casiIgual(evento.Valor, 75) {
+		t.Errorf("Expected 75%% usage, got %.4f", evento.Valor)
+	}
+	if !evento.Timestamp.Equal(instanteT1) {
+		t.Errorf("Expected timestamp %v, got %v", instanteT1, evento.Timestamp)
+	}
+}
+
+func TestColectorCPU_DescartaOcupadoQueRetrocede(t *testing.T) {
+	raiz := t.TempDir()
+	escribirStat := func(linea string) {
+		if err := os.WriteFile(filepath.Join(raiz, "stat"), []byte(linea+"\n"), 0o644); err != nil {
+			t.Fatalf("Expected no error writing stat, got: %v", err)
+		}
+	}
+	colector := NewColectorCPU(raiz)
+
+	// total 1000 -> 1140, pero ocupado 200 -> 190 porque iowait adelanta
+	escribirStat("cpu 100 0 100 700 100 0 0 0 0 0")
+	if _, err := colector.Recolectar(instanteT0); err != nil {
+		t.Fatalf("Expected no error on first sample, got: %v", err)
+	}
+	escribirStat("cpu 100 0 90 700 250 0 0 0 0 0")
+	eventos, err := colector.Recolectar(instanteT1)
+	if err != nil {
+		t.Fatalf("Expected no error on second sample, got: %v", err)
+	}
+	if len(eventos) != 0 {
+		t.Fatalf("Expected the sample to be discarded, got %.2f%%", eventos[0].Valor)
+	}
+}
+
+func TestParsearStat_SinLineaCPU(t *testing.T) {
+	if _, err := parsearStat([]byte("intr 1 2 3\nctxt 4\n")); err == nil {
+		t.Error("Expected error when the cpu line is missing")
+	}
+}
+
+func TestColectorMemoria_EnUsoEnMB(t *testing.T) {
+	colector := NewColectorMemoria(procT0)
+
+	eventos, err := colector.Recolectar(instanteT0)
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(eventos) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(eventos))
+	}
+	// (16384000 - 12288000) kB = 4000 MB
+	if !casiIgual(eventos[0].Valor, 4000) {
+		t.Errorf("Expected 4000 MB in use, got %.2f", eventos[0].Valor)
+	}
+	if got := eventos[0].Metadata["total_mb"]; got != "16000" {
+		t.Errorf("Expected total_mb 16000, got %q", got)
+	}
+}
+
+func TestColectorMemoria_SinMemAvailable(t *testing.T) {
+	raiz := t.TempDir()
+	if err := os.WriteFile(filepath.Join(raiz, "meminfo"), []byte("MemTotal: 1024 kB\nMemFree: 512 kB\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewColectorMemoria(raiz).Recolectar(instanteT0); err == nil {
+		t.Error("Expected error when MemAvailable is missing")
+	}
+}
+
+func TestColectorRed_ThroughputPorInterfaz(t *testing.T) {
+	colector := NewColectorRed(procT0)
+
+	eventos := recolectarDosMuestras(t, colector, func(raiz string) { colector.raiz = raiz })
+
+	valores := make(map[string]float64)
+	for _, evento := range eventos {
+		valores[evento.Metadata["interface"]] = evento.Valor
+	}
+	// lo se ignora y docker0 no existía en la primera muestra
+	if len(valores) != 2 {
+		t.Fatalf("Expected eth0 and wlan0, got %v", valores)
+	}
+	// (2500000 + 500000) bytes * 8 / 2 s = 12 Mbps
+	if !casiIgual(valores["eth0"], 12) {
+		t.Errorf("Expected eth0 at 12 Mbps, got %.4f", valores["eth0"])
+	}
+	if valores["wlan0"] != 0 {
+		t.Errorf("Expected wlan0 at 0 Mbps, got %.4f", valores["wlan0"])
+	}
+}
+
+func TestParsearDiskstats_DescartaParticionesYLoop(t *testing.T) {
+	datos, err := os.ReadFile(filepath.Join(procT0, "diskstats"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	discos, err := parsearDiskstats(datos)
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var nombres []string
+	for nombre := range discos {
+		nombres = append(nombres, nombre)
+	}
+	slices.Sort(nombres)
+	want := []string{"md1", "md10", "nvme0n1", "sda"}
+	if !slices.Equal(nombres, want) {
+		t.Errorf("Expected disks %v, got %v", want, nombres)
+	}
+}
+
+func TestColectorDisco_ThroughputLecturaEscritura(t *testing.T) {
+	colector := NewColectorDisco(procT0)
+
+	eventos := recolectarDosMuestras(t, colector, func(raiz string) { colector.raiz = raiz })
+
+	var sda *Evento
+	for i := range eventos {
+		if eventos[i].Metadata["dispositivo"] == "sda" {
+			sda = &eventos[i]
+		}
+	}
+	if sda == nil {
+		t.Fatalf("Expected an event for sda, got %v", eventos)
+	}
+	// (4000 + 8000) sectores * 512 B / 2 s = 3.072 MB/s
+	if !casiIgual(sda.Valor, 3.072) {
+		t.Errorf("Expected 3.072 MB/s, got %.4f", sda.Valor)
+	}
+	if sda.Metadata["lectura"] != "1.02" || sda.Metadata["escritura"] != "2.05" {
+		t.Errorf("Expected read 1.02 and write 2.05, got %s and %s", sda.Metadata["lectura"], sda.Metadata["escritura"])
+	}
+}
+
+func TestColectorRuntime_MemoriaDelProceso(t *testing.T) {
+	colector := NewColectorRuntime()
+
+	eventos, err := colector.Recolectar(time.Now())
+
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(eventos) == 0 || eventos[0].Tipo != EventoMemoria || eventos[0].Servicio != "runtime" {
+		t.Fatalf("Expected a MEMORIA/runtime event first, got %v", eventos)
+	}
+	if eventos[0].Valor <= 0 {
+		t.Errorf("Expected positive memory usage, got %.2f", eventos[0].Valor)
+	}
+	if eventos[0].Metadata["goroutines"] == "" {
+		t.Error("Expected goroutines in metadata")
+	}
+}
+
+func TestEjecutarColector_PublicaHastaCancelar(t *testing.T) {
+	var colectores []Colector
+	colectores = append(colectores, ColectoresHost(procT0)...)
+	colectores = append(colectores, ColectoresSimulados()...)
+	for _, colector := range colectores {
+		if colector.Nombre() == "" || colector.Intervalo() <= 0 {
+			t.Errorf("Expected name and interval for %T", colector)
+		}
+	}
+
+	fijo := &ColectorSimulado{nombre: "fijo", intervalo: time.Millisecond, generar: func(ahora time.Time) []Evento {
+		return []Evento{{Timestamp: ahora, Tipo: EventoDisco, Valor: 1}}
+	}}
+	eventos := make(chan Evento)
+	ctx, cancel := context.WithCancel(context.Background())
+	terminado := make(chan struct{})
+	go func() {
+		ejecutarColector(fijo, eventos, ctx)
+		close(terminado)
+	}()
+
+	for range 3 {
+		select {
+		case evento := <-eventos:
+			if evento.Tipo != EventoDisco {
+				t.Errorf("Expected DISCO event, got %s", evento.Tipo)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for events")
+		}
+	}
+	cancel()
+	select {
+	case <-terminado:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the collector to stop after cancel")
+	}
+}
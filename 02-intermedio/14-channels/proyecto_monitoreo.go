@@ -8,7 +8,10 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -63,16 +66,33 @@ type Metricas struct {
 	ultimoEvento     time.Time
 	eventosError     int64
 	promedioLatencia float64
+	series           *AlmacenSeries // valores por tipo/servicio
 	mu               sync.RWMutex
 }
 
+// Estadisticas es una foto de las métricas: PorTipo es una copia y Series
+// resume la ventana desde la foto anterior
+type Estadisticas struct {
+	EventosTotal int64
+	EventosError int64
+	TasaError    float64
+	UltimoEvento time.Time
+	PorTipo      map[TipoEvento]int64
+	Series       []ResumenSerie
+}
+
 // Sistema de monitoreo
 type SistemaMonitoreo struct {
 	eventos      chan Evento
-	alertas      chan Evento
-	estadisticas chan map[string]interface{}
+	alertas      chan Alerta
+	estadisticas chan Estadisticas
 	quit         chan bool
 	metricas     *Metricas
+	colectores   []Colector
+	reglas       *MotorReglas
+	incidentes   *GestorIncidentes
+	desborde     *ColaDisco // alertas que no cupieron en el canal
+	wg           sync.WaitGroup
 	ctx          context.Context
 	cancel       context.CancelFunc
 }
@@ -81,131 +101,96 @@ type SistemaMonitoreo struct {
 // GENERADORES DE EVENTOS
 // ==============================================
 
-func generadorCPU(eventos chan<- Evento, ctx context.Context) {
-	ticker := time.NewTicker(200 * time.Millisecond)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Println("🔄 Generador CPU terminando...")
-			return
-		case <-ticker.C:
-			uso := rand.Float64() * 100
-			evento := Evento{
-				Timestamp: time.Now(),
-				Tipo:      EventoCPU,
-				Servicio:  "sistema",
-				Valor:     uso,
-				Metadata: map[string]string{
-					"unidad": "porcentaje",
-					"core":   fmt.Sprintf("core-%d", rand.Intn(4)),
-				},
-			}
-
-			select {
-			case eventos <- evento:
-			case <-ctx.Done():
-				return
-			}
-		}
-	}
+// ColectorSimulado genera valores aleatorios; implementa Colector igual que
+// los que leen el host, así que son intercambiables
+type ColectorSimulado struct {
+	nombre    string
+	intervalo time.Duration
+	generar   func(ahora time.Time) []Evento
 }
 
-func generadorMemoria(eventos chan<- Evento, ctx context.Context) {
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
+func (c *ColectorSimulado) Nombre() string           { return c.nombre }
+func (c *ColectorSimulado) Intervalo() time.Duration { return c.intervalo }
 
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Println("💾 Generador Memoria terminando...")
-			return
-		case <-ticker.C:
-			uso := 2048 + rand.Float64()*6144 // 2-8 GB
-			evento := Evento{
-				Timestamp: time.Now(),
-				Tipo:      EventoMemoria,
-				Servicio:  "sistema",
-				Valor:     uso,
-				Metadata: map[string]string{
-					"unidad": "MB",
-					"tipo":   "RAM",
-				},
-			}
+func (c *ColectorSimulado) Recolectar(ahora time.Time) ([]Evento, error) {
+	return c.generar(ahora), nil
+}
 
-			select {
-			case eventos <- evento:
-			case <-ctx.Done():
-				return
-			}
-		}
+func ColectoresSimulados() []Colector {
+	return []Colector{
+		NewColectorCPUSimulado(),
+		NewColectorMemoriaSimulado(),
+		NewColectorRedSimulado(),
+		NewColectorErroresSimulado(),
 	}
 }
 
-func generadorRed(eventos chan<- Evento, ctx context.Context) {
-	ticker := time.NewTicker(300 * time.Millisecond)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Println("🌐 Generador Red terminando...")
-			return
-		case <-ticker.C:
-			throughput := rand.Float64() * 1000 // Mbps
-			evento := Evento{
-				Timestamp: time.Now(),
-				Tipo:      EventoRed,
-				Servicio:  "networking",
-				Valor:     throughput,
-				Metadata: map[string]string{
-					"unidad":    "Mbps",
-					"interface": fmt.Sprintf("eth%d", rand.Intn(3)),
-				},
-			}
-
-			select {
-			case eventos <- evento:
-			case <-ctx.Done():
-				return
-			}
-		}
-	}
+func NewColectorCPUSimulado() *ColectorSimulado {
+	return &ColectorSimulado{nombre: "cpu-simulado", intervalo: 200 * time.Millisecond, generar: func(ahora time.Time) []Evento {
+		uso := rand.Float64() * 100
+		return []Evento{{
+			Timestamp: ahora,
+			Tipo:      EventoCPU,
+			Servicio:  "sistema",
+			Valor:     uso,
+			Metadata: map[string]string{
+				"unidad": "porcentaje",
+				"core":   fmt.Sprintf("core-%d", rand.Intn(4)),
+			},
+		}}
+	}}
 }
 
-func generadorErrores(eventos chan<- Evento, ctx context.Context) {
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
+func NewColectorMemoriaSimulado() *ColectorSimulado {
+	return &ColectorSimulado{nombre: "memoria-simulado", intervalo: 500 * time.Millisecond, generar: func(ahora time.Time) []Evento {
+		uso := 2048 + rand.Float64()*6144 // 2-8 GB
+		return []Evento{{
+			Timestamp: ahora,
+			Tipo:      EventoMemoria,
+			Servicio:  "sistema",
+			Valor:     uso,
+			Metadata: map[string]string{
+				"unidad": "MB",
+				"tipo":   "RAM",
+			},
+		}}
+	}}
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Println("❌ Generador Errores terminando...")
-			return
-		case <-ticker.C:
-			// Generar error ocasional
-			if rand.Float64() < 0.3 {
-				severidad := rand.Float64() * 10
-				evento := Evento{
-					Timestamp: time.Now(),
-					Tipo:      EventoError,
-					Servicio:  fmt.Sprintf("servicio-%d", rand.Intn(5)),
-					Valor:     severidad,
-					Metadata: map[string]string{
-						"codigo": fmt.Sprintf("ERR-%03d", rand.Intn(999)),
-						"nivel":  obtenerNivelError(severidad),
-					},
-				}
+func NewColectorRedSimulado() *ColectorSimulado {
+	return &ColectorSimulado{nombre: "red-simulado", intervalo: 300 * time.Millisecond, generar: func(ahora time.Time) []Evento {
+		throughput := rand.Float64() * 1000 // Mbps
+		return []Evento{{
+			Timestamp: ahora,
+			Tipo:      EventoRed,
+			Servicio:  "networking",
+			Valor:     throughput,
+			Metadata: map[string]string{
+				"unidad":    "Mbps",
+				"interface": fmt.Sprintf("eth%d", rand.Intn(3)),
+			},
+		}}
+	}}
+}
 
-				select {
-				case eventos <- evento:
-				case <-ctx.Done():
-					return
-				}
-			}
+func NewColectorErroresSimulado() *ColectorSimulado {
+	return &ColectorSimulado{nombre: "errores-simulado", intervalo: 2 * time.Second, generar: func(ahora time.Time) []Evento {
+		// Generar error ocasional
+		if rand.Float64() >= 0.3 {
+			return nil
 		}
-	}
+		severidad := rand.Float64() * 10
+		return []Evento{{
+			Timestamp: ahora,
+			Tipo:      EventoError,
+			Servicio:  fmt.Sprintf("servicio-%d", rand.Intn(5)),
+			Valor:     severidad,
+			Metadata: map[string]string{
+				"codigo": fmt.Sprintf("ERR-%03d", rand.Intn(999)),
+				"nivel":  obtenerNivelError(severidad),
+			},
+		}}
+	}}
 }
 
 func obtenerNivelError(severidad float64) string {
@@ -246,25 +231,14 @@ func procesadorEventos(sistema *SistemaMonitoreo) {
 			sistema.metricas.ultimoEvento = evento.Timestamp
 			sistema.metricas.mu.Unlock()
 
-			// Procesar según tipo
-			switch evento.Tipo {
-			case EventoCPU:
-				if evento.Valor > 90 {
-					enviarAlerta(sistema.alertas, evento, "CPU crítico")
-				}
-			case EventoMemoria:
-				if evento.Valor > 7000 { // > 7GB
-					enviarAlerta(sistema.alertas, evento, "Memoria alta")
-				}
-			case EventoRed:
-				if evento.Valor < 10 {
-					enviarAlerta(sistema.alertas, evento, "Conectividad baja")
-				}
-			case EventoError:
+			if evento.Tipo == EventoError {
 				atomic.AddInt64(&sistema.metricas.eventosError, 1)
-				if evento.Valor > 7 {
-					enviarAlerta(sistema.alertas, evento, "Error crítico")
-				}
+			}
+			sistema.metricas.series.Agregar(evento)
+
+			// Las reglas cargadas deciden qué eventos generan alertas
+			for _, alerta := range sistema.reglas.Evaluar(evento) {
+				sistema.enviarAlerta(alerta)
 			}
 
 			// Log del evento
@@ -277,19 +251,9 @@ func procesadorEventos(sistema *SistemaMonitoreo) {
 	}
 }
 
-func enviarAlerta(alertas chan<- Evento, evento Evento, descripcion string) {
-	evento.Metadata["alerta"] = descripcion
-
-	select {
-	case alertas <- evento:
-		// Alerta enviada
-	default:
-		// Canal de alertas lleno, alerta perdida
-		fmt.Printf("⚠️ Canal de alertas lleno, alerta perdida: %s\n", descripcion)
-	}
-}
-
-func procesadorAlertas(alertas <-chan Evento, ctx context.Context) {
+// procesadorAlertas entrega las alertas al gestor, que las agrupa en
+// incidentes y notifica solo los cambios de estado
+func procesadorAlertas(alertas <-chan Alerta, incidentes *GestorIncidentes, ctx context.Context) {
 	fmt.Println("🚨 Procesador de alertas iniciado")
 
 	for {
@@ -299,16 +263,7 @@ func procesadorAlertas(alertas <-chan Evento, ctx context.Context) {
 			return
 
 		case alerta := <-alertas:
-			nivel := "🟡"
-			if alerta.Valor > 8 {
-				nivel = "🔴"
-			}
-
-			fmt.Printf("%s ALERTA [%s]: %s - %.2f\n",
-				nivel,
-				alerta.Tipo,
-				alerta.Metadata["alerta"],
-				alerta.Valor)
+			incidentes.Procesar(alerta)
 		}
 	}
 }
@@ -319,14 +274,16 @@ func generadorEstadisticas(sistema *SistemaMonitoreo) {
 
 	fmt.Println("📊 Generador de estadísticas iniciado")
 
+	desde := time.Now()
 	for {
 		select {
 		case <-sistema.ctx.Done():
 			fmt.Println("📊 Generador de estadísticas terminando...")
 			return
 
-		case <-ticker.C:
-			stats := calcularEstadisticas(sistema.metricas)
+		case hasta := <-ticker.C:
+			stats := calcularEstadisticas(sistema.metricas, desde, hasta)
+			desde = hasta
 
 			select {
 			case sistema.estadisticas <- stats:
@@ -337,25 +294,33 @@ func generadorEstadisticas(sistema *SistemaMonitoreo) {
 	}
 }
 
-func calcularEstadisticas(metricas *Metricas) map[string]interface{} {
+// calcularEstadisticas copia los contadores bajo el lock: el procesador de
+// estadísticas no comparte memoria con el de eventos
+func calcularEstadisticas(metricas *Metricas, desde, hasta time.Time) Estadisticas {
 	metricas.mu.RLock()
-	defer metricas.mu.RUnlock()
-
-	total := atomic.LoadInt64(&metricas.eventosTotal)
-	errores := atomic.LoadInt64(&metricas.eventosError)
-
-	stats := map[string]interface{}{
-		"eventos_total":    total,
-		"eventos_error":    errores,
-		"tasa_error":       float64(errores) / float64(total) * 100,
-		"ultimo_evento":    metricas.ultimoEvento.Format("15:04:05"),
-		"eventos_por_tipo": metricas.eventosPorTipo,
+	stats := Estadisticas{
+		EventosTotal: atomic.LoadInt64(&metricas.eventosTotal),
+		EventosError: atomic.LoadInt64(&metricas.eventosError),
+		UltimoEvento: metricas.ultimoEvento,
+		PorTipo:      make(map[TipoEvento]int64, len(metricas.eventosPorTipo)),
+	}
+	for tipo, count := range metricas.eventosPorTipo {
+		stats.PorTipo[tipo] = count
 	}
+	metricas.mu.RUnlock()
 
+	if stats.EventosTotal > 0 {
+		stats.TasaError = float64(stats.EventosError) / float64(stats.EventosTotal) * 100
+	}
+	for _, id := range metricas.series.Series() {
+		if resumen, ok := metricas.series.Consultar(id, desde, hasta); ok && resumen.Cuenta > 0 {
+			stats.Series = append(stats.Series, resumen)
+		}
+	}
 	return stats
 }
 
-func procesadorEstadisticas(estadisticas <-chan map[string]interface{}, ctx context.Context) {
+func procesadorEstadisticas(estadisticas <-chan Estadisticas, ctx context.Context) {
 	fmt.Println("📈 Procesador de estadísticas iniciado")
 
 	for {
@@ -369,36 +334,53 @@ func procesadorEstadisticas(estadisticas <-chan map[string]interface{}, ctx cont
 			fmt.Println("\n" + separador)
 			fmt.Println("📊 ESTADÍSTICAS DEL SISTEMA")
 			fmt.Println(separador)
-			fmt.Printf("Total eventos: %v\n", stats["eventos_total"])
-			fmt.Printf("Eventos error: %v\n", stats["eventos_error"])
-			fmt.Printf("Tasa de error: %.2f%%\n", stats["tasa_error"])
-			fmt.Printf("Último evento: %v\n", stats["ultimo_evento"])
-
-			if porTipo, ok := stats["eventos_por_tipo"].(map[TipoEvento]int64); ok {
-				fmt.Println("\nEventos por tipo:")
-				for tipo, count := range porTipo {
-					fmt.Printf("  %s: %d\n", tipo, count)
-				}
+			fmt.Printf("Total eventos: %d\n", stats.EventosTotal)
+			fmt.Printf("Eventos error: %d\n", stats.EventosError)
+			fmt.Printf("Tasa de error: %.2f%%\n", stats.TasaError)
+			fmt.Printf("Último evento: %s\n", stats.UltimoEvento.Format("15:04:05"))
+
+			fmt.Println("\nEventos por tipo:")
+			for tipo, count := range stats.PorTipo {
+				fmt.Printf("  %s: %d\n", tipo, count)
+			}
+
+			fmt.Println("\nÚltima ventana (n / min / promedio / p95 / max):")
+			for _, r := range stats.Series {
+				imprimirResumenSerie(r)
 			}
 			fmt.Println(separador + "\n")
 		}
 	}
 }
 
+func imprimirResumenSerie(r ResumenSerie) {
+	aproximado := ""
+	if r.Aproximado {
+		aproximado = " ≈"
+	}
+	fmt.Printf("  %s/%s: %d / %.2f / %.2f / %.2f / %.2f%s\n",
+		r.Tipo, r.Servicio, r.Cuenta, r.Min, r.Promedio, r.P95, r.Max, aproximado)
+}
+
 // ==============================================
 // SISTEMA PRINCIPAL
 // ==============================================
 
-func NewSistemaMonitoreo() *SistemaMonitoreo {
+func NewSistemaMonitoreo(colectores []Colector, reglas *MotorReglas, incidentes *GestorIncidentes, desborde *ColaDisco, series *AlmacenSeries) *SistemaMonitoreo {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &SistemaMonitoreo{
 		eventos:      make(chan Evento, 100),
-		alertas:      make(chan Evento, 50),
-		estadisticas: make(chan map[string]interface{}, 10),
+		alertas:      make(chan Alerta, 50),
+		colectores:   colectores,
+		reglas:       reglas,
+		incidentes:   incidentes,
+		desborde:     desborde,
+		estadisticas: make(chan Estadisticas, 10),
 		quit:         make(chan bool),
 		metricas: &Metricas{
 			eventosPorTipo: make(map[TipoEvento]int64),
+			series:         series,
 		},
 		ctx:    ctx,
 		cancel: cancel,
@@ -409,29 +391,55 @@ func (s *SistemaMonitoreo) Iniciar() {
 	fmt.Println("🚀 Iniciando Sistema de Monitoreo")
 	fmt.Println("==================================")
 
-	// Lanzar generadores de eventos
-	go generadorCPU(s.eventos, s.ctx)
-	go generadorMemoria(s.eventos, s.ctx)
-	go generadorRed(s.eventos, s.ctx)
-	go generadorErrores(s.eventos, s.ctx)
+	// Lanzar colectores de eventos
+	for _, colector := range s.colectores {
+		s.lanzar(func() { ejecutarColector(colector, s.eventos, s.ctx) })
+	}
 
 	// Lanzar procesadores
-	go procesadorEventos(s)
-	go procesadorAlertas(s.alertas, s.ctx)
-	go generadorEstadisticas(s)
-	go procesadorEstadisticas(s.estadisticas, s.ctx)
+	s.lanzar(func() { procesadorEventos(s) })
+	s.lanzar(func() { procesadorAlertas(s.alertas, s.incidentes, s.ctx) })
+	s.lanzar(s.vaciarDesborde)
+	s.lanzar(func() { s.incidentes.VigilarEscalado(s.ctx) })
+	s.lanzar(s.barrerSeries)
+	s.lanzar(s.caducarReglas)
+	s.lanzar(func() { generadorEstadisticas(s) })
+	s.lanzar(func() { procesadorEstadisticas(s.estadisticas, s.ctx) })
 
 	fmt.Println("✅ Todos los componentes iniciados")
 }
 
+// lanzar arranca una goroutine que Detener espera antes de cerrar los channels
+func (s *SistemaMonitoreo) lanzar(f func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		f()
+	}()
+}
+
 func (s *SistemaMonitoreo) Detener() {
 	fmt.Println("\n🛑 Iniciando shutdown del sistema...")
 
 	// Cancelar context para señalar a todas las goroutines
 	s.cancel()
 
-	// Dar tiempo para que terminen limpiamente
-	time.Sleep(500 * time.Millisecond)
+	// Esperar a que terminen: nadie envía ya a los channels que se cierran
+	s.wg.Wait()
+
+	// Las alertas que quedaron en el canal se guardan para la próxima ejecución
+	guardadas := 0
+	for len(s.alertas) > 0 {
+		if err := s.desborde.Guardar(<-s.alertas); err != nil {
+			fmt.Printf("❌ Alerta perdida al apagar: %v\n", err)
+			continue
+		}
+		guardadas++
+	}
+	if pendientes := s.desborde.Pendientes(); pendientes > 0 {
+		fmt.Printf("💾 %d alertas pendientes en disco (%d del canal)\n", pendientes, guardadas)
+	}
+	s.desborde.Cerrar()
 
 	// Cerrar channels
 	close(s.eventos)
@@ -465,6 +473,33 @@ func (s *SistemaMonitoreo) EstadisticasFinales() {
 	}
 	s.metricas.mu.RUnlock()
 
+	fmt.Println("\nAlertas por regla (enviadas/silenciadas/inhibidas):")
+	contadores := s.reglas.Contadores()
+	nombres := make([]string, 0, len(contadores))
+	for nombre := range contadores {
+		nombres = append(nombres, nombre)
+	}
+	sort.Strings(nombres)
+	for _, nombre := range nombres {
+		c := contadores[nombre]
+		fmt.Printf("  %s: %d/%d/%d\n", nombre, c.Disparadas, c.Silenciadas, c.Inhibidas)
+	}
+
+	fmt.Println("\nSeries retenidas (n / min / promedio / p95 / max, ≈ incluye bloques compactados):")
+	for _, id := range s.metricas.series.Series() {
+		if resumen, ok := s.metricas.series.Consultar(id, time.Time{}, time.Now()); ok {
+			imprimirResumenSerie(resumen)
+		}
+	}
+
+	incidentes := s.incidentes.Resumen()
+	fmt.Printf("\nIncidentes: %d activos, %d resueltos, %d alertas agrupadas, %d desbordadas a disco\n",
+		len(incidentes.Activos), len(incidentes.Resueltos), incidentes.Agrupadas, s.desborde.Escritas())
+	for _, inc := range incidentes.Activos {
+		fmt.Printf("  %s %s %s [%s/%s]: %d disparos desde %s\n", inc.Severidad.Icono(), inc.Huella, inc.Regla,
+			inc.Tipo, inc.Servicio, inc.Disparos, inc.Abierto.Format("15:04:05"))
+	}
+
 	fmt.Println(separador)
 }
 
@@ -472,6 +507,27 @@ func (s *SistemaMonitoreo) EstadisticasFinales() {
 // FUNCIÓN PRINCIPAL DEL PROYECTO
 // ==============================================
 
+// rutaDesborde usa MONITOREO_DESBORDE si está definida y si no el directorio
+// de caché del usuario, para que lo pendiente sobreviva al reinicio sin
+// compartir un archivo en /tmp con otras ejecuciones. Sin caché se usa un
+// archivo temporal propio de esta ejecución.
+func rutaDesborde() (string, error) {
+	if ruta := os.Getenv("MONITOREO_DESBORDE"); ruta != "" {
+		return ruta, nil
+	}
+	if cache, err := os.UserCacheDir(); err == nil {
+		directorio := filepath.Join(cache, "go-deep")
+		if err := os.MkdirAll(directorio, 0o755); err == nil {
+			return filepath.Join(directorio, "monitoreo-alertas-desborde.jsonl"), nil
+		}
+	}
+	archivo, err := os.CreateTemp("", "monitoreo-alertas-desborde-*.jsonl")
+	if err != nil {
+		return "", err
+	}
+	return archivo.Name(), archivo.Close()
+}
+
 func EjecutarProyectoMonitoreo() {
 	fmt.Println("📡 PROYECTO: Sistema de Monitoreo con Channels")
 	fmt.Println("==============================================")
@@ -486,8 +542,87 @@ func EjecutarProyectoMonitoreo() {
 
 	rand.Seed(time.Now().UnixNano())
 
+	// Fuente de métricas: MONITOREO_FUENTE=host lee /proc y el runtime; por
+	// defecto se simulan
+	colectores := ColectoresSimulados()
+	if os.Getenv("MONITOREO_FUENTE") == "host" {
+		colectores = ColectoresHost("/proc")
+	}
+	nombres := make([]string, len(colectores))
+	for i, colector := range colectores {
+		nombres[i] = colector.Nombre()
+	}
+	fmt.Printf("🔌 Colectores: %s\n", strings.Join(nombres, ", "))
+
+	// Cargar reglas de alerta; sin archivo se usan los umbrales de siempre
+	config, err := CargarReglas("reglas_monitoreo.json")
+	if err != nil {
+		fmt.Printf("⚠️ No se pudieron cargar las reglas (%v), usando las de por defecto\n", err)
+		config = ReglasPorDefecto()
+	}
+	reglas := NewMotorReglas(config)
+	fmt.Println("📏 Reglas de alerta:")
+	for _, descripcion := range reglas.Descripciones() {
+		fmt.Printf("   %s\n", descripcion)
+	}
+	fmt.Println()
+
+	// Incidentes: los warning los reconoce un operador simulado; los que
+	// nadie reconoce se escalan
+	var incidentes *GestorIncidentes
+	incidentes = NewGestorIncidentes(ConfigIncidentes{
+		EscalarTras:     3 * time.Second,
+		ExpirarTras:     5 * time.Second,
+		RevisarCada:     500 * time.Millisecond,
+		HistorialMaximo: 100,
+	}, func(n Notificacion) {
+		imprimirNotificacion(n)
+		if n.Tipo == NotificacionAbierto && n.Incidente.Severidad != SeveridadCritical {
+			huella := n.Incidente.Huella
+			time.AfterFunc(time.Second, func() {
+				if incidentes.Reconocer(huella) {
+					fmt.Printf("👤 Operador reconoce el incidente %s\n", huella)
+				}
+			})
+		}
+	})
+
+	// Las alertas que no caben en el canal esperan en disco
+	ruta, err := rutaDesborde()
+	if err != nil {
+		fmt.Printf("❌ No se pudo preparar la cola de desborde: %v\n", err)
+		return
+	}
+	desborde, err := AbrirColaDisco(ruta, 1000)
+	if err != nil {
+		fmt.Printf("❌ No se pudo abrir la cola de desborde: %v\n", err)
+		return
+	}
+	if pendientes := desborde.Pendientes(); pendientes > 0 {
+		fmt.Printf("💾 %d alertas pendientes recuperadas del disco\n", pendientes)
+	}
+
+	// Series: 5s de puntos originales y después bloques de 1s durante 10
+	// minutos; los errores son pocos y se guardan sin compactar
+	series := NewAlmacenSeries(ConfigSeries{
+		PorDefecto: Retencion{Cruda: 5 * time.Second, Bloque: time.Second, Agregada: 10 * time.Minute},
+		PorTipo: map[TipoEvento]Retencion{
+			EventoError: {Cruda: time.Hour},
+		},
+	})
+
 	// Crear y configurar sistema
-	sistema := NewSistemaMonitoreo()
+	sistema := NewSistemaMonitoreo(colectores, reglas, incidentes, desborde, series)
+
+	direccion := os.Getenv("MONITOREO_ADDR")
+	if direccion == "" {
+		direccion = "localhost:2112"
+	}
+	if real, err := sistema.ServirMetricas(direccion); err != nil {
+		fmt.Printf("⚠️ Sin servidor de métricas: %v\n", err)
+	} else {
+		fmt.Printf("📡 Métricas en http://%s/metrics y consultas en http://%s/api/consulta?tipo=CPU&servicio=sistema\n", real, real)
+	}
 
 	// Iniciar sistema
 	sistema.Iniciar()
@@ -510,6 +645,10 @@ func EjecutarProyectoMonitoreo() {
 	fmt.Println("   🔄 Productores y consumidores concurrentes")
 	fmt.Println("   📊 Agregación de datos en tiempo real")
 	fmt.Println("   🚨 Sistema de alertas con channels")
+	fmt.Println("   📏 Motor de reglas configurable con silencios e inhibiciones")
+	fmt.Println("   🧾 Incidentes con agrupación, escalado y desborde a disco")
+	fmt.Println("   🔌 Colectores intercambiables: simulados, /proc y runtime/metrics")
+	fmt.Println("   🗃️ Series temporales con retención, endpoint Prometheus y consultas p95")
 	fmt.Println("   🛑 Shutdown elegante con context")
 	fmt.Println("   ⚡ Performance con buffering estratégico")
 
@@ -0,0 +1,532 @@
+// ==============================================
+// LECCIÓN 14: Channels - Proyecto Sistema de Monitoreo: Colectores
+// ==============================================
+// Métricas reales del host leídas de /proc (stat, meminfo, net/dev,
+// diskstats) y del runtime de Go, detrás de la misma interfaz que los
+// generadores simulados
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime/metrics"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ==============================================
+// INTERFAZ COMÚN
+// ==============================================
+
+// Colector produce eventos en cada intervalo. Recolectar recibe la hora de
+// la muestra para que las tasas se calculen igual en producción y en tests;
+// los colectores que calculan diferencias no emiten nada en la primera.
+type Colector interface {
+	Nombre() string
+	Intervalo() time.Duration
+	Recolectar(ahora time.Time) ([]Evento, error)
+}
+
+// ejecutarColector publica los eventos del colector hasta que se cancela ctx
+func ejecutarColector(colector Colector, eventos chan<- Evento, ctx context.Context) {
+	ticker := time.NewTicker(colector.Intervalo())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Printf("🔄 Colector %s terminando...\n", colector.Nombre())
+			return
+		case ahora := <-ticker.C:
+			lote, err := colector.Recolectar(ahora)
+			if err != nil {
+				fmt.Printf("⚠️ Colector %s: %v\n", colector.Nombre(), err)
+				continue
+			}
+			for _, evento := range lote {
+				select {
+				case eventos <- evento:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// ColectoresHost lee el host a través de raizProc (normalmente "/proc") y
+// el runtime del propio proceso. Los errores siguen siendo simulados: el
+// host no tiene un equivalente.
+func ColectoresHost(raizProc string) []Colector {
+	return []Colector{
+		NewColectorCPU(raizProc),
+		NewColectorMemoria(raizProc),
+		NewColectorRed(raizProc),
+		NewColectorDisco(raizProc),
+		NewColectorRuntime(),
+		NewColectorErroresSimulado(),
+	}
+}
+
+// ==============================================
+// CPU: /proc/stat
+// ==============================================
+
+type tiemposCPU struct {
+	ocupado uint64
+	total   uint64
+}
+
+type ColectorCPU struct {
+	raiz     string
+	anterior tiemposCPU
+	hay      bool
+}
+
+func NewColectorCPU(raizProc string) *ColectorCPU {
+	return &ColectorCPU{raiz: raizProc}
+}
+
+func (c *ColectorCPU) Nombre() string           { return "cpu" }
+func (c *ColectorCPU) Intervalo() time.Duration { return time.Second }
+
+// Recolectar emite el uso de CPU agregado entre esta muestra y la anterior
+func (c *ColectorCPU) Recolectar(ahora time.Time) ([]Evento, error) {
+	datos, err := os.ReadFile(filepath.Join(c.raiz, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	actual, err := parsearStat(datos)
+	if err != nil {
+		return nil, err
+	}
+
+	anterior, hay := c.anterior, c.hay
+	c.anterior, c.hay = actual, true
+	// iowait puede retroceder entre lecturas, y con él el tiempo ocupado: esa
+	// muestra se descarta en vez de restar enteros sin signo
+	if !hay || actual.total <= anterior.total || actual.ocupado < anterior.ocupado {
+		return nil, nil
+	}
+
+	uso := float64(actual.ocupado-anterior.ocupado) / float64(actual.total-anterior.total) * 100
+	return []Evento{{
+		Timestamp: ahora,
+		Tipo:      EventoCPU,
+		Servicio:  "sistema",
+		Valor:     uso,
+		Metadata: map[string]string{
+			"unidad": "porcentaje",
+			"core":   "total",
+			"fuente": "/proc/stat",
+		},
+	}}, nil
+}
+
+// parsearStat lee la línea "cpu" agregada. guest y guest_nice ya están
+// incluidos en user y nice, así que no se suman; iowait cuenta como ocioso.
+func parsearStat(datos []byte) (tiemposCPU, error) {
+	for _, linea := range strings.Split(string(datos), "\n") {
+		campos := strings.Fields(linea)
+		if len(campos) == 0 || campos[0] != "cpu" {
+			continue
+		}
+		if len(campos) < 5 {
+			return tiemposCPU{}, fmt.Errorf("stat: línea cpu incompleta: %q", linea)
+		}
+
+		var valores [8]uint64
+		for i := range valores {
+			if i+1 >= len(campos) {
+				break // kernels antiguos no tienen steal
+			}
+			v, err := strconv.ParseUint(campos[i+1], 10, 64)
+			if err != nil {
+				return tiemposCPU{}, fmt.Errorf("stat: %w", err)
+			}
+			valores[i] = v
+		}
+
+		var t tiemposCPU
+		for _, v := range valores {
+			t.total += v
+		}
+		ocioso := valores[3] + valores[4] // idle + iowait
+		t.ocupado = t.total - ocioso
+		return t, nil
+	}
+	return tiemposCPU{}, fmt.Errorf("stat: falta la línea cpu")
+}
+
+// ==============================================
+// MEMORIA: /proc/meminfo
+// ==============================================
+
+type ColectorMemoria struct {
+	raiz string
+}
+
+func NewColectorMemoria(raizProc string) *ColectorMemoria {
+	return &ColectorMemoria{raiz: raizProc}
+}
+
+func (c *ColectorMemoria) Nombre() string           { return "memoria" }
+func (c *ColectorMemoria) Intervalo() time.Duration { return time.Second }
+
+// Recolectar emite la memoria en uso (total menos disponible) en MB
+func (c *ColectorMemoria) Recolectar(ahora time.Time) ([]Evento, error) {
+	datos, err := os.ReadFile(filepath.Join(c.raiz, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	campos, err := parsearMeminfo(datos)
+	if err != nil {
+		return nil, err
+	}
+	total, okTotal := campos["MemTotal"]
+	disponible, okDisponible := campos["MemAvailable"]
+	if !okTotal || !okDisponible {
+		return nil, fmt.Errorf("meminfo: faltan MemTotal o MemAvailable")
+	}
+
+	return []Evento{{
+		Timestamp: ahora,
+		Tipo:      EventoMemoria,
+		Servicio:  "sistema",
+		Valor:     float64(total-disponible) / 1024,
+		Metadata: map[string]string{
+			"unidad":   "MB",
+			"tipo":     "RAM",
+			"total_mb": strconv.FormatUint(total/1024, 10),
+			"fuente":   "/proc/meminfo",
+		},
+	}}, nil
+}
+
+// parsearMeminfo devuelve los campos de meminfo en kB
+func parsearMeminfo(datos []byte) (map[string]uint64, error) {
+	campos := make(map[string]uint64)
+	escaner := bufio.NewScanner(bytes.NewReader(datos))
+	for escaner.Scan() {
+		nombre, resto, ok := strings.Cut(escaner.Text(), ":")
+		if !ok {
+			continue
+		}
+		valor := strings.Fields(resto)
+		if len(valor) == 0 {
+			continue
+		}
+		v, err := strconv.ParseUint(valor[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("meminfo %s: %w", nombre, err)
+		}
+		campos[nombre] = v
+	}
+	return campos, escaner.Err()
+}
+
+// ==============================================
+// RED: /proc/net/dev
+// ==============================================
+
+type bytesInterfaz struct {
+	recibidos uint64
+	enviados  uint64
+}
+
+type ColectorRed struct {
+	raiz     string
+	anterior map[string]bytesInterfaz
+	cuando   time.Time
+}
+
+func NewColectorRed(raizProc string) *ColectorRed {
+	return &ColectorRed{raiz: raizProc}
+}
+
+func (c *ColectorRed) Nombre() string           { return "red" }
+func (c *ColectorRed) Intervalo() time.Duration { return time.Second }
+
+// Recolectar emite el throughput de cada interfaz (sin loopback) en Mbps
+func (c *ColectorRed) Recolectar(ahora time.Time) ([]Evento, error) {
+	datos, err := os.ReadFile(filepath.Join(c.raiz, "net", "dev"))
+	if err != nil {
+		return nil, err
+	}
+	actual, err := parsearNetDev(datos)
+	if err != nil {
+		return nil, err
+	}
+
+	anterior, cuando := c.anterior, c.cuando
+	c.anterior, c.cuando = actual, ahora
+	segundos := ahora.Sub(cuando).Seconds()
+	if anterior == nil || segundos <= 0 {
+		return nil, nil
+	}
+
+	var lote []Evento
+	for _, nombre := range slices.Sorted(maps.Keys(actual)) {
+		antes, existia := anterior[nombre]
+		ahoraBytes := actual[nombre]
+		if nombre == "lo" || !existia || ahoraBytes.recibidos < antes.recibidos || ahoraBytes.enviados < antes.enviados {
+			continue // interfaz nueva o contador reiniciado
+		}
+		delta := (ahoraBytes.recibidos - antes.recibidos) + (ahoraBytes.enviados - antes.enviados)
+		lote = append(lote, Evento{
+			Timestamp: ahora,
+			Tipo:      EventoRed,
+			Servicio:  "networking",
+			Valor:     float64(delta) * 8 / 1e6 / segundos,
+			Metadata: map[string]string{
+				"unidad":    "Mbps",
+				"interface": nombre,
+				"fuente":    "/proc/net/dev",
+			},
+		})
+	}
+	return lote, nil
+}
+
+// parsearNetDev lee bytes recibidos (columna 1) y enviados (columna 9) por
+// interfaz; las dos primeras líneas son cabeceras
+func parsearNetDev(datos []byte) (map[string]bytesInterfaz, error) {
+	interfaces := make(map[string]bytesInterfaz)
+	lineas := strings.Split(string(datos), "\n")
+	for _, linea := range lineas[min(2, len(lineas)):] {
+		nombre, resto, ok := strings.Cut(linea, ":")
+		if !ok {
+			continue
+		}
+		campos := strings.Fields(resto)
+		if len(campos) < 9 {
+			return nil, fmt.Errorf("net/dev: línea incompleta: %q", linea)
+		}
+		recibidos, err := strconv.ParseUint(campos[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("net/dev: %w", err)
+		}
+		enviados, err := strconv.ParseUint(campos[8], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("net/dev: %w", err)
+		}
+		interfaces[strings.TrimSpace(nombre)] = bytesInterfaz{recibidos: recibidos, enviados: enviados}
+	}
+	return interfaces, nil
+}
+
+// ==============================================
+// DISCO: /proc/diskstats
+// ==============================================
+
+// bytesPorSector es fijo en diskstats, sea cual sea el sector físico
+const bytesPorSector = 512
+
+type sectoresDisco struct {
+	leidos   uint64
+	escritos uint64
+}
+
+type ColectorDisco struct {
+	raiz     string
+	anterior map[string]sectoresDisco
+	cuando   time.Time
+}
+
+func NewColectorDisco(raizProc string) *ColectorDisco {
+	return &ColectorDisco{raiz: raizProc}
+}
+
+func (c *ColectorDisco) Nombre() string           { return "disco" }
+func (c *ColectorDisco) Intervalo() time.Duration { return time.Second }
+
+// Recolectar emite el throughput (lectura + escritura) de cada disco en MB/s
+func (c *ColectorDisco) Recolectar(ahora time.Time) ([]Evento, error) {
+	datos, err := os.ReadFile(filepath.Join(c.raiz, "diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	actual, err := parsearDiskstats(datos)
+	if err != nil {
+		return nil, err
+	}
+
+	anterior, cuando := c.anterior, c.cuando
+	c.anterior, c.cuando = actual, ahora
+	segundos := ahora.Sub(cuando).Seconds()
+	if anterior == nil || segundos <= 0 {
+		return nil, nil
+	}
+
+	var lote []Evento
+	for _, nombre := range slices.Sorted(maps.Keys(actual)) {
+		antes, existia := anterior[nombre]
+		despues := actual[nombre]
+		if !existia || despues.leidos < antes.leidos || despues.escritos < antes.escritos {
+			continue
+		}
+		leidos := float64(despues.leidos-antes.leidos) * bytesPorSector / 1e6 / segundos
+		escritos := float64(despues.escritos-antes.escritos) * bytesPorSector / 1e6 / segundos
+		lote = append(lote, Evento{
+			Timestamp: ahora,
+			Tipo:      EventoDisco,
+			Servicio:  "almacenamiento",
+			Valor:     leidos + escritos,
+			Metadata: map[string]string{
+				"unidad":      "MB/s",
+				"dispositivo": nombre,
+				"lectura":     strconv.FormatFloat(leidos, 'f', 2, 64),
+				"escritura":   strconv.FormatFloat(escritos, 'f', 2, 64),
+				"fuente":      "/proc/diskstats",
+			},
+		})
+	}
+	return lote, nil
+}
+
+// parsearDiskstats devuelve los sectores leídos (campo 6) y escritos
+// (campo 10) de cada disco. Se descartan loop y ram, y las particiones cuyo
+// disco también aparece (sda1 con sda, nvme0n1p1 con nvme0n1) para no contar
+// dos veces el mismo tráfico.
+func parsearDiskstats(datos []byte) (map[string]sectoresDisco, error) {
+	discos := make(map[string]sectoresDisco)
+	for _, linea := range strings.Split(string(datos), "\n") {
+		campos := strings.Fields(linea)
+		if len(campos) == 0 {
+			continue
+		}
+		if len(campos) < 10 {
+			return nil, fmt.Errorf("diskstats: línea incompleta: %q", linea)
+		}
+		nombre := campos[2]
+		if strings.HasPrefix(nombre, "loop") || strings.HasPrefix(nombre, "ram") {
+			continue
+		}
+		leidos, err := strconv.ParseUint(campos[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("diskstats: %w", err)
+		}
+		escritos, err := strconv.ParseUint(campos[9], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("diskstats: %w", err)
+		}
+		discos[nombre] = sectoresDisco{leidos: leidos, escritos: escritos}
+	}
+
+	for nombre := range discos {
+		for otro := range discos {
+			if esParticion(nombre, otro) {
+				delete(discos, nombre)
+				break
+			}
+		}
+	}
+	return discos, nil
+}
+
+// esParticion reconoce sda1 de sda y nvme0n1p1 de nvme0n1: si el disco
+// termina en dígito, la partición lleva una "p" delante del número
+func esParticion(nombre, disco string) bool {
+	resto, ok := strings.CutPrefix(nombre, disco)
+	if !ok || resto == "" {
+		return false
+	}
+	if ultimo := disco[len(disco)-1]; ultimo >= '0' && ultimo <= '9' {
+		if resto, ok = strings.CutPrefix(resto, "p"); !ok || resto == "" {
+			return false
+		}
+	}
+	return strings.Trim(resto, "0123456789") == ""
+}
+
+// ==============================================
+// RUNTIME DE GO: runtime/metrics
+// ==============================================
+
+const (
+	metricaCPUTotal   = "/cpu/classes/total:cpu-seconds"
+	metricaCPUOcioso  = "/cpu/classes/idle:cpu-seconds"
+	metricaMemoria    = "/memory/classes/total:bytes"
+	metricaHeap       = "/memory/classes/heap/objects:bytes"
+	metricaGoroutines = "/sched/goroutines:goroutines"
+	metricaCiclosGC   = "/gc/cycles/total:gc-cycles"
+)
+
+// ColectorRuntime describe el propio proceso. Las métricas de CPU del
+// runtime son estimaciones que se actualizan en cada GC, así que el uso solo
+// se emite cuando avanzaron.
+type ColectorRuntime struct {
+	muestras []metrics.Sample
+	anterior tiemposCPU
+	hay      bool
+}
+
+func NewColectorRuntime() *ColectorRuntime {
+	nombres := []string{metricaCPUTotal, metricaCPUOcioso, metricaMemoria, metricaHeap, metricaGoroutines, metricaCiclosGC}
+	muestras := make([]metrics.Sample, len(nombres))
+	for i, nombre := range nombres {
+		muestras[i].Name = nombre
+	}
+	return &ColectorRuntime{muestras: muestras}
+}
+
+func (c *ColectorRuntime) Nombre() string           { return "runtime" }
+func (c *ColectorRuntime) Intervalo() time.Duration { return time.Second }
+
+func (c *ColectorRuntime) Recolectar(ahora time.Time) ([]Evento, error) {
+	metrics.Read(c.muestras)
+	valores := make(map[string]float64, len(c.muestras))
+	for _, muestra := range c.muestras {
+		switch muestra.Value.Kind() {
+		case metrics.KindUint64:
+			valores[muestra.Name] = float64(muestra.Value.Uint64())
+		case metrics.KindFloat64:
+			valores[muestra.Name] = muestra.Value.Float64()
+		default:
+			return nil, fmt.Errorf("runtime/metrics: %s no está disponible", muestra.Name)
+		}
+	}
+
+	lote := []Evento{{
+		Timestamp: ahora,
+		Tipo:      EventoMemoria,
+		Servicio:  "runtime",
+		Valor:     valores[metricaMemoria] / (1 << 20),
+		Metadata: map[string]string{
+			"unidad":     "MB",
+			"tipo":       "runtime",
+			"heap_mb":    strconv.FormatFloat(valores[metricaHeap]/(1<<20), 'f', 2, 64),
+			"goroutines": strconv.FormatFloat(valores[metricaGoroutines], 'f', 0, 64),
+			"ciclos_gc":  strconv.FormatFloat(valores[metricaCiclosGC], 'f', 0, 64),
+			"fuente":     "runtime/metrics",
+		},
+	}}
+
+	// Los segundos de CPU se escalan a nanosegundos para reutilizar tiemposCPU
+	total := valores[metricaCPUTotal] * 1e9
+	actual := tiemposCPU{total: uint64(total), ocupado: uint64(total - valores[metricaCPUOcioso]*1e9)}
+	anterior, hay := c.anterior, c.hay
+	c.anterior, c.hay = actual, true
+	if hay && actual.total > anterior.total && actual.ocupado >= anterior.ocupado {
+		lote = append(lote, Evento{
+			Timestamp: ahora,
+			Tipo:      EventoCPU,
+			Servicio:  "runtime",
+			Valor:     float64(actual.ocupado-anterior.ocupado) / float64(actual.total-anterior.total) * 100,
+			Metadata: map[string]string{
+				"unidad": "porcentaje",
+				"core":   "GOMAXPROCS",
+				"fuente": "runtime/metrics",
+			},
+		})
+	}
+	return lote, nil
+}
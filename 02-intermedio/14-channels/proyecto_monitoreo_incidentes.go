@@ -0,0 +1,541 @@
+// ==============================================
+// LECCIÓN 14: Channels - Proyecto Sistema de Monitoreo: Incidentes
+// ==============================================
+// Alertas agrupadas en incidentes por huella (tipo/servicio/regla), con
+// resolución, escalado de incidentes sin reconocer y una cola en disco para
+// que un canal de alertas lleno no pierda nada
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// INCIDENTES
+// ==============================================
+
+// Huella identifica las alertas que pertenecen al mismo problema
+func (a Alerta) Huella() string {
+	suma := sha256.Sum256([]byte(a.Evento.Tipo.String() + "\x00" + a.Servicio + "\x00" + a.Regla))
+	return hex.EncodeToString(suma[:8])
+}
+
+type EstadoIncidente string
+
+const (
+	IncidenteAbierto    EstadoIncidente = "abierto"
+	IncidenteEscalado   EstadoIncidente = "escalado"
+	IncidenteResuelto   EstadoIncidente = "resuelto"
+	IncidenteReconocido EstadoIncidente = "reconocido"
+)
+
+type Incidente struct {
+	Huella      string
+	Regla       string
+	Tipo        TipoEvento
+	Servicio    string
+	Severidad   Severidad
+	Descripcion string
+	Estado      EstadoIncidente
+	Disparos    int64 // alertas agrupadas en el incidente
+	UltimoValor float64
+	Abierto     time.Time // instante del evento que lo abrió
+	Ultimo      time.Time // instante del último evento agrupado
+	Reconocido  bool
+
+	// Recibido y UltimaRecepcion usan el reloj del gestor y no el del
+	// evento: una alerta reinyectada desde el disco trae un Timestamp
+	// antiguo, y contar desde él la escalaría nada más llegar
+	Recibido        time.Time
+	UltimaRecepcion time.Time
+}
+
+type TipoNotificacion string
+
+const (
+	NotificacionAbierto  TipoNotificacion = "abierto"
+	NotificacionEscalado TipoNotificacion = "escalado"
+	NotificacionResuelto TipoNotificacion = "resuelto"
+)
+
+type Notificacion struct {
+	Tipo      TipoNotificacion
+	Incidente Incidente // copia del estado al notificar
+}
+
+type ConfigIncidentes struct {
+	EscalarTras     time.Duration // tiempo abierto sin reconocer antes de escalar
+	RevisarCada     time.Duration // frecuencia de la revisión de escalados y caducados
+	HistorialMaximo int           // incidentes resueltos que se conservan
+
+	// ExpirarTras resuelve un incidente que lleva ese tiempo sin recibir
+	// alertas. Mientras una regla dispara, cada evento que la cumple envía
+	// una alerta; si dejan de llegar sin resolución es porque la serie ya
+	// no informa o porque el incidente vino del disco de una ejecución
+	// anterior, cuyo estado de reglas no se conserva y nunca lo resolverá.
+	ExpirarTras time.Duration
+}
+
+// GestorIncidentes agrupa las alertas repetidas en un único incidente y
+// notifica solo los cambios de estado: apertura, escalado y resolución
+type GestorIncidentes struct {
+	config    ConfigIncidentes
+	notificar func(Notificacion)
+	activos   map[string]*Incidente
+	resueltos []Incidente
+	agrupadas int64 // alertas absorbidas por un incidente ya abierto
+	mu        sync.Mutex
+}
+
+func NewGestorIncidentes(config ConfigIncidentes, notificar func(Notificacion)) *GestorIncidentes {
+	if config.RevisarCada <= 0 {
+		config.RevisarCada = time.Second
+	}
+	return &GestorIncidentes{
+		config:    config,
+		notificar: notificar,
+		activos:   make(map[string]*Incidente),
+	}
+}
+
+// Procesar incorpora una alerta; las notificaciones se emiten fuera del lock
+func (g *GestorIncidentes) Procesar(alerta Alerta) {
+	g.mu.Lock()
+	notificacion, hay := g.procesar(alerta, time.Now())
+	g.mu.Unlock()
+
+	if hay {
+		g.notificar(notificacion)
+	}
+}
+
+func (g *GestorIncidentes) procesar(alerta Alerta, ahora time.Time) (Notificacion, bool) {
+	huella := alerta.Huella()
+	incidente, existe := g.activos[huella]
+
+	if alerta.Resuelta {
+		if !existe {
+			return Notificacion{}, false
+		}
+		incidente.Ultimo = alerta.Evento.Timestamp
+		incidente.UltimaRecepcion = ahora
+		return Notificacion{NotificacionResuelto, g.resolver(incidente)}, true
+	}
+
+	if existe {
+		incidente.Disparos++
+		incidente.UltimoValor = alerta.Valor
+		incidente.Ultimo = alerta.Evento.Timestamp
+		incidente.UltimaRecepcion = ahora
+		g.agrupadas++
+		return Notificacion{}, false
+	}
+
+	incidente = &Incidente{
+		Huella:      huella,
+		Regla:       alerta.Regla,
+		Tipo:        alerta.Evento.Tipo,
+		Servicio:    alerta.Servicio,
+		Severidad:   alerta.Severidad,
+		Descripcion: alerta.Descripcion,
+		Estado:      IncidenteAbierto,
+		Disparos:    1,
+		UltimoValor: alerta.Valor,
+		Abierto:     alerta.Evento.Timestamp,
+		Ultimo:      alerta.Evento.Timestamp,
+
+		Recibido:        ahora,
+		UltimaRecepcion: ahora,
+	}
+	g.activos[huella] = incidente
+	return Notificacion{NotificacionAbierto, *incidente}, true
+}
+
+// resolver pasa el incidente al historial y devuelve su copia final
+func (g *GestorIncidentes) resolver(incidente *Incidente) Incidente {
+	incidente.Estado = IncidenteResuelto
+	delete(g.activos, incidente.Huella)
+	g.resueltos = append(g.resueltos, *incidente)
+	if g.config.HistorialMaximo > 0 && len(g.resueltos) > g.config.HistorialMaximo {
+		g.resueltos = g.resueltos[len(g.resueltos)-g.config.HistorialMaximo:]
+	}
+	return *incidente
+}
+
+// Reconocer marca un incidente como atendido, lo que detiene su escalado
+func (g *GestorIncidentes) Reconocer(huella string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	incidente, existe := g.activos[huella]
+	if !existe {
+		return false
+	}
+	incidente.Reconocido = true
+	if incidente.Estado == IncidenteAbierto {
+		incidente.Estado = IncidenteReconocido
+	}
+	return true
+}
+
+// VigilarEscalado escala una sola vez cada incidente que sigue sin
+// reconocer pasado EscalarTras, y resuelve los que caducan por ExpirarTras
+func (g *GestorIncidentes) VigilarEscalado(ctx context.Context) {
+	if g.config.EscalarTras <= 0 && g.config.ExpirarTras <= 0 {
+		return
+	}
+	ticker := time.NewTicker(g.config.RevisarCada)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ahora := <-ticker.C:
+			for _, notificacion := range g.escalar(ahora) {
+				g.notificar(notificacion)
+			}
+		}
+	}
+}
+
+// escalar devuelve primero las resoluciones por caducidad y después los
+// escalados, cada grupo por orden de recepción
+func (g *GestorIncidentes) escalar(ahora time.Time) []Notificacion {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	activos := make([]*Incidente, 0, len(g.activos))
+	for _, incidente := range g.activos {
+		activos = append(activos, incidente)
+	}
+	sort.Slice(activos, func(i, j int) bool { return activos[i].Recibido.Before(activos[j].Recibido) })
+
+	var resueltas, escaladas []Notificacion
+	for _, incidente := range activos {
+		switch {
+		case g.config.ExpirarTras > 0 && ahora.Sub(incidente.UltimaRecepcion) >= g.config.ExpirarTras:
+			resueltas = append(resueltas, Notificacion{NotificacionResuelto, g.resolver(incidente)})
+		case g.config.EscalarTras > 0 && incidente.Estado == IncidenteAbierto &&
+			ahora.Sub(incidente.Recibido) >= g.config.EscalarTras:
+			incidente.Estado = IncidenteEscalado
+			escaladas = append(escaladas, Notificacion{NotificacionEscalado, *incidente})
+		}
+	}
+	return append(resueltas, escaladas...)
+}
+
+type ResumenIncidentes struct {
+	Activos   []Incidente
+	Resueltos []Incidente
+	Agrupadas int64
+}
+
+func (g *GestorIncidentes) Resumen() ResumenIncidentes {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	resumen := ResumenIncidentes{
+		Resueltos: append([]Incidente(nil), g.resueltos...),
+		Agrupadas: g.agrupadas,
+	}
+	for _, incidente := range g.activos {
+		resumen.Activos = append(resumen.Activos, *incidente)
+	}
+	sort.Slice(resumen.Activos, func(i, j int) bool {
+		return resumen.Activos[i].Recibido.Before(resumen.Activos[j].Recibido)
+	})
+	return resumen
+}
+
+// ==============================================
+// COLA DE DESBORDE EN DISCO
+// ==============================================
+
+var (
+	ErrColaDiscoLlena    = errors.New("cola de desborde en disco llena")
+	ErrColaDiscoCorrupta = errors.New("cola de desborde en disco corrupta")
+)
+
+// ColaDisco guarda en un archivo JSON Lines las alertas que no caben en el
+// canal. Lo pendiente sobrevive a un reinicio; si el proceso cae a mitad del
+// vaciado, algunas alertas se reinyectan dos veces y el gestor las agrupa.
+type ColaDisco struct {
+	ruta       string
+	maximo     int
+	archivo    *os.File
+	lector     *bufio.Reader
+	pendientes int
+	escritas   int64
+	mu         sync.Mutex
+}
+
+// AbrirColaDisco abre (o crea) la cola y cuenta las alertas pendientes de
+// una ejecución anterior
+func AbrirColaDisco(ruta string, maximo int) (*ColaDisco, error) {
+	archivo, err := os.OpenFile(ruta, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	c := &ColaDisco{ruta: ruta, maximo: maximo, archivo: archivo, lector: bufio.NewReader(archivo)}
+
+	// Solo cuentan las líneas terminadas; una última línea a medias (el
+	// proceso cayó mientras escribía) se recorta para que la lectura y el
+	// contador no se desalineen
+	var completos int64
+	for {
+		linea, err := c.lector.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			archivo.Close()
+			return nil, err
+		}
+		completos += int64(len(linea))
+		c.pendientes++
+	}
+	info, err := archivo.Stat()
+	if err != nil {
+		archivo.Close()
+		return nil, err
+	}
+	if info.Size() > completos {
+		if err := archivo.Truncate(completos); err != nil {
+			archivo.Close()
+			return nil, err
+		}
+	}
+	if _, err := archivo.Seek(0, io.SeekStart); err != nil {
+		archivo.Close()
+		return nil, err
+	}
+	c.lector.Reset(archivo)
+	return c, nil
+}
+
+// Guardar agrega una alerta al final; falla con ErrColaDiscoLlena al
+// llegar al máximo, y entonces el que envía debe esperar
+func (c *ColaDisco) Guardar(alerta Alerta) error {
+	linea, err := json.Marshal(alerta)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pendientes >= c.maximo {
+		return ErrColaDiscoLlena
+	}
+	// La escritura va al final sin mover la posición de lectura
+	info, err := c.archivo.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := c.archivo.WriteAt(append(linea, '\n'), info.Size()); err != nil {
+		return err
+	}
+	c.pendientes++
+	c.escritas++
+	return nil
+}
+
+// Sacar devuelve la alerta más antigua; ok es false si no hay pendientes.
+// Cuando se vacía, el archivo se trunca para no crecer sin límite.
+func (c *ColaDisco) Sacar() (alerta Alerta, ok bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pendientes == 0 {
+		return Alerta{}, false, nil
+	}
+
+	linea, err := c.lector.ReadBytes('\n')
+	if err != nil {
+		// Faltan líneas que el contador daba por escritas: el archivo se
+		// tocó desde fuera. Se descarta lo que queda para no devolver el
+		// mismo error en cada llamada.
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("%w: %s termina con %d alertas pendientes sin leer (%d bytes sueltos)",
+				ErrColaDiscoCorrupta, c.ruta, c.pendientes, len(linea))
+		}
+		c.pendientes = 0
+		if errReinicio := c.reiniciar(); errReinicio != nil {
+			return Alerta{}, false, errors.Join(err, errReinicio)
+		}
+		return Alerta{}, false, err
+	}
+	c.pendientes--
+	if c.pendientes == 0 {
+		if err := c.reiniciar(); err != nil {
+			return Alerta{}, false, err
+		}
+	}
+	if err := json.Unmarshal(linea, &alerta); err != nil {
+		return Alerta{}, false, fmt.Errorf("alerta corrupta en %s: %w", c.ruta, err)
+	}
+	return alerta, true, nil
+}
+
+func (c *ColaDisco) reiniciar() error {
+	if err := c.archivo.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := c.archivo.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	c.lector.Reset(c.archivo)
+	return nil
+}
+
+func (c *ColaDisco) Pendientes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pendientes
+}
+
+// Escritas cuenta las alertas que pasaron por el disco en esta ejecución
+func (c *ColaDisco) Escritas() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.escritas
+}
+
+// Cerrar compacta el archivo dejando solo lo pendiente, para que la próxima
+// ejecución no reinyecte alertas ya entregadas. Lo pendiente se escribe en
+// un temporal que sustituye al original con rename: si el proceso cae a
+// mitad, queda el archivo anterior completo y no uno truncado.
+func (c *ColaDisco) Cerrar() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resto, err := io.ReadAll(c.lector)
+	if errCierre := c.archivo.Close(); err == nil {
+		err = errCierre
+	}
+	if err != nil {
+		return err
+	}
+
+	temporal := c.ruta + ".tmp"
+	if err := escribirSincronizado(temporal, resto); err != nil {
+		os.Remove(temporal)
+		return err
+	}
+	if err := os.Rename(temporal, c.ruta); err != nil {
+		os.Remove(temporal)
+		return err
+	}
+	return nil
+}
+
+// escribirSincronizado crea ruta con datos y espera a que lleguen al disco
+func escribirSincronizado(ruta string, datos []byte) error {
+	archivo, err := os.OpenFile(ruta, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := archivo.Write(datos); err != nil {
+		archivo.Close()
+		return err
+	}
+	if err := archivo.Sync(); err != nil {
+		archivo.Close()
+		return err
+	}
+	return archivo.Close()
+}
+
+// ==============================================
+// ENVÍO SIN PÉRDIDAS
+// ==============================================
+
+// enviarAlerta intenta el canal sin bloquear; si está lleno, o si ya hay
+// alertas en disco (para no adelantarlas), la alerta va al disco. Solo con el
+// disco lleno se bloquea, aplicando contrapresión al procesador de eventos.
+func (s *SistemaMonitoreo) enviarAlerta(alerta Alerta) {
+	if s.desborde.Pendientes() == 0 {
+		select {
+		case s.alertas <- alerta:
+			return
+		default:
+		}
+	}
+
+	err := s.desborde.Guardar(alerta)
+	if err == nil {
+		return
+	}
+	fmt.Printf("⚠️ Desborde de alertas no disponible (%v), esperando al canal: %s\n", err, alerta.Descripcion)
+	select {
+	case s.alertas <- alerta:
+	case <-s.ctx.Done():
+		// Al apagar, se intenta dejarla en disco para la próxima ejecución
+		if err := s.desborde.Guardar(alerta); err != nil {
+			fmt.Printf("❌ Alerta perdida al apagar: %s (%v)\n", alerta.Descripcion, err)
+		}
+	}
+}
+
+// vaciarDesborde reinyecta en el canal las alertas guardadas en disco
+func (s *SistemaMonitoreo) vaciarDesborde() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			// Se espera a que haya sitio antes de sacar: lo que sale del
+			// disco ya no vuelve a él
+			if len(s.alertas) == cap(s.alertas) {
+				break
+			}
+			alerta, ok, err := s.desborde.Sacar()
+			if err != nil {
+				fmt.Printf("❌ Error leyendo el desborde de alertas: %v\n", err)
+				break
+			}
+			if !ok {
+				break
+			}
+			select {
+			case s.alertas <- alerta:
+			case <-s.ctx.Done():
+				if err := s.desborde.Guardar(alerta); err != nil {
+					fmt.Printf("❌ Alerta perdida al apagar: %s (%v)\n", alerta.Descripcion, err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// imprimirNotificacion es el canal de notificación de la demo
+func imprimirNotificacion(n Notificacion) {
+	inc := n.Incidente
+	switch n.Tipo {
+	case NotificacionAbierto:
+		fmt.Printf("%s INCIDENTE %s abierto [%s/%s] %s: %s - %.2f\n",
+			inc.Severidad.Icono(), inc.Huella, inc.Tipo, inc.Servicio, inc.Regla, inc.Descripcion, inc.UltimoValor)
+	case NotificacionEscalado:
+		fmt.Printf("📟 INCIDENTE %s escalado: %s sin reconocer desde %s (%d disparos)\n",
+			inc.Huella, inc.Regla, inc.Recibido.Format("15:04:05"), inc.Disparos)
+	case NotificacionResuelto:
+		fmt.Printf("✅ INCIDENTE %s resuelto: %s tras %d disparos en %v\n",
+			inc.Huella, inc.Regla, inc.Disparos, inc.Ultimo.Sub(inc.Abierto).Round(time.Millisecond))
+	}
+}
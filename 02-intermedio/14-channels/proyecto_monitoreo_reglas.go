@@ -0,0 +1,537 @@
+// ==============================================
+// LECCIÓN 14: Channels - Proyecto Sistema de Monitoreo: Motor de reglas
+// ==============================================
+// Reglas de alerta cargadas desde un archivo JSON: condiciones por tipo y
+// servicio, duración mínima, tasa de cambio, severidades, silencios e
+// inhibiciones
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==============================================
+// FORMATO DE LAS REGLAS
+// ==============================================
+
+var ErrReglasInvalidas = errors.New("reglas inválidas")
+
+type Severidad string
+
+const (
+	SeveridadInfo     Severidad = "info"
+	SeveridadWarning  Severidad = "warning"
+	SeveridadCritical Severidad = "critical"
+)
+
+func (s Severidad) Icono() string {
+	switch s {
+	case SeveridadCritical:
+		return "🔴"
+	case SeveridadWarning:
+		return "🟡"
+	default:
+		return "🔵"
+	}
+}
+
+// Duracion acepta "30s", "1m30s"... en el JSON
+type Duracion time.Duration
+
+func (d *Duracion) UnmarshalJSON(data []byte) error {
+	var texto string
+	if err := json.Unmarshal(data, &texto); err != nil {
+		return fmt.Errorf("duración debe ser un texto como \"30s\": %w", err)
+	}
+	valor, err := time.ParseDuration(texto)
+	if err != nil {
+		return err
+	}
+	*d = Duracion(valor)
+	return nil
+}
+
+func (d Duracion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Regla dispara cuando la métrica del evento cumple la condición durante
+// al menos Durante. Con Metrica "tasa" se compara el cambio por segundo del
+// valor dentro de VentanaTasa en lugar del valor. Una alerta se resuelve
+// cuando llega un evento que no cumple la condición o cuando la serie pasa
+// SinDatos sin eventos (por defecto sinDatosPorDefecto).
+type Regla struct {
+	Nombre      string    `json:"nombre"`
+	Tipo        string    `json:"tipo"`     // CPU, MEMORIA, RED, DISCO, ERROR
+	Servicio    string    `json:"servicio"` // vacío = cualquiera
+	Metrica     string    `json:"metrica"`  // "valor" (por defecto) o "tasa"
+	Operador    string    `json:"operador"` // >, >=, <, <=, ==, !=
+	Umbral      float64   `json:"umbral"`
+	VentanaTasa Duracion  `json:"ventana_tasa"`
+	Durante     Duracion  `json:"durante"`
+	SinDatos    Duracion  `json:"sin_datos"`
+	Severidad   Severidad `json:"severidad"`
+	Descripcion string    `json:"descripcion"`
+}
+
+// sinDatosPorDefecto cubre varios intervalos de los colectores: un evento
+// que se retrasa no resuelve la alerta, un servicio que dejó de informar sí
+const sinDatosPorDefecto = 10 * time.Second
+
+func (r Regla) sinDatos() time.Duration {
+	if r.SinDatos == 0 {
+		return sinDatosPorDefecto
+	}
+	return time.Duration(r.SinDatos)
+}
+
+// Silencio oculta las alertas de una regla ("*" para todas), opcionalmente
+// de un servicio, hasta un instante o durante un tiempo desde la carga
+type Silencio struct {
+	Regla      string    `json:"regla"`
+	Servicio   string    `json:"servicio"`
+	Hasta      time.Time `json:"hasta"`
+	Duracion   Duracion  `json:"duracion"`
+	Comentario string    `json:"comentario"`
+}
+
+// Inhibicion suprime las alertas de Destino mientras Origen está disparando,
+// en el mismo servicio si MismoServicio
+type Inhibicion struct {
+	Origen        string `json:"origen"`
+	Destino       string `json:"destino"`
+	MismoServicio bool   `json:"mismo_servicio"`
+}
+
+type ConfigReglas struct {
+	Reglas       []Regla      `json:"reglas"`
+	Silencios    []Silencio   `json:"silencios"`
+	Inhibiciones []Inhibicion `json:"inhibiciones"`
+}
+
+func CargarReglas(ruta string) (*ConfigReglas, error) {
+	datos, err := os.ReadFile(ruta)
+	if err != nil {
+		return nil, err
+	}
+	return ParsearReglas(datos)
+}
+
+func ParsearReglas(datos []byte) (*ConfigReglas, error) {
+	decoder := json.NewDecoder(bytes.NewReader(datos))
+	decoder.DisallowUnknownFields()
+	var config ConfigReglas
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReglasInvalidas, err)
+	}
+	if err := config.Validar(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+var tiposEvento = map[string]TipoEvento{
+	EventoCPU.String():     EventoCPU,
+	EventoMemoria.String(): EventoMemoria,
+	EventoRed.String():     EventoRed,
+	EventoDisco.String():   EventoDisco,
+	EventoError.String():   EventoError,
+}
+
+// Validar revisa todas las reglas y devuelve todos los problemas juntos
+func (c *ConfigReglas) Validar() error {
+	var errs []error
+	nombres := make(map[string]bool, len(c.Reglas))
+
+	for i := range c.Reglas {
+		r := &c.Reglas[i]
+		if r.Metrica == "" {
+			r.Metrica = "valor"
+		}
+		if r.Severidad == "" {
+			r.Severidad = SeveridadWarning
+		}
+
+		switch {
+		case r.Nombre == "":
+			errs = append(errs, fmt.Errorf("regla %d: falta el nombre", i))
+		case nombres[r.Nombre]:
+			errs = append(errs, fmt.Errorf("regla %s: nombre duplicado", r.Nombre))
+		}
+		nombres[r.Nombre] = true
+
+		if _, ok := tiposEvento[r.Tipo]; !ok {
+			errs = append(errs, fmt.Errorf("regla %s: tipo desconocido %q", r.Nombre, r.Tipo))
+		}
+		if _, ok := operadores[r.Operador]; !ok {
+			errs = append(errs, fmt.Errorf("regla %s: operador desconocido %q", r.Nombre, r.Operador))
+		}
+		switch r.Metrica {
+		case "valor":
+		case "tasa":
+			if r.VentanaTasa <= 0 {
+				errs = append(errs, fmt.Errorf("regla %s: la métrica tasa requiere ventana_tasa", r.Nombre))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("regla %s: métrica desconocida %q", r.Nombre, r.Metrica))
+		}
+		switch r.Severidad {
+		case SeveridadInfo, SeveridadWarning, SeveridadCritical:
+		default:
+			errs = append(errs, fmt.Errorf("regla %s: severidad desconocida %q", r.Nombre, r.Severidad))
+		}
+		if r.Durante < 0 {
+			errs = append(errs, fmt.Errorf("regla %s: durante no puede ser negativo", r.Nombre))
+		}
+		if r.SinDatos < 0 {
+			errs = append(errs, fmt.Errorf("regla %s: sin_datos no puede ser negativo", r.Nombre))
+		}
+	}
+
+	for _, s := range c.Silencios {
+		if s.Regla != "*" && !nombres[s.Regla] {
+			errs = append(errs, fmt.Errorf("silencio: regla desconocida %q", s.Regla))
+		}
+		if s.Hasta.IsZero() && s.Duracion <= 0 {
+			errs = append(errs, fmt.Errorf("silencio de %s: requiere hasta o duracion", s.Regla))
+		}
+	}
+	for _, inh := range c.Inhibiciones {
+		if !nombres[inh.Origen] || !nombres[inh.Destino] {
+			errs = append(errs, fmt.Errorf("inhibición %s → %s: regla desconocida", inh.Origen, inh.Destino))
+		}
+		if inh.Origen == inh.Destino {
+			errs = append(errs, fmt.Errorf("inhibición %s: una regla no puede inhibirse a sí misma", inh.Origen))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrReglasInvalidas, errors.Join(errs...))
+	}
+	return nil
+}
+
+var operadores = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+// ReglasPorDefecto reproduce los umbrales originales del sistema
+func ReglasPorDefecto() *ConfigReglas {
+	return &ConfigReglas{Reglas: []Regla{
+		{Nombre: "cpu_critico", Tipo: "CPU", Metrica: "valor", Operador: ">", Umbral: 90, Severidad: SeveridadCritical, Descripcion: "CPU crítico"},
+		{Nombre: "memoria_alta", Tipo: "MEMORIA", Metrica: "valor", Operador: ">", Umbral: 7000, Severidad: SeveridadWarning, Descripcion: "Memoria alta"},
+		{Nombre: "conectividad_baja", Tipo: "RED", Metrica: "valor", Operador: "<", Umbral: 10, Severidad: SeveridadWarning, Descripcion: "Conectividad baja"},
+		{Nombre: "error_critico", Tipo: "ERROR", Metrica: "valor", Operador: ">", Umbral: 7, Severidad: SeveridadCritical, Descripcion: "Error crítico"},
+	}}
+}
+
+// ==============================================
+// ALERTAS
+// ==============================================
+
+type Alerta struct {
+	Regla       string
+	Severidad   Severidad
+	Descripcion string
+	Servicio    string
+	Valor       float64   // valor o tasa que cumplió la condición
+	Desde       time.Time // desde cuándo se cumple la condición
+	Resuelta    bool      // la condición dejó de cumplirse
+	Evento      Evento
+}
+
+// ==============================================
+// MOTOR DE REGLAS
+// ==============================================
+
+type muestra struct {
+	momento time.Time
+	valor   float64
+}
+
+// estadoSerie es el estado de una regla para un servicio concreto
+type estadoSerie struct {
+	cumpleDesde time.Time // cero si la condición no se cumple
+	disparando  bool
+	historial   []muestra // solo para reglas de tasa
+	recibido    time.Time // reloj del proceso al llegar el último evento
+}
+
+type claveSerie struct {
+	regla    string
+	servicio string
+}
+
+type ContadoresRegla struct {
+	Disparadas  int64
+	Silenciadas int64
+	Inhibidas   int64
+}
+
+type MotorReglas struct {
+	reglas       []Regla
+	porTipo      map[TipoEvento][]int // índices en reglas
+	porNombre    map[string]int
+	silencios    []Silencio
+	inhibiciones []Inhibicion
+	series       map[claveSerie]*estadoSerie
+	contadores   map[string]*ContadoresRegla
+	mu           sync.Mutex
+}
+
+// NewMotorReglas prepara las reglas ya validadas; los silencios con
+// duración empiezan a contar en este momento
+func NewMotorReglas(config *ConfigReglas) *MotorReglas {
+	m := &MotorReglas{
+		reglas:       config.Reglas,
+		porTipo:      make(map[TipoEvento][]int),
+		porNombre:    make(map[string]int, len(config.Reglas)),
+		inhibiciones: config.Inhibiciones,
+		series:       make(map[claveSerie]*estadoSerie),
+		contadores:   make(map[string]*ContadoresRegla, len(config.Reglas)),
+	}
+	for i, regla := range m.reglas {
+		tipo := tiposEvento[regla.Tipo]
+		m.porTipo[tipo] = append(m.porTipo[tipo], i)
+		m.porNombre[regla.Nombre] = i
+		m.contadores[regla.Nombre] = &ContadoresRegla{}
+	}
+	for _, silencio := range config.Silencios {
+		m.Silenciar(silencio)
+	}
+	return m
+}
+
+// Silenciar agrega un silencio en tiempo de ejecución
+func (m *MotorReglas) Silenciar(silencio Silencio) {
+	if silencio.Hasta.IsZero() {
+		silencio.Hasta = time.Now().Add(time.Duration(silencio.Duracion))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.silencios = append(m.silencios, silencio)
+}
+
+// Evaluar actualiza el estado de las reglas del tipo del evento y devuelve
+// las alertas que deben enviarse, incluidas las resoluciones de las series
+// que estaban disparando
+func (m *MotorReglas) Evaluar(evento Evento) []Alerta {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Primero se actualiza el estado de todas las reglas, para que las
+	// inhibiciones vean el estado de este mismo evento
+	var candidatas []Alerta
+	for _, i := range m.porTipo[evento.Tipo] {
+		regla := m.reglas[i]
+		if regla.Servicio != "" && regla.Servicio != evento.Servicio {
+			continue
+		}
+		if alerta, dispara := m.evaluarRegla(regla, evento); dispara {
+			candidatas = append(candidatas, alerta)
+		}
+	}
+
+	var alertas []Alerta
+	for _, alerta := range candidatas {
+		contadores := m.contadores[alerta.Regla]
+		switch {
+		case alerta.Resuelta:
+			// Las resoluciones no se suprimen: quien no vio la alerta la ignora
+			alertas = append(alertas, alerta)
+		case m.silenciada(alerta, evento.Timestamp):
+			contadores.Silenciadas++
+		case m.inhibida(alerta):
+			contadores.Inhibidas++
+		default:
+			contadores.Disparadas++
+			alertas = append(alertas, alerta)
+		}
+	}
+	return alertas
+}
+
+func (m *MotorReglas) evaluarRegla(regla Regla, evento Evento) (Alerta, bool) {
+	clave := claveSerie{regla.Nombre, evento.Servicio}
+	serie, ok := m.series[clave]
+	if !ok {
+		serie = &estadoSerie{}
+		m.series[clave] = serie
+	}
+	serie.recibido = time.Now()
+
+	valor, hayValor := evento.Valor, true
+	if regla.Metrica == "tasa" {
+		valor, hayValor = serie.tasa(evento, time.Duration(regla.VentanaTasa))
+	}
+
+	if !hayValor || !operadores[regla.Operador](valor, regla.Umbral) {
+		estabaDisparando := serie.disparando
+		serie.cumpleDesde = time.Time{}
+		serie.disparando = false
+		if !estabaDisparando {
+			return Alerta{}, false
+		}
+		return Alerta{
+			Regla:       regla.Nombre,
+			Severidad:   regla.Severidad,
+			Descripcion: regla.Descripcion,
+			Servicio:    evento.Servicio,
+			Valor:       valor,
+			Resuelta:    true,
+			Evento:      evento,
+		}, true
+	}
+	if serie.cumpleDesde.IsZero() {
+		serie.cumpleDesde = evento.Timestamp
+	}
+	if evento.Timestamp.Sub(serie.cumpleDesde) < time.Duration(regla.Durante) {
+		return Alerta{}, false
+	}
+
+	serie.disparando = true
+	return Alerta{
+		Regla:       regla.Nombre,
+		Severidad:   regla.Severidad,
+		Descripcion: regla.Descripcion,
+		Servicio:    evento.Servicio,
+		Valor:       valor,
+		Desde:       serie.cumpleDesde,
+		Evento:      evento,
+	}, true
+}
+
+// Caducar resuelve las series que están disparando y llevan más de
+// SinDatos sin recibir eventos: sin él, una alerta solo se resuelve con un
+// evento que no cumple la condición, y un servicio que deja de informar la
+// dejaría abierta para siempre. Como en Evaluar, las resoluciones no se
+// silencian ni se inhiben.
+func (m *MotorReglas) Caducar(ahora time.Time) []Alerta {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var alertas []Alerta
+	for clave, serie := range m.series {
+		if !serie.disparando {
+			continue
+		}
+		regla := m.reglas[m.porNombre[clave.regla]]
+		if ahora.Sub(serie.recibido) < regla.sinDatos() {
+			continue
+		}
+		serie.disparando = false
+		serie.cumpleDesde = time.Time{}
+		serie.historial = nil
+		alertas = append(alertas, Alerta{
+			Regla:       regla.Nombre,
+			Severidad:   regla.Severidad,
+			Descripcion: regla.Descripcion,
+			Servicio:    clave.servicio,
+			Resuelta:    true,
+			Evento:      Evento{Timestamp: ahora, Tipo: tiposEvento[regla.Tipo], Servicio: clave.servicio},
+		})
+	}
+	sort.Slice(alertas, func(i, j int) bool {
+		if alertas[i].Regla != alertas[j].Regla {
+			return alertas[i].Regla < alertas[j].Regla
+		}
+		return alertas[i].Servicio < alertas[j].Servicio
+	})
+	return alertas
+}
+
+// tasa agrega la muestra y devuelve el cambio por segundo respecto a la
+// muestra más antigua dentro de la ventana
+func (s *estadoSerie) tasa(evento Evento, ventana time.Duration) (float64, bool) {
+	s.historial = append(s.historial, muestra{evento.Timestamp, evento.Valor})
+	limite := evento.Timestamp.Add(-ventana)
+	for len(s.historial) > 1 && s.historial[0].momento.Before(limite) {
+		s.historial = s.historial[1:]
+	}
+	if len(s.historial) < 2 {
+		return 0, false
+	}
+	primera, ultima := s.historial[0], s.historial[len(s.historial)-1]
+	segundos := ultima.momento.Sub(primera.momento).Seconds()
+	if segundos <= 0 {
+		return 0, false
+	}
+	return (ultima.valor - primera.valor) / segundos, true
+}
+
+func (m *MotorReglas) silenciada(alerta Alerta, ahora time.Time) bool {
+	for _, s := range m.silencios {
+		if (s.Regla == "*" || s.Regla == alerta.Regla) &&
+			(s.Servicio == "" || s.Servicio == alerta.Servicio) &&
+			ahora.Before(s.Hasta) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MotorReglas) inhibida(alerta Alerta) bool {
+	for _, inh := range m.inhibiciones {
+		if inh.Destino != alerta.Regla {
+			continue
+		}
+		for clave, serie := range m.series {
+			if clave.regla == inh.Origen && serie.disparando &&
+				(!inh.MismoServicio || clave.servicio == alerta.Servicio) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Contadores devuelve, por regla, cuántas alertas se enviaron o suprimieron
+func (m *MotorReglas) Contadores() map[string]ContadoresRegla {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copia := make(map[string]ContadoresRegla, len(m.contadores))
+	for nombre, c := range m.contadores {
+		copia[nombre] = *c
+	}
+	return copia
+}
+
+// Descripciones lista las reglas cargadas en forma legible
+func (m *MotorReglas) Descripciones() []string {
+	descripciones := make([]string, len(m.reglas))
+	for i, regla := range m.reglas {
+		descripciones[i] = fmt.Sprintf("%s %s: %s", regla.Severidad.Icono(), regla.Nombre, describirRegla(regla))
+	}
+	return descripciones
+}
+
+// describirRegla produce la forma legible de una regla: "CPU > 90 durante 2s"
+func describirRegla(regla Regla) string {
+	var b strings.Builder
+	b.WriteString(regla.Tipo)
+	if regla.Servicio != "" {
+		fmt.Fprintf(&b, "[%s]", regla.Servicio)
+	}
+	if regla.Metrica == "tasa" {
+		fmt.Fprintf(&b, " tasa/%v", time.Duration(regla.VentanaTasa))
+	}
+	fmt.Fprintf(&b, " %s %g", regla.Operador, regla.Umbral)
+	if regla.Durante > 0 {
+		fmt.Fprintf(&b, " durante %v", time.Duration(regla.Durante))
+	}
+	return b.String()
+}
@@ -0,0 +1,554 @@
+// ==============================================
+// LECCIÓN 14: Channels - Proyecto Sistema de Monitoreo: Series Temporales
+// ==============================================
+// Series en memoria por tipo/servicio con compactación y retención, consulta
+// de min/max/promedio/p95 por rango y exposición HTTP en formato Prometheus
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ==============================================
+// SERIES TEMPORALES
+// ==============================================
+
+type SerieID struct {
+	Tipo     TipoEvento
+	Servicio string
+}
+
+func (id SerieID) String() string {
+	return id.Tipo.String() + "/" + id.Servicio
+}
+
+type Punto struct {
+	Timestamp time.Time
+	Valor     float64
+}
+
+// Bloque resume los puntos de un intervalo ya compactado. El p95 se calcula
+// al compactar porque los puntos originales se descartan.
+type Bloque struct {
+	Inicio time.Time
+	Cuenta int64
+	Suma   float64
+	Min    float64
+	Max    float64
+	P95    float64
+}
+
+// Retencion decide cuánto vive cada resolución de una serie
+type Retencion struct {
+	Cruda    time.Duration // puntos originales
+	Bloque   time.Duration // tamaño de los bloques; 0 descarta sin compactar
+	Agregada time.Duration // bloques compactados
+}
+
+type ConfigSeries struct {
+	PorDefecto Retencion
+	PorTipo    map[TipoEvento]Retencion
+	PorSerie   map[SerieID]Retencion // tiene prioridad sobre PorTipo
+}
+
+func (c ConfigSeries) retencionPara(id SerieID) Retencion {
+	if r, ok := c.PorSerie[id]; ok {
+		return r
+	}
+	if r, ok := c.PorTipo[id.Tipo]; ok {
+		return r
+	}
+	return c.PorDefecto
+}
+
+// barrerSeriesCada es la frecuencia con la que se revisan las series inactivas
+const barrerSeriesCada = 5 * time.Second
+
+// caducarReglasCada es la frecuencia con la que se revisan las reglas que
+// disparan sin recibir eventos
+const caducarReglasCada = time.Second
+
+type serie struct {
+	retencion  Retencion
+	crudos     []Punto // ordenados por timestamp
+	bloques    []Bloque
+	compactado time.Time // todo lo anterior ya está en bloques o descartado
+	ultimo     Punto
+}
+
+// AlmacenSeries guarda una serie por tipo/servicio. El reloj de cada serie
+// es su evento más reciente, así que la compactación no depende de la hora
+// del proceso y se puede probar con timestamps fijos; Barrer se encarga de
+// las series que dejan de recibir eventos.
+type AlmacenSeries struct {
+	config ConfigSeries
+	series map[SerieID]*serie
+	// totales cuenta los eventos por serie y Barrer no lo toca: un contador
+	// de Prometheus no debe desaparecer ni volver a empezar desde 0
+	totales map[SerieID]int64
+	tardios int64 // puntos que llegaron después de compactar su intervalo
+	mu      sync.RWMutex
+}
+
+func NewAlmacenSeries(config ConfigSeries) *AlmacenSeries {
+	return &AlmacenSeries{
+		config:  config,
+		series:  make(map[SerieID]*serie),
+		totales: make(map[SerieID]int64),
+	}
+}
+
+// Agregar incorpora el valor del evento a su serie; devuelve false si el
+// punto cae en un intervalo ya compactado
+func (a *AlmacenSeries) Agregar(evento Evento) bool {
+	id := SerieID{Tipo: evento.Tipo, Servicio: evento.Servicio}
+	punto := Punto{Timestamp: evento.Timestamp, Valor: evento.Valor}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, existe := a.series[id]
+	if !existe {
+		s = &serie{retencion: a.config.retencionPara(id)}
+		a.series[id] = s
+	}
+	a.totales[id]++
+	if punto.Timestamp.Before(s.compactado) {
+		a.tardios++
+		return false
+	}
+
+	// Casi siempre llegan en orden: la búsqueda termina en el último hueco
+	i := sort.Search(len(s.crudos), func(i int) bool { return s.crudos[i].Timestamp.After(punto.Timestamp) })
+	s.crudos = append(s.crudos, Punto{})
+	copy(s.crudos[i+1:], s.crudos[i:])
+	s.crudos[i] = punto
+
+	if !punto.Timestamp.Before(s.ultimo.Timestamp) {
+		s.ultimo = punto
+	}
+	s.compactar(s.ultimo.Timestamp)
+	return true
+}
+
+// compactar pasa a bloques los puntos más viejos que la retención cruda y
+// descarta los bloques más viejos que la agregada. Solo se compactan bloques
+// completos: el límite se alinea al tamaño de bloque.
+func (s *serie) compactar(ahora time.Time) {
+	r := s.retencion
+	limite := ahora.Add(-r.Cruda)
+	if r.Bloque > 0 {
+		limite = limite.Truncate(r.Bloque)
+	}
+	if limite.After(s.compactado) {
+		s.compactado = limite
+		n := sort.Search(len(s.crudos), func(i int) bool { return !s.crudos[i].Timestamp.Before(limite) })
+		if r.Bloque > 0 {
+			for inicio := 0; inicio < n; {
+				bloque := s.crudos[inicio].Timestamp.Truncate(r.Bloque)
+				fin := inicio
+				for fin < n && s.crudos[fin].Timestamp.Truncate(r.Bloque).Equal(bloque) {
+					fin++
+				}
+				s.bloques = append(s.bloques, nuevoBloque(bloque, s.crudos[inicio:fin]))
+				inicio = fin
+			}
+		}
+		s.crudos = append(s.crudos[:0], s.crudos[n:]...)
+	}
+
+	vencidos := sort.Search(len(s.bloques), func(i int) bool {
+		return !s.bloques[i].Inicio.Add(r.Bloque).Before(ahora.Add(-r.Agregada))
+	})
+	s.bloques = append(s.bloques[:0], s.bloques[vencidos:]...)
+}
+
+func nuevoBloque(inicio time.Time, puntos []Punto) Bloque {
+	b := Bloque{Inicio: inicio, Min: math.Inf(1), Max: math.Inf(-1)}
+	valores := make([]ponderado, len(puntos))
+	for i, p := range puntos {
+		b.Cuenta++
+		b.Suma += p.Valor
+		b.Min = math.Min(b.Min, p.Valor)
+		b.Max = math.Max(b.Max, p.Valor)
+		valores[i] = ponderado{valor: p.Valor, peso: 1}
+	}
+	b.P95 = percentil(valores, 0.95)
+	return b
+}
+
+// ==============================================
+// CONSULTAS
+// ==============================================
+
+type ResumenSerie struct {
+	Tipo     string    `json:"tipo"`
+	Servicio string    `json:"servicio"`
+	Desde    time.Time `json:"desde"`
+	Hasta    time.Time `json:"hasta"`
+	Cuenta   int64     `json:"cuenta"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+	Promedio float64   `json:"promedio"`
+	P95      float64   `json:"p95"`
+	// Aproximado indica que el rango incluye bloques compactados: el p95
+	// sale de los p95 de cada bloque ponderados por su cuenta
+	Aproximado bool `json:"aproximado"`
+}
+
+type ponderado struct {
+	valor float64
+	peso  int64
+}
+
+// percentil usa el rango más cercano: con pesos 1 es el percentil exacto
+func percentil(valores []ponderado, q float64) float64 {
+	if len(valores) == 0 {
+		return 0
+	}
+	sort.Slice(valores, func(i, j int) bool { return valores[i].valor < valores[j].valor })
+	var total int64
+	for _, v := range valores {
+		total += v.peso
+	}
+	rango := int64(math.Ceil(q * float64(total)))
+	var acumulado int64
+	for _, v := range valores {
+		acumulado += v.peso
+		if acumulado >= rango {
+			return v.valor
+		}
+	}
+	return valores[len(valores)-1].valor
+}
+
+// Consultar resume la serie en [desde, hasta). Un bloque compactado cuenta
+// entero si su inicio cae dentro del rango. ok es false si la serie no existe.
+func (a *AlmacenSeries) Consultar(id SerieID, desde, hasta time.Time) (resumen ResumenSerie, ok bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	s, existe := a.series[id]
+	if !existe {
+		return ResumenSerie{}, false
+	}
+	resumen = ResumenSerie{Tipo: id.Tipo.String(), Servicio: id.Servicio, Desde: desde, Hasta: hasta}
+	dentro := func(t time.Time) bool { return !t.Before(desde) && t.Before(hasta) }
+
+	var suma float64
+	var valores []ponderado
+	agregar := func(cuenta int64, sumaParcial, min, max, p95 float64) {
+		if resumen.Cuenta == 0 {
+			resumen.Min, resumen.Max = min, max
+		}
+		resumen.Cuenta += cuenta
+		suma += sumaParcial
+		resumen.Min = math.Min(resumen.Min, min)
+		resumen.Max = math.Max(resumen.Max, max)
+		valores = append(valores, ponderado{valor: p95, peso: cuenta})
+	}
+	for _, b := range s.bloques {
+		if dentro(b.Inicio) {
+			agregar(b.Cuenta, b.Suma, b.Min, b.Max, b.P95)
+			resumen.Aproximado = true
+		}
+	}
+	for _, p := range s.crudos {
+		if dentro(p.Timestamp) {
+			agregar(1, p.Valor, p.Valor, p.Valor, p.Valor)
+		}
+	}
+
+	if resumen.Cuenta > 0 {
+		resumen.Promedio = suma / float64(resumen.Cuenta)
+		resumen.P95 = percentil(valores, 0.95)
+	}
+	return resumen, true
+}
+
+// Barrer aplica la retención con un reloj externo a las series que no
+// recibieron puntos desde entonces: sin él, una serie que deja de reportar
+// conserva sus datos para siempre. Las que se quedan vacías se eliminan
+// (su contador de eventos se conserva); devuelve cuántas.
+func (a *AlmacenSeries) Barrer(ahora time.Time) (eliminadas int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, s := range a.series {
+		if ahora.After(s.ultimo.Timestamp) {
+			s.compactar(ahora)
+		}
+		if len(s.crudos) == 0 && len(s.bloques) == 0 {
+			delete(a.series, id)
+			eliminadas++
+		}
+	}
+	return eliminadas
+}
+
+// Series devuelve los IDs ordenados por tipo y servicio
+func (a *AlmacenSeries) Series() []SerieID {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	ids := make([]SerieID, 0, len(a.series))
+	for id := range a.series {
+		ids = append(ids, id)
+	}
+	return ordenarIDs(ids)
+}
+
+func ordenarIDs(ids []SerieID) []SerieID {
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].Tipo != ids[j].Tipo {
+			return ids[i].Tipo < ids[j].Tipo
+		}
+		return ids[i].Servicio < ids[j].Servicio
+	})
+	return ids
+}
+
+// EstadoSerie es lo que se expone de cada serie en /metrics. Activa es
+// false si Barrer ya eliminó la serie y solo queda su contador.
+type EstadoSerie struct {
+	ID      SerieID
+	Total   int64
+	Activa  bool
+	Ultimo  Punto
+	Crudos  int
+	Bloques int
+}
+
+// Estado toma los IDs y sus datos bajo el mismo lock: entre dos lecturas
+// Barrer podría eliminar la serie
+func (a *AlmacenSeries) Estado() (estados []EstadoSerie, tardios int64) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	ids := make([]SerieID, 0, len(a.totales))
+	for id := range a.totales {
+		ids = append(ids, id)
+	}
+	for _, id := range ordenarIDs(ids) {
+		estado := EstadoSerie{ID: id, Total: a.totales[id]}
+		if s, existe := a.series[id]; existe {
+			estado.Activa = true
+			estado.Ultimo, estado.Crudos, estado.Bloques = s.ultimo, len(s.crudos), len(s.bloques)
+		}
+		estados = append(estados, estado)
+	}
+	return estados, a.tardios
+}
+
+// ==============================================
+// EXPOSICIÓN PROMETHEUS
+// ==============================================
+
+type muestraProm struct {
+	etiquetas [][2]string
+	valor     float64
+}
+
+var escapeEtiqueta = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escribirFamilia escribe una métrica en el formato de texto 0.0.4
+func escribirFamilia(w io.Writer, nombre, ayuda, tipo string, muestras []muestraProm) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", nombre, ayuda, nombre, tipo)
+	for _, m := range muestras {
+		fmt.Fprint(w, nombre)
+		if len(m.etiquetas) > 0 {
+			partes := make([]string, len(m.etiquetas))
+			for i, e := range m.etiquetas {
+				partes[i] = e[0] + `="` + escapeEtiqueta.Replace(e[1]) + `"`
+			}
+			fmt.Fprintf(w, "{%s}", strings.Join(partes, ","))
+		}
+		fmt.Fprintf(w, " %s\n", strconv.FormatFloat(m.valor, 'g', -1, 64))
+	}
+}
+
+// barrerSeries aplica la retención con la hora del proceso a las series
+// inactivas
+// caducarReglas resuelve las alertas de las series que dejaron de informar
+func (s *SistemaMonitoreo) caducarReglas() {
+	ticker := time.NewTicker(caducarReglasCada)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case ahora := <-ticker.C:
+			for _, alerta := range s.reglas.Caducar(ahora) {
+				s.enviarAlerta(alerta)
+			}
+		}
+	}
+}
+
+func (s *SistemaMonitoreo) barrerSeries() {
+	ticker := time.NewTicker(barrerSeriesCada)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case ahora := <-ticker.C:
+			s.metricas.series.Barrer(ahora)
+		}
+	}
+}
+
+func (s *SistemaMonitoreo) escribirMetricas(w io.Writer) {
+	estados, tardios := s.metricas.series.Estado()
+	var eventos, ultimos, puntos []muestraProm
+	for _, e := range estados {
+		tipo, servicio := [2]string{"tipo", e.ID.Tipo.String()}, [2]string{"servicio", e.ID.Servicio}
+		eventos = append(eventos, muestraProm{[][2]string{tipo, servicio}, float64(e.Total)})
+		if !e.Activa {
+			continue // barrida: el contador sigue, pero ya no hay valor ni puntos
+		}
+		ultimos = append(ultimos, muestraProm{[][2]string{tipo, servicio}, e.Ultimo.Valor})
+		puntos = append(puntos,
+			muestraProm{[][2]string{tipo, servicio, {"resolucion", "cruda"}}, float64(e.Crudos)},
+			muestraProm{[][2]string{tipo, servicio, {"resolucion", "agregada"}}, float64(e.Bloques)})
+	}
+	escribirFamilia(w, "monitoreo_eventos_total", "Eventos procesados por tipo y servicio.", "counter", eventos)
+	escribirFamilia(w, "monitoreo_ultimo_valor", "Último valor observado por tipo y servicio.", "gauge", ultimos)
+	escribirFamilia(w, "monitoreo_series_puntos", "Puntos retenidos en memoria por resolución.", "gauge", puntos)
+	escribirFamilia(w, "monitoreo_series_tardios_total", "Puntos descartados por llegar a un intervalo ya compactado.", "counter",
+		[]muestraProm{{valor: float64(tardios)}})
+	escribirFamilia(w, "monitoreo_eventos_error_total", "Eventos de tipo ERROR procesados.", "counter",
+		[]muestraProm{{valor: float64(atomic.LoadInt64(&s.metricas.eventosError))}})
+
+	contadores := s.reglas.Contadores()
+	nombres := make([]string, 0, len(contadores))
+	for nombre := range contadores {
+		nombres = append(nombres, nombre)
+	}
+	sort.Strings(nombres)
+	var alertas []muestraProm
+	for _, nombre := range nombres {
+		c := contadores[nombre]
+		for _, r := range []struct {
+			resultado string
+			cuenta    int64
+		}{{"disparada", c.Disparadas}, {"silenciada", c.Silenciadas}, {"inhibida", c.Inhibidas}} {
+			alertas = append(alertas, muestraProm{[][2]string{{"regla", nombre}, {"resultado", r.resultado}}, float64(r.cuenta)})
+		}
+	}
+	escribirFamilia(w, "monitoreo_alertas_total", "Alertas evaluadas por regla y resultado.", "counter", alertas)
+
+	incidentes := s.incidentes.Resumen()
+	escribirFamilia(w, "monitoreo_incidentes_activos", "Incidentes abiertos, escalados o reconocidos.", "gauge",
+		[]muestraProm{{valor: float64(len(incidentes.Activos))}})
+	escribirFamilia(w, "monitoreo_alertas_desbordadas_total", "Alertas que pasaron por la cola en disco.", "counter",
+		[]muestraProm{{valor: float64(s.desborde.Escritas())}})
+}
+
+// ==============================================
+// API HTTP
+// ==============================================
+
+// Manejador expone /metrics (Prometheus), /api/series y /api/consulta
+func (s *SistemaMonitoreo) Manejador() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		s.escribirMetricas(w)
+	})
+	mux.HandleFunc("GET /api/series", func(w http.ResponseWriter, r *http.Request) {
+		var series []map[string]string
+		for _, id := range s.metricas.series.Series() {
+			series = append(series, map[string]string{"tipo": id.Tipo.String(), "servicio": id.Servicio})
+		}
+		responderJSON(w, http.StatusOK, series)
+	})
+	mux.HandleFunc("GET /api/consulta", s.manejarConsulta)
+	return mux
+}
+
+// manejarConsulta atiende /api/consulta?tipo=CPU&servicio=sistema&desde=5m.
+// desde y hasta aceptan RFC 3339 o una duración hacia atrás; por defecto es
+// el último minuto.
+func (s *SistemaMonitoreo) manejarConsulta(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tipo, ok := tiposEvento[q.Get("tipo")]
+	if !ok {
+		responderJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("tipo desconocido %q", q.Get("tipo"))})
+		return
+	}
+
+	ahora := time.Now()
+	hasta, err := parsearInstante(q.Get("hasta"), ahora, ahora)
+	if err != nil {
+		responderJSON(w, http.StatusBadRequest, map[string]string{"error": "hasta: " + err.Error()})
+		return
+	}
+	desde, err := parsearInstante(q.Get("desde"), ahora, hasta.Add(-time.Minute))
+	if err != nil {
+		responderJSON(w, http.StatusBadRequest, map[string]string{"error": "desde: " + err.Error()})
+		return
+	}
+	if !desde.Before(hasta) {
+		responderJSON(w, http.StatusBadRequest, map[string]string{"error": "desde debe ser anterior a hasta"})
+		return
+	}
+
+	id := SerieID{Tipo: tipo, Servicio: q.Get("servicio")}
+	resumen, ok := s.metricas.series.Consultar(id, desde, hasta)
+	if !ok {
+		responderJSON(w, http.StatusNotFound, map[string]string{"error": "serie desconocida " + id.String()})
+		return
+	}
+	responderJSON(w, http.StatusOK, resumen)
+}
+
+func parsearInstante(valor string, ahora, porDefecto time.Time) (time.Time, error) {
+	if valor == "" {
+		return porDefecto, nil
+	}
+	if hace, err := time.ParseDuration(valor); err == nil {
+		return ahora.Add(-hace), nil
+	}
+	return time.Parse(time.RFC3339, valor)
+}
+
+func responderJSON(w http.ResponseWriter, estado int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(estado)
+	json.NewEncoder(w).Encode(v)
+}
+
+// ServirMetricas escucha en direccion y devuelve la dirección real (útil con
+// el puerto 0). El servidor se apaga al cancelar el contexto del sistema.
+func (s *SistemaMonitoreo) ServirMetricas(direccion string) (string, error) {
+	listener, err := net.Listen("tcp", direccion)
+	if err != nil {
+		return "", err
+	}
+	servidor := &http.Server{Handler: s.Manejador(), ReadHeaderTimeout: 5 * time.Second}
+
+	s.lanzar(func() {
+		if err := servidor.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("❌ Servidor de métricas: %v\n", err)
+		}
+	})
+	s.lanzar(func() {
+		<-s.ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		servidor.Shutdown(ctx)
+	})
+	return listener.Addr().String(), nil
+}
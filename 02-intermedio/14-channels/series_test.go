@@ -0,0 +1,319 @@
+// 🧪 Tests: Series temporales, endpoint Prometheus y API de consulta
+// Archivo: series_test.go
+// Ejecutar con: go test -v proyecto_monitoreo*.go series_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var origenSeries = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// agregarCada agrega n eventos separados por paso con valores 0, 1, 2...
+func agregarCada(almacen *AlmacenSeries, tipo TipoEvento, servicio string, n int, paso time.Duration) {
+	for i := range n {
+		almacen.Agregar(Evento{
+			Timestamp: origenSeries.Add(time.Duration(i) * paso),
+			Tipo:      tipo,
+			Servicio:  servicio,
+			Valor:     float64(i),
+		})
+	}
+}
+
+func TestAlmacenSeries_ConsultaSobrePuntosCrudos(t *testing.T) {
+	almacen := NewAlmacenSeries(ConfigSeries{PorDefecto: Retencion{Cruda: time.Hour, Bloque: time.Minute, Agregada: time.Hour}})
+	for i := 1; i <= 20; i++ {
+		almacen.Agregar(Evento{Timestamp: origenSeries.Add(time.Duration(i) * time.Second), Tipo: EventoCPU, Servicio: "sistema", Valor: float64(i)})
+	}
+
+	resumen, ok := almacen.Consultar(SerieID{EventoCPU, "sistema"}, origenSeries, origenSeries.Add(time.Minute))
+
+	if !ok {
+		t.Fatal("Expected the series to exist")
+	}
+	if resumen.Cuenta != 20 || resumen.Min != 1 || resumen.Max != 20 || resumen.Promedio != 10.5 {
+		t.Errorf("Expected n=20 min=1 max=20 avg=10.5, got %+v", resumen)
+	}
+	// Rango más cercano: ceil(0.95 * 20) = 19
+	if resumen.P95 != 19 {
+		t.Errorf("Expected p95 19, got %v", resumen.P95)
+	}
+	if resumen.Aproximado {
+		t.Error("Expected an exact summary over raw points")
+	}
+}
+
+func TestAlmacenSeries_RangoSemiabierto(t *testing.T) {
+	almacen := NewAlmacenSeries(ConfigSeries{PorDefecto: Retencion{Cruda: time.Hour}})
+	agregarCada(almacen, EventoCPU, "sistema", 10, time.Second)
+
+	resumen, _ := almacen.Consultar(SerieID{EventoCPU, "sistema"}, origenSeries.Add(2*time.Second), origenSeries.Add(5*time.Second))
+
+	if resumen.Cuenta != 3 || resumen.Min != 2 || resumen.Max != 4 {
+		t.Errorf("Expected values 2..4 in [2s, 5s), got %+v", resumen)
+	}
+}
+
+func TestAlmacenSeries_CompactaYAplicaRetencion(t *testing.T) {
+	almacen := NewAlmacenSeries(ConfigSeries{PorDefecto: Retencion{Cruda: 2 * time.Second, Bloque: time.Second, Agregada: 5 * time.Second}})
+	// Puntos cada 500ms de 0s a 9.5s
+	agregarCada(almacen, EventoMemoria, "sistema", 20, 500*time.Millisecond)
+
+	estados, _ := almacen.Estado()
+
+	// Límite crudo: 9.5s - 2s = 7.5s, alineado a 7s -> quedan 7s..9.5s.
+	// Bloques de 1s: se conservan los que terminan después de 4.5s (4s, 5s, 6s)
+	if len(estados) != 1 || estados[0].Crudos != 6 || estados[0].Bloques != 3 {
+		t.Fatalf("Expected 6 raw points and 3 blocks, got %+v", estados)
+	}
+	if estados[0].Total != 20 {
+		t.Errorf("Expected 20 events counted, got %d", estados[0].Total)
+	}
+
+	resumen, _ := almacen.Consultar(SerieID{EventoMemoria, "sistema"}, time.Time{}, origenSeries.Add(time.Minute))
+	if resumen.Cuenta != 12 || resumen.Min != 8 || resumen.Max != 19 {
+		t.Errorf("Expected 12 retained values from 8 to 19, got %+v", resumen)
+	}
+	if !resumen.Aproximado {
+		t.Error("Expected the summary to be flagged as approximate")
+	}
+}
+
+func TestAlmacenSeries_DescartaPuntosTardios(t *testing.T) {
+	almacen := NewAlmacenSeries(ConfigSeries{PorDefecto: Retencion{Cruda: 2 * time.Second, Bloque: time.Second, Agregada: time.Minute}})
+	agregarCada(almacen, EventoRed, "networking", 20, 500*time.Millisecond)
+
+	aceptado := almacen.Agregar(Evento{Timestamp: origenSeries.Add(time.Second), Tipo: EventoRed, Servicio: "networking", Valor: 1000})
+
+	if aceptado {
+		t.Error("Expected a point inside an already compacted block to be rejected")
+	}
+	if _, tardios := almacen.Estado(); tardios != 1 {
+		t.Errorf("Expected 1 late point, got %d", tardios)
+	}
+}
+
+func TestAlmacenSeries_RetencionPorTipoYSerie(t *testing.T) {
+	corta := Retencion{Cruda: time.Second, Bloque: time.Second, Agregada: time.Second}
+	almacen := NewAlmacenSeries(ConfigSeries{
+		PorDefecto: corta,
+		PorTipo:    map[TipoEvento]Retencion{EventoError: {Cruda: time.Hour}},
+		PorSerie:   map[SerieID]Retencion{{EventoCPU, "critico"}: {Cruda: time.Hour}},
+	})
+	agregarCada(almacen, EventoError, "servicio-1", 30, time.Second)
+	agregarCada(almacen, EventoCPU, "critico", 30, time.Second)
+	agregarCada(almacen, EventoCPU, "sistema", 30, time.Second)
+
+	estados, _ := almacen.Estado()
+
+	crudos := make(map[string]int)
+	for _, e := range estados {
+		crudos[e.ID.String()] = e.Crudos
+	}
+	if crudos["ERROR/servicio-1"] != 30 || crudos["CPU/critico"] != 30 {
+		t.Errorf("Expected all raw points for per-type and per-series retention, got %v", crudos)
+	}
+	if crudos["CPU/sistema"] >= 30 {
+		t.Errorf("Expected the default retention to compact CPU/sistema, got %v", crudos)
+	}
+}
+
+func TestAlmacenSeries_BarrerSeriesInactivas(t *testing.T) {
+	almacen := NewAlmacenSeries(ConfigSeries{PorDefecto: Retencion{Cruda: 2 * time.Second, Bloque: time.Second, Agregada: 5 * time.Second}})
+	// Las dos series terminan en 9s, pero solo la de memoria deja de reportar
+	agregarCada(almacen, EventoMemoria, "inactivo", 10, time.Second)
+	agregarCada(almacen, EventoCPU, "activo", 10, time.Second)
+	almacen.Agregar(Evento{Timestamp: origenSeries.Add(20 * time.Second), Tipo: EventoCPU, Servicio: "activo", Valor: 1})
+
+	// A los 12s la serie inactiva compacta como si hubiera seguido recibiendo
+	if eliminadas := almacen.Barrer(origenSeries.Add(12 * time.Second)); eliminadas != 0 {
+		t.Fatalf("Expected no series removed yet, got %d", eliminadas)
+	}
+	estados, _ := almacen.Estado()
+	if len(estados) != 2 || estados[1].ID.Servicio != "inactivo" || estados[1].Crudos != 0 || estados[1].Bloques != 4 {
+		t.Fatalf("Expected the idle series compacted to 4 blocks, got %+v", estados)
+	}
+
+	// A los 24s ya no le queda nada y desaparece; la activa conserva su bloque
+	if eliminadas := almacen.Barrer(origenSeries.Add(24 * time.Second)); eliminadas != 1 {
+		t.Fatalf("Expected 1 series removed, got %d", eliminadas)
+	}
+	if ids := almacen.Series(); len(ids) != 1 || ids[0] != (SerieID{EventoCPU, "activo"}) {
+		t.Errorf("Expected only CPU/activo to remain, got %v", ids)
+	}
+	// El contador de eventos de la serie eliminada sobrevive al barrido
+	estados, _ = almacen.Estado()
+	if len(estados) != 2 || estados[1].Activa || estados[1].Total != 10 {
+		t.Errorf("Expected the swept series to keep its 10 events, got %+v", estados)
+	}
+}
+
+func TestPercentil_Ponderado(t *testing.T) {
+	valores := []ponderado{{valor: 100, peso: 1}, {valor: 1, peso: 90}, {valor: 50, peso: 9}}
+
+	// 95 de 100 -> cae en el grupo de 50 (acumulado 91..99)
+	if got := percentil(valores, 0.95); got != 50 {
+		t.Errorf("Expected weighted p95 50, got %v", got)
+	}
+	if got := percentil(nil, 0.95); got != 0 {
+		t.Errorf("Expected 0 for no values, got %v", got)
+	}
+}
+
+// nuevoSistemaHTTP arma un sistema sin colectores para probar el manejador
+func nuevoSistemaHTTP(t *testing.T, series *AlmacenSeries) *SistemaMonitoreo {
+	t.Helper()
+	desborde, err := AbrirColaDisco(filepath.Join(t.TempDir(), "desborde.jsonl"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	incidentes := NewGestorIncidentes(ConfigIncidentes{}, func(Notificacion) {})
+	sistema := NewSistemaMonitoreo(nil, NewMotorReglas(ReglasPorDefecto()), incidentes, desborde, series)
+	t.Cleanup(func() {
+		sistema.cancel()
+		desborde.Cerrar()
+	})
+	return sistema
+}
+
+func TestManejador_MetricsFormatoPrometheus(t *testing.T) {
+	series := NewAlmacenSeries(ConfigSeries{PorDefecto: Retencion{Cruda: time.Hour}})
+	agregarCada(series, EventoCPU, "sistema", 3, time.Second)
+	agregarCada(series, EventoRed, `eth"0\`, 1, time.Second)
+	servidor := httptest.NewServer(nuevoSistemaHTTP(t, series).Manejador())
+	defer servidor.Close()
+
+	respuesta, err := http.Get(servidor.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer respuesta.Body.Close()
+	cuerpo, _ := io.ReadAll(respuesta.Body)
+	texto := string(cuerpo)
+
+	if ct := respuesta.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected Prometheus text content type, got %q", ct)
+	}
+	for _, linea := range []string{
+		"# TYPE monitoreo_eventos_total counter",
+		`monitoreo_eventos_total{tipo="CPU",servicio="sistema"} 3`,
+		`monitoreo_ultimo_valor{tipo="CPU",servicio="sistema"} 2`,
+		`monitoreo_eventos_total{tipo="RED",servicio="eth\"0\\"} 1`,
+		`monitoreo_series_puntos{tipo="CPU",servicio="sistema",resolucion="cruda"} 3`,
+		`monitoreo_alertas_total{regla="cpu_critico",resultado="disparada"} 0`,
+		"monitoreo_incidentes_activos 0",
+	} {
+		if !strings.Contains(texto, linea+"\n") {
+			t.Errorf("Expected line %q in:\n%s", linea, texto)
+		}
+	}
+}
+
+func TestManejador_MetricsDuranteBarrido(t *testing.T) {
+	series := NewAlmacenSeries(ConfigSeries{PorDefecto: Retencion{Cruda: time.Second, Bloque: time.Second, Agregada: time.Second}})
+	servidor := httptest.NewServer(nuevoSistemaHTTP(t, series).Manejador())
+	defer servidor.Close()
+
+	// Cada serie recibe un evento y enseguida el barrido la elimina
+	const eventos = 200
+	terminado := make(chan struct{})
+	go func() {
+		defer close(terminado)
+		for i := range eventos {
+			instante := origenSeries.Add(time.Duration(i) * time.Second)
+			series.Agregar(Evento{Timestamp: instante, Tipo: EventoCPU, Servicio: "svc-" + strconv.Itoa(i%5), Valor: 1})
+			series.Barrer(instante.Add(10 * time.Second))
+		}
+	}()
+
+	linea := `monitoreo_eventos_total{tipo="CPU",servicio="svc-0"} `
+	anterior := 0
+	leer := func() string {
+		respuesta, err := http.Get(servidor.URL + "/metrics")
+		if err != nil {
+			t.Fatalf("Expected /metrics to answer during the sweep, got: %v", err)
+		}
+		defer respuesta.Body.Close()
+		cuerpo, _ := io.ReadAll(respuesta.Body)
+		texto := string(cuerpo)
+		if i := strings.Index(texto, linea); i >= 0 {
+			valor, _ := strconv.Atoi(strings.SplitN(texto[i+len(linea):], "\n", 2)[0])
+			if valor < anterior {
+				t.Fatalf("Expected a monotonic counter, went from %d to %d", anterior, valor)
+			}
+			anterior = valor
+		}
+		return texto
+	}
+	for barriendo := true; barriendo; {
+		select {
+		case <-terminado:
+			barriendo = false
+		default:
+		}
+		leer()
+	}
+
+	texto := leer()
+	if !strings.Contains(texto, linea+strconv.Itoa(eventos/5)+"\n") {
+		t.Errorf("Expected %s%d after every series was swept, got:\n%s", linea, eventos/5, texto)
+	}
+	if strings.Contains(texto, `monitoreo_ultimo_valor{tipo="CPU",servicio="svc-0"}`) {
+		t.Errorf("Expected no last value for a swept series, got:\n%s", texto)
+	}
+}
+
+func TestManejador_Consulta(t *testing.T) {
+	series := NewAlmacenSeries(ConfigSeries{PorDefecto: Retencion{Cruda: time.Hour}})
+	agregarCada(series, EventoCPU, "sistema", 10, time.Second)
+	servidor := httptest.NewServer(nuevoSistemaHTTP(t, series).Manejador())
+	defer servidor.Close()
+
+	desde := origenSeries.Format(time.RFC3339)
+	hasta := origenSeries.Add(5 * time.Second).Format(time.RFC3339)
+	tests := []struct {
+		name   string
+		query  string
+		estado int
+	}{
+		{name: "valid range", query: "tipo=CPU&servicio=sistema&desde=" + desde + "&hasta=" + hasta, estado: http.StatusOK},
+		{name: "unknown type", query: "tipo=GPU&servicio=sistema", estado: http.StatusBadRequest},
+		{name: "bad instant", query: "tipo=CPU&servicio=sistema&desde=ayer", estado: http.StatusBadRequest},
+		{name: "inverted range", query: "tipo=CPU&servicio=sistema&desde=" + hasta + "&hasta=" + desde, estado: http.StatusBadRequest},
+		{name: "unknown series", query: "tipo=CPU&servicio=otro", estado: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			respuesta, err := http.Get(servidor.URL + "/api/consulta?" + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer respuesta.Body.Close()
+
+			if respuesta.StatusCode != tt.estado {
+				t.Fatalf("Expected status %d, got %d", tt.estado, respuesta.StatusCode)
+			}
+			if tt.estado != http.StatusOK {
+				return
+			}
+			var resumen ResumenSerie
+			if err := json.NewDecoder(respuesta.Body).Decode(&resumen); err != nil {
+				t.Fatal(err)
+			}
+			if resumen.Cuenta != 5 || resumen.Min != 0 || resumen.Max != 4 || resumen.Promedio != 2 || resumen.P95 != 4 {
+				t.Errorf("Expected n=5 min=0 max=4 avg=2 p95=4, got %+v", resumen)
+			}
+		})
+	}
+}